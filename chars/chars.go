@@ -1,5 +1,7 @@
 package chars
 
+import "unicode"
+
 // https://github.com/angular/angular/blob/master/packages/compiler/src/chars.ts
 
 var VEOF = 0
@@ -71,8 +73,15 @@ var VAT = 64
 
 var VBT = 96
 
+var VZWNJ = 0x200C
+var VZWJ = 0x200D
+var VLS = 0x2028
+var VPS = 0x2029
+var VBOM = 0xFEFF
+
 func IsWhitespace(code int) bool {
-	return (code >= VTAB && code <= VSPACE) || (code == VNBSP)
+	return (code >= VTAB && code <= VSPACE) || (code == VNBSP) || code == VBOM || code == VLS || code == VPS ||
+		(code > 0x7F && unicode.Is(unicode.Zs, rune(code)))
 }
 
 func IsDigit(code int) bool {
@@ -98,3 +107,37 @@ func IsOctalDigit(code int) bool {
 func IsQuote(code int) bool {
 	return code == VSQ || code == VDQ || code == VBT
 }
+
+// IsIdentifierStart reports whether code can start an ECMAScript identifier:
+// `$`, `_` or a character with the Unicode ID_Start property.
+func IsIdentifierStart(code int) bool {
+	if code < 0x80 {
+		return IsAsciiLetter(code) || code == V_ || code == VDOLLAR
+	}
+
+	r := rune(code)
+	return unicode.In(r, unicode.L, unicode.Nl, unicode.Other_ID_Start) && !unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+// IsIdentifierPart reports whether code can continue an ECMAScript identifier:
+// `$`, ZWNJ, ZWJ or a character with the Unicode ID_Continue property.
+func IsIdentifierPart(code int) bool {
+	if code < 0x80 {
+		return IsAsciiLetter(code) || IsDigit(code) || code == V_ || code == VDOLLAR
+	}
+
+	if code == VZWNJ || code == VZWJ || IsIdentifierStart(code) {
+		return true
+	}
+
+	r := rune(code)
+	return unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) && !unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+// UTF16Len returns the number of UTF-16 code units needed to encode r.
+func UTF16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -1,298 +1,595 @@
 package ep
 
-//
-//// https://github.com/angular/angular/blob/master/packages/compiler/src/expression_parser/ast.ts
-//
-//type ParserError struct {
-//	message string
-//	input string
-//	errLocation string
-//}
-//
-//type ParseSpan struct {
-//	start int
-//	End int
-//}
-//
-//func (p ParseSpan) toAbsolute(absoluteOffset int) AbsoluteSourceSpan {
-//  return AbsoluteSourceSpan{absoluteOffset + p.start, absoluteOffset + p.End}
-//}
-//
-//type AbsoluteSourceSpan struct {
-//	start int
-//	End int
-//}
-//
-//type AST struct  {
-//	span ParseSpan
-//	sourceSpan AbsoluteSourceSpan
-//}
-//
-//func (a AST) visit(visitor AstVisitor) {
-//}
-//
-//func (a AST) toString() string {
-//	return "AST"
-//}
-//
-//type ASTWithName struct  {
-//	AST
-//	nameSpan AbsoluteSourceSpan
-//}
-//
-//func (a ASTWithName) visit(visitor AstVisitor) {
-//}
-//
-//func (a ASTWithName) toString() string {
-//	return "AST"
-//}
-//
-///**
-// * Represents a quoted expression of the form:
-// *
-// * quote = prefix `:` uninterpretedExpression
-// * prefix = identifier
-// * uninterpretedExpression = arbitrary string
-// *
-// * A quoted expression is meant to be pre-processed by an AST transformer that
-// * converts it into another AST that no longer contains quoted expressions.
-// * It is meant to allow third-party developers to extend Angular template
-// * expression language. The `uninterpretedExpression` part of the quote is
-// * therefore not interpreted by the Angular's own expression parser.
-// */
-//
-//type Quote struct  {
-//	AST
-//	prefix string
-//	uninterpretedExpression string
-//	location interface{}
-//}
-//
-//func (a Quote) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitQuote(q, context)
-//}
-//
-//func (a Quote) toString() string {
-//	return "Quote"
-//}
-//
-///////
-//
-//type EmptyExpr struct  {
-//	AST
-//}
-//func (a EmptyExpr) visit(visitor AstVisitor, context interface{}) {
-//
-//}
-//func (a EmptyExpr) toString() string {
-//	return "AST"
-//}
-//
-/////
-//
-//type ImplicitReceiver struct  {
-//	AST
-//}
-//func (a ImplicitReceiver) visit(visitor AstVisitor, context interface{})interface{} {
-//	return visitor.visitImplicitReceiver(a, context)
-//}
-//
-//
-/////
-//
-//type ThisReceiver struct  {
-//	AST
-//}
-//func (a ThisReceiver) visit(visitor AstVisitor, context interface{})interface{} {
-//	return visitor.visitThisReceiver(a, context)
-//}
-//
-/////
-//
-//type Chain struct  {
-//	AST
-//	expressions []interface{}
-//}
-//func (a Chain) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitChain(a, context)
-//}
-//
-/////
-//
-//type Conditional struct  {
-//	AST
-//	condition AST
-//	trueExp AST
-//	falseExp AST
-//}
-//func (a Conditional) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitConditional(a, context)
-//}
-//
-/////
-//
-//type PropertyRead struct  {
-//	ASTWithName
-//	receiver AST
-//	name string
-//}
-//func (a PropertyRead) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitPropertyRead(a, context)
-//}
-//
-/////
-//
-//type PropertyWrite struct  {
-//	ASTWithName
-//	receiver AST
-//	name string
-//	value AST
-//}
-//func (a PropertyWrite) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitPropertyWrite(a, context)
-//}
-//
-/////
-//
-//type SafePropertyRead struct  {
-//	ASTWithName
-//	receiver AST
-//	name string
-//}
-//func (a SafePropertyRead) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitSafePropertyRead(a, context)
-//}
-//
-/////
-//
-//type KeyedRead struct  {
-//	AST
-//	receiver AST
-//	key string
-//}
-//func (a KeyedRead) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitKeyedRead(a, context)
-//}
-//
-/////
-//
-//type SafeKeyedRead struct  {
-//	AST
-//	receiver AST
-//	key string
-//}
-//func (a SafeKeyedRead) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitSafeKeyedRead(a, context)
-//}
-//
-/////
-//
-//type KeyedWrite struct  {
-//	AST
-//	receiver AST
-//	key string
-//}
-//func (a KeyedWrite) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitKeyedWrite(a, context)
-//}
-//
-/////
-//
-//type BindingPipe struct  {
-//	ASTWithName
-//	exp AST
-//	name string
-//	args []interface{}
-//}
-//func (a BindingPipe) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitPipe(a, context)
-//}
-//
-/////
-//
-//type LiteralPrimitive struct  {
-//	AST
-//	value interface{}
-//}
-//func (a LiteralPrimitive) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitLiteralPrimitive(a, context)
-//}
-//
-/////
-//
-//type LiteralArray struct  {
-//	AST
-//	expressions []interface{}
-//}
-//func (a LiteralArray) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitLiteralArray(a, context)
-//}
-//
-//type LiteralMapKey struct {
-//	key string
-//	quoted bool
-//}
-//
-/////
-//
-//type LiteralMap struct  {
-//	AST
-//	keys []LiteralMapKey
-//	values []interface{}
-//}
-//func (a LiteralMap) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitLiteralMap(a, context)
-//}
-//
-/////
-//
-//type Interpolation struct  {
-//	AST
-//	public []interface{}
-//	expressions []interface{}
-//}
-//func (a Interpolation) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.Interpolation(a, context)
-//}
-//
-//
-/////
-//
-//type Binary struct  {
-//	AST
-//	operation string
-//	left AST
-//	right AST
-//}
-//func (a Binary) visit(visitor AstVisitor, context interface{}) interface{} {
-//	return visitor.visitBinary(a, context)
-//}
-//
-/////
-//
-//type Unary struct  {
-//	operation string
-//	left AST
-//	right AST
-//	operator string
-//	expr AST
-//	binaryOp AST
-//	binaryLeft AST
-//	binaryRight AST
-//}
-//func (a Unary) visit(visitor AstVisitor, context interface{}) interface{} {
-//	// TODO
-//	//if (visitor.visitUnary != nil) {
-//	//	return visitor.visitUnary(this, context);
-//	//}
-//
-//	return visitor.visitBinary(this, context);
-//
-//	//return visitor.visitBinary(a, context)
-//}
-//
-//func UnaryCreateMinus(span ParseSpan, sourceSpan AbsoluteSourceSpan, expr AST) Unary {
-//	return Unary {
-//
-//	}
-//}
+// https://github.com/angular/angular/blob/master/packages/compiler/src/expression_parser/ast.ts
+
+type ParserError struct {
+	Message     string
+	Input       string
+	ErrLocation string
+	CtxLocation string
+}
+
+func NewParserError(message string, input string, errLocation string, ctxLocation string) ParserError {
+	return ParserError{
+		Message:     `Parser Error: ` + message + ` ` + errLocation + ` [` + input + `] in ` + ctxLocation,
+		Input:       input,
+		ErrLocation: errLocation,
+		CtxLocation: ctxLocation,
+	}
+}
+
+func (e ParserError) Error() string {
+	return e.Message
+}
+
+// ParseSpan is a span relative to the start of the parsed expression, in
+// bytes.
+type ParseSpan struct {
+	Start int
+	End   int
+}
+
+func (p ParseSpan) ToAbsolute(absoluteOffset int) AbsoluteSourceSpan {
+	return AbsoluteSourceSpan{absoluteOffset + p.Start, absoluteOffset + p.End}
+}
+
+// AbsoluteSourceSpan is a span relative to the start of the template, in
+// bytes.
+type AbsoluteSourceSpan struct {
+	Start int
+	End   int
+}
+
+type AST interface {
+	Visit(visitor AstVisitor, context interface{}) interface{}
+	GetSpan() ParseSpan
+	GetSourceSpan() AbsoluteSourceSpan
+}
+
+type ASTBase struct {
+	Span       ParseSpan
+	SourceSpan AbsoluteSourceSpan
+}
+
+func (a ASTBase) GetSpan() ParseSpan {
+	return a.Span
+}
+
+func (a ASTBase) GetSourceSpan() AbsoluteSourceSpan {
+	return a.SourceSpan
+}
+
+type ASTWithName struct {
+	ASTBase
+	NameSpan AbsoluteSourceSpan
+}
+
+type EmptyExpr struct {
+	ASTBase
+}
+
+func (a *EmptyExpr) Visit(visitor AstVisitor, context interface{}) interface{} {
+	// do nothing
+	return nil
+}
+
+type ImplicitReceiver struct {
+	ASTBase
+}
+
+func (a *ImplicitReceiver) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitImplicitReceiver(a, context)
+}
+
+// ThisReceiver is the explicit `this` receiver, e.g. `this.foo`.
+type ThisReceiver struct {
+	ImplicitReceiver
+}
+
+func (a *ThisReceiver) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitThisReceiver(a, context)
+}
+
+// Chain is a list of expressions separated by `;`, only valid in actions.
+type Chain struct {
+	ASTBase
+	Expressions []AST
+}
+
+func (a *Chain) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitChain(a, context)
+}
+
+type Conditional struct {
+	ASTBase
+	Condition AST
+	TrueExp   AST
+	FalseExp  AST
+}
+
+func (a *Conditional) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitConditional(a, context)
+}
+
+type PropertyRead struct {
+	ASTWithName
+	Receiver AST
+	Name     string
+}
+
+func (a *PropertyRead) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitPropertyRead(a, context)
+}
+
+type PropertyWrite struct {
+	ASTWithName
+	Receiver AST
+	Name     string
+	Value    AST
+}
+
+func (a *PropertyWrite) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitPropertyWrite(a, context)
+}
+
+type SafePropertyRead struct {
+	ASTWithName
+	Receiver AST
+	Name     string
+}
+
+func (a *SafePropertyRead) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitSafePropertyRead(a, context)
+}
+
+type KeyedRead struct {
+	ASTBase
+	Receiver AST
+	Key      AST
+}
+
+func (a *KeyedRead) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitKeyedRead(a, context)
+}
+
+type SafeKeyedRead struct {
+	ASTBase
+	Receiver AST
+	Key      AST
+}
+
+func (a *SafeKeyedRead) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitSafeKeyedRead(a, context)
+}
+
+type KeyedWrite struct {
+	ASTBase
+	Receiver AST
+	Key      AST
+	Value    AST
+}
+
+func (a *KeyedWrite) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitKeyedWrite(a, context)
+}
+
+type BindingPipe struct {
+	ASTWithName
+	Exp  AST
+	Name string
+	Args []AST
+}
+
+func (a *BindingPipe) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitPipe(a, context)
+}
+
+// LiteralPrimitive holds nil for null, Undefined, bool, float64 or string.
+type LiteralPrimitive struct {
+	ASTBase
+	Value interface{}
+}
+
+func (a *LiteralPrimitive) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitLiteralPrimitive(a, context)
+}
+
+type undefined struct{}
+
+// Undefined is the value of the `undefined` literal.
+var Undefined = undefined{}
+
+type LiteralArray struct {
+	ASTBase
+	Expressions []AST
+}
+
+func (a *LiteralArray) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitLiteralArray(a, context)
+}
+
+type LiteralMapKey struct {
+	Key    string
+	Quoted bool
+}
+
+type LiteralMap struct {
+	ASTBase
+	Keys   []LiteralMapKey
+	Values []AST
+}
+
+func (a *LiteralMap) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitLiteralMap(a, context)
+}
+
+type Interpolation struct {
+	ASTBase
+	Strings     []string
+	Expressions []AST
+}
+
+func (a *Interpolation) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitInterpolation(a, context)
+}
+
+type Binary struct {
+	ASTBase
+	Operation string
+	Left      AST
+	Right     AST
+}
+
+func (a *Binary) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitBinary(a, context)
+}
+
+// Unary is a `-x` or `+x` expression.
+type Unary struct {
+	ASTBase
+	Operator string
+	Expr     AST
+}
+
+func (a *Unary) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitUnary(a, context)
+}
+
+type PrefixNot struct {
+	ASTBase
+	Expression AST
+}
+
+func (a *PrefixNot) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitPrefixNot(a, context)
+}
+
+// TypeofExpression is a `typeof x` expression.
+type TypeofExpression struct {
+	ASTBase
+	Expression AST
+}
+
+func (a *TypeofExpression) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitTypeofExpression(a, context)
+}
+
+// VoidExpression is a `void x` expression.
+type VoidExpression struct {
+	ASTBase
+	Expression AST
+}
+
+func (a *VoidExpression) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitVoidExpression(a, context)
+}
+
+// CompoundAssignment is an `a += b` style assignment, Operation is the
+// assignment operator, e.g. "+=" or "??=", and Target the PropertyRead or
+// KeyedRead being written.
+type CompoundAssignment struct {
+	ASTBase
+	Operation string
+	Target    AST
+	Value     AST
+}
+
+func (a *CompoundAssignment) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitCompoundAssignment(a, context)
+}
+
+type NonNullAssert struct {
+	ASTBase
+	Expression AST
+}
+
+func (a *NonNullAssert) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitNonNullAssert(a, context)
+}
+
+type Call struct {
+	ASTBase
+	Receiver     AST
+	Args         []AST
+	ArgumentSpan AbsoluteSourceSpan
+}
+
+func (a *Call) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitCall(a, context)
+}
+
+type SafeCall struct {
+	ASTBase
+	Receiver     AST
+	Args         []AST
+	ArgumentSpan AbsoluteSourceSpan
+}
+
+func (a *SafeCall) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitSafeCall(a, context)
+}
+
+// TemplateLiteral is a backtick string, Elements has one more entry than
+// Expressions: `a${b}c` has the elements "a", "c" and the expression b.
+type TemplateLiteral struct {
+	ASTBase
+	Elements    []*TemplateLiteralElement
+	Expressions []AST
+}
+
+func (a *TemplateLiteral) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitTemplateLiteral(a, context)
+}
+
+type TemplateLiteralElement struct {
+	ASTBase
+	Text string
+}
+
+func (a *TemplateLiteralElement) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitTemplateLiteralElement(a, context)
+}
+
+// TaggedTemplateLiteral is a template literal called with a tag function,
+// e.g. tag`a${b}`.
+type TaggedTemplateLiteral struct {
+	ASTBase
+	Tag      AST
+	Template *TemplateLiteral
+}
+
+func (a *TaggedTemplateLiteral) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return visitor.VisitTaggedTemplateLiteral(a, context)
+}
+
+// ASTWithSource is the root of a parsed expression together with its source
+// text and the errors found while parsing it.
+type ASTWithSource struct {
+	ASTBase
+	Ast      AST
+	Source   string
+	Location string
+	Errors   []ParserError
+}
+
+func NewASTWithSource(ast AST, source string, location string, absoluteOffset int, errors []ParserError) *ASTWithSource {
+	return &ASTWithSource{
+		ASTBase: ASTBase{
+			Span:       ParseSpan{0, len(source)},
+			SourceSpan: AbsoluteSourceSpan{absoluteOffset, absoluteOffset + len(source)},
+		},
+		Ast:      ast,
+		Source:   source,
+		Location: location,
+		Errors:   errors,
+	}
+}
+
+func (a *ASTWithSource) Visit(visitor AstVisitor, context interface{}) interface{} {
+	return a.Ast.Visit(visitor, context)
+}
+
+type AstVisitor interface {
+	VisitUnary(ast *Unary, context interface{}) interface{}
+	VisitBinary(ast *Binary, context interface{}) interface{}
+	VisitChain(ast *Chain, context interface{}) interface{}
+	VisitConditional(ast *Conditional, context interface{}) interface{}
+	VisitThisReceiver(ast *ThisReceiver, context interface{}) interface{}
+	VisitImplicitReceiver(ast *ImplicitReceiver, context interface{}) interface{}
+	VisitInterpolation(ast *Interpolation, context interface{}) interface{}
+	VisitKeyedRead(ast *KeyedRead, context interface{}) interface{}
+	VisitKeyedWrite(ast *KeyedWrite, context interface{}) interface{}
+	VisitLiteralArray(ast *LiteralArray, context interface{}) interface{}
+	VisitLiteralMap(ast *LiteralMap, context interface{}) interface{}
+	VisitLiteralPrimitive(ast *LiteralPrimitive, context interface{}) interface{}
+	VisitPipe(ast *BindingPipe, context interface{}) interface{}
+	VisitPrefixNot(ast *PrefixNot, context interface{}) interface{}
+	VisitNonNullAssert(ast *NonNullAssert, context interface{}) interface{}
+	VisitTypeofExpression(ast *TypeofExpression, context interface{}) interface{}
+	VisitVoidExpression(ast *VoidExpression, context interface{}) interface{}
+	VisitCompoundAssignment(ast *CompoundAssignment, context interface{}) interface{}
+	VisitPropertyRead(ast *PropertyRead, context interface{}) interface{}
+	VisitPropertyWrite(ast *PropertyWrite, context interface{}) interface{}
+	VisitSafePropertyRead(ast *SafePropertyRead, context interface{}) interface{}
+	VisitSafeKeyedRead(ast *SafeKeyedRead, context interface{}) interface{}
+	VisitCall(ast *Call, context interface{}) interface{}
+	VisitSafeCall(ast *SafeCall, context interface{}) interface{}
+	VisitTemplateLiteral(ast *TemplateLiteral, context interface{}) interface{}
+	VisitTemplateLiteralElement(ast *TemplateLiteralElement, context interface{}) interface{}
+	VisitTaggedTemplateLiteral(ast *TaggedTemplateLiteral, context interface{}) interface{}
+}
+
+// RecursiveAstVisitor visits every node of an expression. Embed it and
+// override the methods of interest, calling back into the embedded visitor
+// with Self set to the outer visitor to keep visiting children.
+type RecursiveAstVisitor struct {
+	Self AstVisitor
+}
+
+func (v *RecursiveAstVisitor) visit(ast AST, context interface{}) {
+	if ast == nil {
+		return
+	}
+
+	if v.Self != nil {
+		ast.Visit(v.Self, context)
+	} else {
+		ast.Visit(v, context)
+	}
+}
+
+func (v *RecursiveAstVisitor) visitAll(asts []AST, context interface{}) {
+	for _, ast := range asts {
+		v.visit(ast, context)
+	}
+}
+
+func (v *RecursiveAstVisitor) VisitUnary(ast *Unary, context interface{}) interface{} {
+	v.visit(ast.Expr, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitBinary(ast *Binary, context interface{}) interface{} {
+	v.visit(ast.Left, context)
+	v.visit(ast.Right, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitChain(ast *Chain, context interface{}) interface{} {
+	v.visitAll(ast.Expressions, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitConditional(ast *Conditional, context interface{}) interface{} {
+	v.visit(ast.Condition, context)
+	v.visit(ast.TrueExp, context)
+	v.visit(ast.FalseExp, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitThisReceiver(ast *ThisReceiver, context interface{}) interface{} {
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitImplicitReceiver(ast *ImplicitReceiver, context interface{}) interface{} {
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitInterpolation(ast *Interpolation, context interface{}) interface{} {
+	v.visitAll(ast.Expressions, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitKeyedRead(ast *KeyedRead, context interface{}) interface{} {
+	v.visit(ast.Receiver, context)
+	v.visit(ast.Key, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitKeyedWrite(ast *KeyedWrite, context interface{}) interface{} {
+	v.visit(ast.Receiver, context)
+	v.visit(ast.Key, context)
+	v.visit(ast.Value, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitLiteralArray(ast *LiteralArray, context interface{}) interface{} {
+	v.visitAll(ast.Expressions, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitLiteralMap(ast *LiteralMap, context interface{}) interface{} {
+	v.visitAll(ast.Values, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitLiteralPrimitive(ast *LiteralPrimitive, context interface{}) interface{} {
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitPipe(ast *BindingPipe, context interface{}) interface{} {
+	v.visit(ast.Exp, context)
+	v.visitAll(ast.Args, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitPrefixNot(ast *PrefixNot, context interface{}) interface{} {
+	v.visit(ast.Expression, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitNonNullAssert(ast *NonNullAssert, context interface{}) interface{} {
+	v.visit(ast.Expression, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitTypeofExpression(ast *TypeofExpression, context interface{}) interface{} {
+	v.visit(ast.Expression, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitVoidExpression(ast *VoidExpression, context interface{}) interface{} {
+	v.visit(ast.Expression, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitCompoundAssignment(ast *CompoundAssignment, context interface{}) interface{} {
+	v.visit(ast.Target, context)
+	v.visit(ast.Value, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitPropertyRead(ast *PropertyRead, context interface{}) interface{} {
+	v.visit(ast.Receiver, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitPropertyWrite(ast *PropertyWrite, context interface{}) interface{} {
+	v.visit(ast.Receiver, context)
+	v.visit(ast.Value, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitSafePropertyRead(ast *SafePropertyRead, context interface{}) interface{} {
+	v.visit(ast.Receiver, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitSafeKeyedRead(ast *SafeKeyedRead, context interface{}) interface{} {
+	v.visit(ast.Receiver, context)
+	v.visit(ast.Key, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitCall(ast *Call, context interface{}) interface{} {
+	v.visit(ast.Receiver, context)
+	v.visitAll(ast.Args, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitSafeCall(ast *SafeCall, context interface{}) interface{} {
+	v.visit(ast.Receiver, context)
+	v.visitAll(ast.Args, context)
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitTemplateLiteral(ast *TemplateLiteral, context interface{}) interface{} {
+	for i, element := range ast.Elements {
+		v.visit(element, context)
+
+		if i < len(ast.Expressions) {
+			v.visit(ast.Expressions[i], context)
+		}
+	}
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitTemplateLiteralElement(ast *TemplateLiteralElement, context interface{}) interface{} {
+	return nil
+}
+
+func (v *RecursiveAstVisitor) VisitTaggedTemplateLiteral(ast *TaggedTemplateLiteral, context interface{}) interface{} {
+	v.visit(ast.Tag, context)
+	v.visit(ast.Template, context)
+	return nil
+}
@@ -0,0 +1,639 @@
+package ep
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The binary encoding of templates starts with binaryMagic and BinaryVersion,
+// followed by a table of the interned strings and the body. Integers are
+// zigzag varints, strings indexes into the table, slices their length plus
+// one with 0 for nil, and expressions a kind byte followed by their fields.
+
+// BinaryVersion is the version of the binary encoding of templates and
+// expressions, data of another version isn't decoded.
+const BinaryVersion = 2
+
+const binaryMagic = "NGTB"
+
+var ErrBinaryFormat = errors.New("ep: invalid binary template encoding")
+
+// BinaryWriter encodes values in the binary encoding, every distinct string
+// is written once.
+type BinaryWriter struct {
+	body    []byte
+	strings map[string]int
+	table   []string
+}
+
+func NewBinaryWriter() *BinaryWriter {
+	return &BinaryWriter{strings: map[string]int{}}
+}
+
+// Bytes returns the header, the string table and the body written so far.
+func (w *BinaryWriter) Bytes() []byte {
+	out := appendUvarint([]byte(binaryMagic), BinaryVersion)
+	out = appendUvarint(out, uint64(len(w.table)))
+	for _, s := range w.table {
+		out = appendUvarint(out, uint64(len(s)))
+		out = append(out, s...)
+	}
+	return append(out, w.body...)
+}
+
+func appendUvarint(out []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(out, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(out []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(out, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+func (w *BinaryWriter) Int(v int) {
+	w.body = appendVarint(w.body, int64(v))
+}
+
+func (w *BinaryWriter) Bool(v bool) {
+	if v {
+		w.body = append(w.body, 1)
+	} else {
+		w.body = append(w.body, 0)
+	}
+}
+
+func (w *BinaryWriter) String(s string) {
+	index, ok := w.strings[s]
+	if !ok {
+		index = len(w.table)
+		w.strings[s] = index
+		w.table = append(w.table, s)
+	}
+	w.body = appendUvarint(w.body, uint64(index))
+}
+
+// Length writes the length of a slice, nil and empty slices are kept apart.
+func (w *BinaryWriter) Length(n int, isNil bool) {
+	if isNil {
+		w.body = appendUvarint(w.body, 0)
+		return
+	}
+	w.body = appendUvarint(w.body, uint64(n)+1)
+}
+
+func (w *BinaryWriter) Strings(values []string) {
+	w.Length(len(values), values == nil)
+	for _, s := range values {
+		w.String(s)
+	}
+}
+
+// Span writes the start and the length of a span.
+func (w *BinaryWriter) Span(span AbsoluteSourceSpan) {
+	w.Int(span.Start)
+	w.Int(span.End - span.Start)
+}
+
+func (w *BinaryWriter) OptionalSpan(span *AbsoluteSourceSpan) {
+	w.Bool(span != nil)
+	if span != nil {
+		w.Span(*span)
+	}
+}
+
+func (w *BinaryWriter) ParserErrors(errors []ParserError) {
+	w.Length(len(errors), errors == nil)
+	for _, e := range errors {
+		w.String(e.Message)
+		w.String(e.Input)
+		w.String(e.ErrLocation)
+		w.String(e.CtxLocation)
+	}
+}
+
+// BinaryReader decodes values written by a BinaryWriter. The first error is
+// kept and returned by Err, the values read after it are zero.
+type BinaryReader struct {
+	data  []byte
+	table []string
+	err   error
+}
+
+// NewBinaryReader reads the header and the string table of data.
+func NewBinaryReader(data []byte) (*BinaryReader, error) {
+	if len(data) < len(binaryMagic) || string(data[:len(binaryMagic)]) != binaryMagic {
+		return nil, ErrBinaryFormat
+	}
+	r := &BinaryReader{data: data[len(binaryMagic):]}
+
+	if version := r.uvarint(); r.err == nil && version != BinaryVersion {
+		return nil, fmt.Errorf("ep: binary template encoding version %d, expected %d", version, BinaryVersion)
+	}
+
+	count := r.uvarint()
+	if count > uint64(len(r.data)) {
+		return nil, ErrBinaryFormat
+	}
+	r.table = make([]string, 0, count)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		n := r.uvarint()
+		if n > uint64(len(r.data)) {
+			r.fail()
+			break
+		}
+		r.table = append(r.table, string(r.data[:n]))
+		r.data = r.data[n:]
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r, nil
+}
+
+func (r *BinaryReader) Err() error {
+	return r.err
+}
+
+// Fail marks the data as invalid, e.g. when it has an unknown kind of node.
+func (r *BinaryReader) Fail() {
+	r.fail()
+}
+
+func (r *BinaryReader) fail() {
+	if r.err == nil {
+		r.err = ErrBinaryFormat
+	}
+	r.data = nil
+}
+
+func (r *BinaryReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *BinaryReader) byte() byte {
+	if len(r.data) == 0 {
+		r.fail()
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *BinaryReader) Int() int {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.data = r.data[n:]
+	return int(v)
+}
+
+func (r *BinaryReader) Bool() bool {
+	return r.byte() != 0
+}
+
+func (r *BinaryReader) String() string {
+	index := r.uvarint()
+	if index >= uint64(len(r.table)) {
+		r.fail()
+		return ""
+	}
+	return r.table[index]
+}
+
+// Length reads the length of a slice, -1 for nil. Every item takes at least
+// one byte, longer lengths fail.
+func (r *BinaryReader) Length() int {
+	n := r.uvarint()
+	if n > uint64(len(r.data))+1 {
+		r.fail()
+		return -1
+	}
+	return int(n) - 1
+}
+
+func (r *BinaryReader) Strings() []string {
+	n := r.Length()
+	if n < 0 {
+		return nil
+	}
+	values := make([]string, n)
+	for i := range values {
+		values[i] = r.String()
+	}
+	return values
+}
+
+func (r *BinaryReader) Span() AbsoluteSourceSpan {
+	start := r.Int()
+	return AbsoluteSourceSpan{Start: start, End: start + r.Int()}
+}
+
+func (r *BinaryReader) OptionalSpan() *AbsoluteSourceSpan {
+	if !r.Bool() {
+		return nil
+	}
+	span := r.Span()
+	return &span
+}
+
+func (r *BinaryReader) ParserErrors() []ParserError {
+	n := r.Length()
+	if n < 0 {
+		return nil
+	}
+	errors := make([]ParserError, n)
+	for i := range errors {
+		errors[i] = ParserError{Message: r.String(), Input: r.String(), ErrLocation: r.String(), CtxLocation: r.String()}
+	}
+	return errors
+}
+
+// the kinds of expressions in the binary encoding, 0 is nil
+const (
+	binaryNil byte = iota
+	binaryEmptyExpr
+	binaryImplicitReceiver
+	binaryThisReceiver
+	binaryChain
+	binaryConditional
+	binaryPropertyRead
+	binaryPropertyWrite
+	binarySafePropertyRead
+	binaryKeyedRead
+	binarySafeKeyedRead
+	binaryKeyedWrite
+	binaryBindingPipe
+	binaryLiteralPrimitive
+	binaryLiteralArray
+	binaryLiteralMap
+	binaryInterpolation
+	binaryBinary
+	binaryUnary
+	binaryPrefixNot
+	binaryTypeofExpression
+	binaryVoidExpression
+	binaryCompoundAssignment
+	binaryNonNullAssert
+	binaryCall
+	binarySafeCall
+	binaryTemplateLiteral
+	binaryTemplateLiteralElement
+	binaryTaggedTemplateLiteral
+	binaryASTWithSource
+)
+
+// the values of literals in the binary encoding
+const (
+	binaryNull byte = iota
+	binaryUndefined
+	binaryFalse
+	binaryTrue
+	binaryNumber
+	binaryString
+)
+
+func (w *BinaryWriter) base(kind byte, base ASTBase) {
+	w.body = append(w.body, kind)
+	w.Int(base.Span.Start)
+	w.Int(base.Span.End - base.Span.Start)
+	w.Span(base.SourceSpan)
+}
+
+func (w *BinaryWriter) withName(kind byte, base ASTWithName) {
+	w.base(kind, base.ASTBase)
+	w.Span(base.NameSpan)
+}
+
+func (w *BinaryWriter) ASTs(asts []AST) {
+	w.Length(len(asts), asts == nil)
+	for _, ast := range asts {
+		w.AST(ast)
+	}
+}
+
+// AST writes an expression and its subexpressions.
+func (w *BinaryWriter) AST(ast AST) {
+	switch a := ast.(type) {
+	case nil:
+		w.body = append(w.body, binaryNil)
+	case *EmptyExpr:
+		w.base(binaryEmptyExpr, a.ASTBase)
+	case *ImplicitReceiver:
+		w.base(binaryImplicitReceiver, a.ASTBase)
+	case *ThisReceiver:
+		w.base(binaryThisReceiver, a.ASTBase)
+	case *Chain:
+		w.base(binaryChain, a.ASTBase)
+		w.ASTs(a.Expressions)
+	case *Conditional:
+		w.base(binaryConditional, a.ASTBase)
+		w.AST(a.Condition)
+		w.AST(a.TrueExp)
+		w.AST(a.FalseExp)
+	case *PropertyRead:
+		w.withName(binaryPropertyRead, a.ASTWithName)
+		w.AST(a.Receiver)
+		w.String(a.Name)
+	case *PropertyWrite:
+		w.withName(binaryPropertyWrite, a.ASTWithName)
+		w.AST(a.Receiver)
+		w.String(a.Name)
+		w.AST(a.Value)
+	case *SafePropertyRead:
+		w.withName(binarySafePropertyRead, a.ASTWithName)
+		w.AST(a.Receiver)
+		w.String(a.Name)
+	case *KeyedRead:
+		w.base(binaryKeyedRead, a.ASTBase)
+		w.AST(a.Receiver)
+		w.AST(a.Key)
+	case *SafeKeyedRead:
+		w.base(binarySafeKeyedRead, a.ASTBase)
+		w.AST(a.Receiver)
+		w.AST(a.Key)
+	case *KeyedWrite:
+		w.base(binaryKeyedWrite, a.ASTBase)
+		w.AST(a.Receiver)
+		w.AST(a.Key)
+		w.AST(a.Value)
+	case *BindingPipe:
+		w.withName(binaryBindingPipe, a.ASTWithName)
+		w.AST(a.Exp)
+		w.String(a.Name)
+		w.ASTs(a.Args)
+	case *LiteralPrimitive:
+		w.base(binaryLiteralPrimitive, a.ASTBase)
+		w.literal(a.Value)
+	case *LiteralArray:
+		w.base(binaryLiteralArray, a.ASTBase)
+		w.ASTs(a.Expressions)
+	case *LiteralMap:
+		w.base(binaryLiteralMap, a.ASTBase)
+		w.Length(len(a.Keys), a.Keys == nil)
+		for _, key := range a.Keys {
+			w.String(key.Key)
+			w.Bool(key.Quoted)
+		}
+		w.ASTs(a.Values)
+	case *Interpolation:
+		w.base(binaryInterpolation, a.ASTBase)
+		w.Strings(a.Strings)
+		w.ASTs(a.Expressions)
+	case *Binary:
+		w.base(binaryBinary, a.ASTBase)
+		w.String(a.Operation)
+		w.AST(a.Left)
+		w.AST(a.Right)
+	case *Unary:
+		w.base(binaryUnary, a.ASTBase)
+		w.String(a.Operator)
+		w.AST(a.Expr)
+	case *PrefixNot:
+		w.base(binaryPrefixNot, a.ASTBase)
+		w.AST(a.Expression)
+	case *TypeofExpression:
+		w.base(binaryTypeofExpression, a.ASTBase)
+		w.AST(a.Expression)
+	case *VoidExpression:
+		w.base(binaryVoidExpression, a.ASTBase)
+		w.AST(a.Expression)
+	case *CompoundAssignment:
+		w.base(binaryCompoundAssignment, a.ASTBase)
+		w.String(a.Operation)
+		w.AST(a.Target)
+		w.AST(a.Value)
+	case *NonNullAssert:
+		w.base(binaryNonNullAssert, a.ASTBase)
+		w.AST(a.Expression)
+	case *Call:
+		w.base(binaryCall, a.ASTBase)
+		w.AST(a.Receiver)
+		w.ASTs(a.Args)
+		w.Span(a.ArgumentSpan)
+	case *SafeCall:
+		w.base(binarySafeCall, a.ASTBase)
+		w.AST(a.Receiver)
+		w.ASTs(a.Args)
+		w.Span(a.ArgumentSpan)
+	case *TemplateLiteral:
+		w.base(binaryTemplateLiteral, a.ASTBase)
+		w.Length(len(a.Elements), a.Elements == nil)
+		for _, element := range a.Elements {
+			if element == nil {
+				w.AST(nil)
+			} else {
+				w.AST(element)
+			}
+		}
+		w.ASTs(a.Expressions)
+	case *TemplateLiteralElement:
+		w.base(binaryTemplateLiteralElement, a.ASTBase)
+		w.String(a.Text)
+	case *TaggedTemplateLiteral:
+		w.base(binaryTaggedTemplateLiteral, a.ASTBase)
+		w.AST(a.Tag)
+		if a.Template == nil {
+			w.AST(nil)
+		} else {
+			w.AST(a.Template)
+		}
+	case *ASTWithSource:
+		w.base(binaryASTWithSource, a.ASTBase)
+		w.AST(a.Ast)
+		w.String(a.Source)
+		w.String(a.Location)
+		w.ParserErrors(a.Errors)
+	default:
+		panic(fmt.Sprintf("ep: can't encode %T", ast))
+	}
+}
+
+func (w *BinaryWriter) literal(value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		w.body = append(w.body, binaryNull)
+	case undefined:
+		w.body = append(w.body, binaryUndefined)
+	case bool:
+		if v {
+			w.body = append(w.body, binaryTrue)
+		} else {
+			w.body = append(w.body, binaryFalse)
+		}
+	case float64:
+		w.body = append(w.body, binaryNumber)
+		var bits [8]byte
+		binary.LittleEndian.PutUint64(bits[:], math.Float64bits(v))
+		w.body = append(w.body, bits[:]...)
+	case string:
+		w.body = append(w.body, binaryString)
+		w.String(v)
+	default:
+		panic(fmt.Sprintf("ep: can't encode literal %T", value))
+	}
+}
+
+func (r *BinaryReader) base() ASTBase {
+	start := r.Int()
+	return ASTBase{Span: ParseSpan{Start: start, End: start + r.Int()}, SourceSpan: r.Span()}
+}
+
+func (r *BinaryReader) withName() ASTWithName {
+	return ASTWithName{ASTBase: r.base(), NameSpan: r.Span()}
+}
+
+func (r *BinaryReader) ASTs() []AST {
+	n := r.Length()
+	if n < 0 {
+		return nil
+	}
+	asts := make([]AST, n)
+	for i := range asts {
+		asts[i] = r.AST()
+	}
+	return asts
+}
+
+// AST reads an expression written by BinaryWriter.AST.
+func (r *BinaryReader) AST() AST {
+	switch kind := r.byte(); kind {
+	case binaryNil:
+		return nil
+	case binaryEmptyExpr:
+		return &EmptyExpr{ASTBase: r.base()}
+	case binaryImplicitReceiver:
+		return &ImplicitReceiver{ASTBase: r.base()}
+	case binaryThisReceiver:
+		return &ThisReceiver{ImplicitReceiver{ASTBase: r.base()}}
+	case binaryChain:
+		return &Chain{ASTBase: r.base(), Expressions: r.ASTs()}
+	case binaryConditional:
+		return &Conditional{ASTBase: r.base(), Condition: r.AST(), TrueExp: r.AST(), FalseExp: r.AST()}
+	case binaryPropertyRead:
+		return &PropertyRead{ASTWithName: r.withName(), Receiver: r.AST(), Name: r.String()}
+	case binaryPropertyWrite:
+		return &PropertyWrite{ASTWithName: r.withName(), Receiver: r.AST(), Name: r.String(), Value: r.AST()}
+	case binarySafePropertyRead:
+		return &SafePropertyRead{ASTWithName: r.withName(), Receiver: r.AST(), Name: r.String()}
+	case binaryKeyedRead:
+		return &KeyedRead{ASTBase: r.base(), Receiver: r.AST(), Key: r.AST()}
+	case binarySafeKeyedRead:
+		return &SafeKeyedRead{ASTBase: r.base(), Receiver: r.AST(), Key: r.AST()}
+	case binaryKeyedWrite:
+		return &KeyedWrite{ASTBase: r.base(), Receiver: r.AST(), Key: r.AST(), Value: r.AST()}
+	case binaryBindingPipe:
+		return &BindingPipe{ASTWithName: r.withName(), Exp: r.AST(), Name: r.String(), Args: r.ASTs()}
+	case binaryLiteralPrimitive:
+		return &LiteralPrimitive{ASTBase: r.base(), Value: r.literal()}
+	case binaryLiteralArray:
+		return &LiteralArray{ASTBase: r.base(), Expressions: r.ASTs()}
+	case binaryLiteralMap:
+		literal := &LiteralMap{ASTBase: r.base()}
+		if n := r.Length(); n >= 0 {
+			literal.Keys = make([]LiteralMapKey, n)
+			for i := range literal.Keys {
+				literal.Keys[i] = LiteralMapKey{Key: r.String(), Quoted: r.Bool()}
+			}
+		}
+		literal.Values = r.ASTs()
+		return literal
+	case binaryInterpolation:
+		return &Interpolation{ASTBase: r.base(), Strings: r.Strings(), Expressions: r.ASTs()}
+	case binaryBinary:
+		return &Binary{ASTBase: r.base(), Operation: r.String(), Left: r.AST(), Right: r.AST()}
+	case binaryUnary:
+		return &Unary{ASTBase: r.base(), Operator: r.String(), Expr: r.AST()}
+	case binaryPrefixNot:
+		return &PrefixNot{ASTBase: r.base(), Expression: r.AST()}
+	case binaryTypeofExpression:
+		return &TypeofExpression{ASTBase: r.base(), Expression: r.AST()}
+	case binaryVoidExpression:
+		return &VoidExpression{ASTBase: r.base(), Expression: r.AST()}
+	case binaryCompoundAssignment:
+		return &CompoundAssignment{ASTBase: r.base(), Operation: r.String(), Target: r.AST(), Value: r.AST()}
+	case binaryNonNullAssert:
+		return &NonNullAssert{ASTBase: r.base(), Expression: r.AST()}
+	case binaryCall:
+		return &Call{ASTBase: r.base(), Receiver: r.AST(), Args: r.ASTs(), ArgumentSpan: r.Span()}
+	case binarySafeCall:
+		return &SafeCall{ASTBase: r.base(), Receiver: r.AST(), Args: r.ASTs(), ArgumentSpan: r.Span()}
+	case binaryTemplateLiteral:
+		literal := &TemplateLiteral{ASTBase: r.base()}
+		if n := r.Length(); n >= 0 {
+			literal.Elements = make([]*TemplateLiteralElement, n)
+			for i := range literal.Elements {
+				literal.Elements[i], _ = r.AST().(*TemplateLiteralElement)
+			}
+		}
+		literal.Expressions = r.ASTs()
+		return literal
+	case binaryTemplateLiteralElement:
+		return &TemplateLiteralElement{ASTBase: r.base(), Text: r.String()}
+	case binaryTaggedTemplateLiteral:
+		literal := &TaggedTemplateLiteral{ASTBase: r.base(), Tag: r.AST()}
+		literal.Template, _ = r.AST().(*TemplateLiteral)
+		return literal
+	case binaryASTWithSource:
+		return r.astWithSource()
+	default:
+		r.fail()
+		return nil
+	}
+}
+
+// ASTWithSource reads an expression written by BinaryWriter.AST which has to
+// be nil or an *ASTWithSource.
+func (r *BinaryReader) ASTWithSource() *ASTWithSource {
+	switch kind := r.byte(); kind {
+	case binaryNil:
+		return nil
+	case binaryASTWithSource:
+		return r.astWithSource()
+	default:
+		r.fail()
+		return nil
+	}
+}
+
+func (r *BinaryReader) astWithSource() *ASTWithSource {
+	return &ASTWithSource{ASTBase: r.base(), Ast: r.AST(), Source: r.String(), Location: r.String(), Errors: r.ParserErrors()}
+}
+
+func (r *BinaryReader) literal() interface{} {
+	switch r.byte() {
+	case binaryNull:
+		return nil
+	case binaryUndefined:
+		return Undefined
+	case binaryFalse:
+		return false
+	case binaryTrue:
+		return true
+	case binaryNumber:
+		if len(r.data) < 8 {
+			r.fail()
+			return nil
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.data))
+		r.data = r.data[8:]
+		return v
+	case binaryString:
+		return r.String()
+	default:
+		r.fail()
+		return nil
+	}
+}
@@ -0,0 +1,1066 @@
+package ep
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Context resolves the names read from the implicit receiver of an
+// expression, e.g. `name` in `{{ name }}`.
+type Context interface {
+	Get(name string) (interface{}, bool)
+}
+
+// WritableContext is a Context which takes the assignments of event handlers
+// like `(click)="count = count + 1"`.
+type WritableContext interface {
+	Context
+	Set(name string, value interface{}) error
+}
+
+// Scope is a Context with local variables on top of a parent context, like the
+// template variables of an embedded view. Names which are not local are read
+// from and written to Parent, which can be any value expressions can read.
+type Scope struct {
+	Parent interface{}
+	Locals map[string]interface{}
+}
+
+func NewScope(parent interface{}, locals map[string]interface{}) *Scope {
+	return &Scope{Parent: parent, Locals: locals}
+}
+
+func (s *Scope) Get(name string) (interface{}, bool) {
+	if value, ok := s.Locals[name]; ok {
+		return value, true
+	}
+	return lookupProperty(s.Parent, name)
+}
+
+func (s *Scope) Set(name string, value interface{}) error {
+	if _, ok := s.Locals[name]; ok {
+		return fmt.Errorf("Cannot assign value %q to template variable %q. Template variables are read-only.", ToString(value), name)
+	}
+	return setProperty(s.Parent, name, value)
+}
+
+// PipeTransform is the implementation of a pipe, like the transform method of
+// an Angular pipe.
+type PipeTransform interface {
+	Transform(value interface{}, args ...interface{}) (interface{}, error)
+}
+
+// PipeFunc adapts a function to PipeTransform.
+type PipeFunc func(value interface{}, args ...interface{}) (interface{}, error)
+
+func (f PipeFunc) Transform(value interface{}, args ...interface{}) (interface{}, error) {
+	return f(value, args...)
+}
+
+// EvalError is the error of an expression, like a JavaScript TypeError.
+type EvalError struct {
+	Message    string
+	SourceSpan AbsoluteSourceSpan
+}
+
+func (e *EvalError) Error() string {
+	return e.Message
+}
+
+// Evaluator evaluates expressions with the semantics of JavaScript: values are
+// nil for null, Undefined, bool, numbers, strings, slices for arrays and maps
+// or structs for objects. The context of an expression is a Context, a map
+// with string keys or a struct, the names of struct fields and methods are
+// also matched with an upper case first letter or by their json tag.
+type Evaluator struct {
+	pipes map[string]PipeTransform
+}
+
+func NewEvaluator() *Evaluator {
+	return &Evaluator{pipes: map[string]PipeTransform{}}
+}
+
+// RegisterPipe makes a pipe available to expressions as `value | name`.
+func (e *Evaluator) RegisterPipe(name string, pipe PipeTransform) {
+	e.pipes[name] = pipe
+}
+
+// Evaluate returns the value of an expression, or the value of the last one
+// for a chain of actions.
+func (e *Evaluator) Evaluate(ast AST, context interface{}) (interface{}, error) {
+	return e.value(ast, context)
+}
+
+// shortCircuited is the value of a chain like `a?.b.c` once `a` is null, it
+// becomes undefined where the chain ends.
+type shortCircuited struct{}
+
+var shortCircuit = shortCircuited{}
+
+func (e *Evaluator) value(ast AST, context interface{}) (interface{}, error) {
+	value, err := e.eval(ast, context)
+	if value == shortCircuit {
+		value = Undefined
+	}
+	return value, err
+}
+
+func (e *Evaluator) values(asts []AST, context interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(asts))
+	for i, ast := range asts {
+		value, err := e.value(ast, context)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (e *Evaluator) error(ast AST, format string, args ...interface{}) error {
+	return &EvalError{Message: fmt.Sprintf(format, args...), SourceSpan: ast.GetSourceSpan()}
+}
+
+func (e *Evaluator) eval(ast AST, context interface{}) (interface{}, error) {
+	switch ast := ast.(type) {
+	case *ASTWithSource:
+		return e.eval(ast.Ast, context)
+	case *EmptyExpr:
+		return Undefined, nil
+	case *ImplicitReceiver, *ThisReceiver:
+		return context, nil
+	case *LiteralPrimitive:
+		return ast.Value, nil
+	case *LiteralArray:
+		return e.values(ast.Expressions, context)
+	case *LiteralMap:
+		values, err := e.values(ast.Values, context)
+		if err != nil {
+			return nil, err
+		}
+		object := make(map[string]interface{}, len(values))
+		for i, key := range ast.Keys {
+			object[key.Key] = values[i]
+		}
+		return object, nil
+	case *Interpolation:
+		values, err := e.values(ast.Expressions, context)
+		if err != nil {
+			return nil, err
+		}
+		var out strings.Builder
+		for i, str := range ast.Strings {
+			out.WriteString(str)
+			if i < len(values) {
+				out.WriteString(InterpolationString(values[i]))
+			}
+		}
+		return out.String(), nil
+	case *TemplateLiteral:
+		values, err := e.values(ast.Expressions, context)
+		if err != nil {
+			return nil, err
+		}
+		var out strings.Builder
+		for i, element := range ast.Elements {
+			out.WriteString(element.Text)
+			if i < len(values) {
+				out.WriteString(ToString(values[i]))
+			}
+		}
+		return out.String(), nil
+	case *TaggedTemplateLiteral:
+		tag, err := e.value(ast.Tag, context)
+		if err != nil {
+			return nil, err
+		}
+		values, err := e.values(ast.Template.Expressions, context)
+		if err != nil {
+			return nil, err
+		}
+		strs := make([]interface{}, len(ast.Template.Elements))
+		for i, element := range ast.Template.Elements {
+			strs[i] = element.Text
+		}
+		return e.call(ast, ast.Tag, tag, append([]interface{}{strs}, values...))
+	case *PropertyRead:
+		receiver, err := e.eval(ast.Receiver, context)
+		if err != nil || receiver == shortCircuit {
+			return receiver, err
+		}
+		if _, ok := ast.Receiver.(*ImplicitReceiver); ok {
+			return readImplicit(receiver, ast.Name), nil
+		}
+		if IsNullish(receiver) {
+			return nil, e.error(ast, "Cannot read properties of %s (reading '%s')", ToString(receiver), ast.Name)
+		}
+		return readProperty(receiver, ast.Name), nil
+	case *SafePropertyRead:
+		receiver, err := e.eval(ast.Receiver, context)
+		if err != nil || receiver == shortCircuit || IsNullish(receiver) {
+			return shortCircuit, err
+		}
+		return readProperty(receiver, ast.Name), nil
+	case *KeyedRead:
+		receiver, err := e.eval(ast.Receiver, context)
+		if err != nil || receiver == shortCircuit {
+			return receiver, err
+		}
+		key, err := e.value(ast.Key, context)
+		if err != nil {
+			return nil, err
+		}
+		if IsNullish(receiver) {
+			return nil, e.error(ast, "Cannot read properties of %s (reading '%s')", ToString(receiver), ToString(key))
+		}
+		return readKey(receiver, key), nil
+	case *SafeKeyedRead:
+		receiver, err := e.eval(ast.Receiver, context)
+		if err != nil || receiver == shortCircuit || IsNullish(receiver) {
+			return shortCircuit, err
+		}
+		key, err := e.value(ast.Key, context)
+		if err != nil {
+			return nil, err
+		}
+		return readKey(receiver, key), nil
+	case *Call:
+		function, err := e.eval(ast.Receiver, context)
+		if err != nil || function == shortCircuit {
+			return function, err
+		}
+		args, err := e.values(ast.Args, context)
+		if err != nil {
+			return nil, err
+		}
+		return e.call(ast, ast.Receiver, function, args)
+	case *SafeCall:
+		function, err := e.eval(ast.Receiver, context)
+		if err != nil || function == shortCircuit || IsNullish(function) {
+			return shortCircuit, err
+		}
+		args, err := e.values(ast.Args, context)
+		if err != nil {
+			return nil, err
+		}
+		return e.call(ast, ast.Receiver, function, args)
+	case *NonNullAssert:
+		return e.eval(ast.Expression, context)
+	case *PrefixNot:
+		value, err := e.value(ast.Expression, context)
+		return !IsTruthy(value), err
+	case *TypeofExpression:
+		value, err := e.value(ast.Expression, context)
+		return TypeOf(value), err
+	case *VoidExpression:
+		_, err := e.value(ast.Expression, context)
+		return Undefined, err
+	case *Unary:
+		value, err := e.value(ast.Expr, context)
+		if ast.Operator == "-" {
+			return -ToNumber(value), err
+		}
+		return ToNumber(value), err
+	case *Binary:
+		return e.binary(ast, context)
+	case *Conditional:
+		condition, err := e.value(ast.Condition, context)
+		if err != nil {
+			return nil, err
+		}
+		if IsTruthy(condition) {
+			return e.value(ast.TrueExp, context)
+		}
+		return e.value(ast.FalseExp, context)
+	case *Chain:
+		var value interface{} = Undefined
+		for _, expression := range ast.Expressions {
+			var err error
+			if value, err = e.value(expression, context); err != nil {
+				return nil, err
+			}
+		}
+		return value, nil
+	case *BindingPipe:
+		pipe, ok := e.pipes[ast.Name]
+		if !ok {
+			return nil, e.error(ast, "The pipe '%s' could not be found", ast.Name)
+		}
+		value, err := e.value(ast.Exp, context)
+		if err != nil {
+			return nil, err
+		}
+		args, err := e.values(ast.Args, context)
+		if err != nil {
+			return nil, err
+		}
+		return pipe.Transform(value, args...)
+	case *PropertyWrite:
+		receiver, err := e.value(ast.Receiver, context)
+		if err != nil {
+			return nil, err
+		}
+		value, err := e.value(ast.Value, context)
+		if err != nil {
+			return nil, err
+		}
+		if err := setProperty(receiver, ast.Name, value); err != nil {
+			return nil, e.error(ast, "%s", err)
+		}
+		return value, nil
+	case *KeyedWrite:
+		receiver, err := e.value(ast.Receiver, context)
+		if err != nil {
+			return nil, err
+		}
+		key, err := e.value(ast.Key, context)
+		if err != nil {
+			return nil, err
+		}
+		value, err := e.value(ast.Value, context)
+		if err != nil {
+			return nil, err
+		}
+		if err := setKey(receiver, key, value); err != nil {
+			return nil, e.error(ast, "%s", err)
+		}
+		return value, nil
+	case *CompoundAssignment:
+		return e.compoundAssignment(ast, context)
+	default:
+		return nil, e.error(ast, "Unsupported expression %T", ast)
+	}
+}
+
+func (e *Evaluator) binary(ast *Binary, context interface{}) (interface{}, error) {
+	left, err := e.value(ast.Left, context)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ast.Operation {
+	case "&&":
+		if !IsTruthy(left) {
+			return left, nil
+		}
+		return e.value(ast.Right, context)
+	case "||":
+		if IsTruthy(left) {
+			return left, nil
+		}
+		return e.value(ast.Right, context)
+	case "??":
+		if !IsNullish(left) {
+			return left, nil
+		}
+		return e.value(ast.Right, context)
+	}
+
+	right, err := e.value(ast.Right, context)
+	if err != nil {
+		return nil, err
+	}
+
+	if ast.Operation == "in" {
+		if !isObject(right) {
+			return nil, e.error(ast, "Cannot use 'in' operator to search for '%s' in %s", ToString(left), ToString(right))
+		}
+		_, ok := lookupKey(right, left)
+		return ok, nil
+	}
+
+	return binaryOperation(ast.Operation, left, right), nil
+}
+
+func (e *Evaluator) compoundAssignment(ast *CompoundAssignment, context interface{}) (interface{}, error) {
+	current, err := e.value(ast.Target, context)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ast.Operation {
+	case "&&=":
+		if !IsTruthy(current) {
+			return current, nil
+		}
+	case "||=":
+		if IsTruthy(current) {
+			return current, nil
+		}
+	case "??=":
+		if !IsNullish(current) {
+			return current, nil
+		}
+	}
+
+	value, err := e.value(ast.Value, context)
+	if err != nil {
+		return nil, err
+	}
+	if operation := strings.TrimSuffix(ast.Operation, "="); operation != "&&" && operation != "||" && operation != "??" {
+		value = binaryOperation(operation, current, value)
+	}
+
+	switch target := ast.Target.(type) {
+	case *PropertyRead:
+		receiver, err := e.value(target.Receiver, context)
+		if err != nil {
+			return nil, err
+		}
+		err = setProperty(receiver, target.Name, value)
+		if err != nil {
+			return nil, e.error(ast, "%s", err)
+		}
+	case *KeyedRead:
+		receiver, err := e.value(target.Receiver, context)
+		if err != nil {
+			return nil, err
+		}
+		key, err := e.value(target.Key, context)
+		if err != nil {
+			return nil, err
+		}
+		if err := setKey(receiver, key, value); err != nil {
+			return nil, e.error(ast, "%s", err)
+		}
+	default:
+		return nil, e.error(ast, "Invalid left-hand side in assignment")
+	}
+
+	return value, nil
+}
+
+// call calls a Go function with JavaScript arguments, missing arguments are
+// zero values. A trailing error result is returned as the error of the call.
+func (e *Evaluator) call(ast AST, receiver AST, function interface{}, args []interface{}) (result interface{}, err error) {
+	fn := reflect.ValueOf(function)
+	if !fn.IsValid() || fn.Kind() != reflect.Func || fn.IsNil() {
+		return nil, e.error(ast, "%s is not a function", Print(receiver))
+	}
+
+	fnType := fn.Type()
+	var in []reflect.Value
+	for i := 0; i < fnType.NumIn(); i++ {
+		if fnType.IsVariadic() && i == fnType.NumIn()-1 {
+			for j := i; j < len(args); j++ {
+				arg, err := convertValue(args[j], fnType.In(i).Elem())
+				if err != nil {
+					return nil, e.error(ast, "%s", err)
+				}
+				in = append(in, arg)
+			}
+			break
+		}
+
+		var value interface{} = Undefined
+		if i < len(args) {
+			value = args[i]
+		}
+		arg, err := convertValue(value, fnType.In(i))
+		if err != nil {
+			return nil, e.error(ast, "%s", err)
+		}
+		in = append(in, arg)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = e.error(ast, "%v", r)
+		}
+	}()
+
+	out := fn.Call(in)
+	if len(out) > 0 && fnType.Out(len(out)-1) == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:len(out)-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return Undefined, nil
+	case 1:
+		return out[0].Interface(), nil
+	default:
+		results := make([]interface{}, len(out))
+		for i, value := range out {
+			results[i] = value.Interface()
+		}
+		return results, nil
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func binaryOperation(operation string, left interface{}, right interface{}) interface{} {
+	switch operation {
+	case "+":
+		if isStringLike(left) || isStringLike(right) {
+			return ToString(left) + ToString(right)
+		}
+		return ToNumber(left) + ToNumber(right)
+	case "-":
+		return ToNumber(left) - ToNumber(right)
+	case "*":
+		return ToNumber(left) * ToNumber(right)
+	case "/":
+		return ToNumber(left) / ToNumber(right)
+	case "%":
+		return math.Mod(ToNumber(left), ToNumber(right))
+	case "**":
+		return math.Pow(ToNumber(left), ToNumber(right))
+	case "==":
+		return LooseEquals(left, right)
+	case "!=":
+		return !LooseEquals(left, right)
+	case "===":
+		return StrictEquals(left, right)
+	case "!==":
+		return !StrictEquals(left, right)
+	case "<", ">", "<=", ">=":
+		return compare(operation, left, right)
+	}
+	return Undefined
+}
+
+// compare compares strings by their UTF-16 code units like JS and everything
+// else as numbers, comparisons with NaN are false.
+func compare(operation string, left interface{}, right interface{}) bool {
+	if isStringLike(left) && isStringLike(right) {
+		order := compareUTF16(ToString(left), ToString(right))
+		switch operation {
+		case "<":
+			return order < 0
+		case ">":
+			return order > 0
+		case "<=":
+			return order <= 0
+		default:
+			return order >= 0
+		}
+	}
+
+	l, r := ToNumber(left), ToNumber(right)
+	switch operation {
+	case "<":
+		return l < r
+	case ">":
+		return l > r
+	case "<=":
+		return l <= r
+	default:
+		return l >= r
+	}
+}
+
+// compareUTF16 returns -1, 0 or 1 like strings.Compare but orders the strings
+// by their UTF-16 code units, so "\uff61" sorts after "😀", whose first unit is
+// a surrogate.
+func compareUTF16(a string, b string) int {
+	for a != "" && b != "" {
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			unitsA, unitsB := utf16.Encode([]rune{ra}), utf16.Encode([]rune{rb})
+			for i := 0; i < len(unitsA) && i < len(unitsB); i++ {
+				if unitsA[i] != unitsB[i] {
+					if unitsA[i] < unitsB[i] {
+						return -1
+					}
+					return 1
+				}
+			}
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// IsNullish reports whether a value is null or undefined, nil pointers and
+// functions are null.
+func IsNullish(value interface{}) bool {
+	if value == nil || value == Undefined {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Func, reflect.Interface, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// IsTruthy converts a value to a boolean like JavaScript, only false, 0, NaN,
+// "", null and undefined are false.
+func IsTruthy(value interface{}) bool {
+	if IsNullish(value) {
+		return false
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Kind() == reflect.Bool:
+		return v.Bool()
+	case v.Kind() == reflect.String:
+		return v.Len() > 0
+	case isNumber(value):
+		n := ToNumber(value)
+		return n != 0 && !math.IsNaN(n)
+	}
+	return true
+}
+
+// TypeOf returns the result of the typeof operator.
+func TypeOf(value interface{}) string {
+	if value == Undefined {
+		return "undefined"
+	}
+	if IsNullish(value) {
+		return "object"
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Func:
+		return "function"
+	}
+	if isNumber(value) {
+		return "number"
+	}
+	return "object"
+}
+
+func isNumber(value interface{}) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isObject reports whether a value is neither a primitive nor a function.
+func isObject(value interface{}) bool {
+	return TypeOf(value) == "object" && !IsNullish(value)
+}
+
+// isStringLike reports whether a value is a string or converts to one in
+// arithmetic, like arrays and objects.
+func isStringLike(value interface{}) bool {
+	return TypeOf(value) == "string" || isObject(value)
+}
+
+// ToNumber converts a value to a number like JavaScript's Number().
+func ToNumber(value interface{}) float64 {
+	if value == Undefined {
+		return math.NaN()
+	}
+	if IsNullish(value) {
+		return 0
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return stringToNumber(v.String())
+	case reflect.Slice, reflect.Array:
+		return stringToNumber(ToString(value))
+	}
+	return math.NaN()
+}
+
+func stringToNumber(str string) float64 {
+	str = strings.TrimSpace(str)
+	switch str {
+	case "":
+		return 0
+	case "Infinity", "+Infinity":
+		return math.Inf(1)
+	case "-Infinity":
+		return math.Inf(-1)
+	}
+
+	if len(str) > 2 && str[0] == '0' && strings.ContainsRune("xXoObB", rune(str[1])) {
+		if n, err := strconv.ParseUint(str, 0, 64); err == nil {
+			return float64(n)
+		}
+		return math.NaN()
+	}
+
+	// ParseFloat also takes "inf", "nan" and hexadecimal floats
+	for _, c := range str {
+		if !strings.ContainsRune("0123456789+-.eE", c) {
+			return math.NaN()
+		}
+	}
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil && !strings.Contains(err.Error(), "range") {
+		return math.NaN()
+	}
+	return n
+}
+
+// ToString converts a value to a string like JavaScript's String(), values
+// implementing fmt.Stringer use their String method.
+func ToString(value interface{}) string {
+	if value == Undefined {
+		return "undefined"
+	}
+	if IsNullish(value) {
+		return "null"
+	}
+	if stringer, ok := value.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return formatNumber(v.Float())
+	case reflect.Slice, reflect.Array:
+		elements := make([]string, v.Len())
+		for i := range elements {
+			if element := v.Index(i).Interface(); !IsNullish(element) {
+				elements[i] = ToString(element)
+			}
+		}
+		return strings.Join(elements, ",")
+	case reflect.Func:
+		return "function"
+	}
+	return "[object Object]"
+}
+
+// InterpolationString converts the value of an interpolation to a string,
+// null and undefined become "".
+func InterpolationString(value interface{}) string {
+	if IsNullish(value) {
+		return ""
+	}
+	return ToString(value)
+}
+
+// StrictEquals compares values like `===`, objects are equal if they are the
+// same object.
+func StrictEquals(left interface{}, right interface{}) bool {
+	if IsNullish(left) || IsNullish(right) {
+		return IsNullish(left) && IsNullish(right) && (left == Undefined) == (right == Undefined)
+	}
+	if isNumber(left) && isNumber(right) {
+		return ToNumber(left) == ToNumber(right)
+	}
+
+	l, r := reflect.ValueOf(left), reflect.ValueOf(right)
+	if l.Kind() != r.Kind() {
+		return false
+	}
+
+	switch l.Kind() {
+	case reflect.Bool:
+		return l.Bool() == r.Bool()
+	case reflect.String:
+		return l.String() == r.String()
+	case reflect.Map, reflect.Ptr, reflect.Func:
+		return l.Type() == r.Type() && l.Pointer() == r.Pointer()
+	case reflect.Slice:
+		return l.Type() == r.Type() && l.Pointer() == r.Pointer() && l.Len() == r.Len()
+	}
+
+	return l.Type() == r.Type() && l.Type().Comparable() && left == right
+}
+
+// LooseEquals compares values like `==`: null equals undefined, and primitives
+// of different types are compared as numbers.
+func LooseEquals(left interface{}, right interface{}) bool {
+	if IsNullish(left) || IsNullish(right) {
+		return IsNullish(left) && IsNullish(right)
+	}
+	if isObject(left) && isObject(right) {
+		return StrictEquals(left, right)
+	}
+
+	if isObject(left) {
+		left = ToString(left)
+	}
+	if isObject(right) {
+		right = ToString(right)
+	}
+	if TypeOf(left) == "string" && TypeOf(right) == "string" {
+		return ToString(left) == ToString(right)
+	}
+	if TypeOf(left) == "function" || TypeOf(right) == "function" {
+		return StrictEquals(left, right)
+	}
+	return ToNumber(left) == ToNumber(right)
+}
+
+// readImplicit reads a name from the context of an expression, `$any()` is
+// the identity function unless the context defines it.
+func readImplicit(context interface{}, name string) interface{} {
+	if value, ok := lookupProperty(context, name); ok {
+		return value
+	}
+	if name == "$any" {
+		return func(value interface{}) interface{} { return value }
+	}
+	return Undefined
+}
+
+func readProperty(object interface{}, name string) interface{} {
+	if value, ok := lookupProperty(object, name); ok {
+		return value
+	}
+	return Undefined
+}
+
+func readKey(object interface{}, key interface{}) interface{} {
+	if value, ok := lookupKey(object, key); ok {
+		return value
+	}
+	return Undefined
+}
+
+// lookupKey reads `object[key]`, numeric keys index arrays and strings.
+func lookupKey(object interface{}, key interface{}) (interface{}, bool) {
+	v := indirect(reflect.ValueOf(object))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		index, ok := arrayIndex(key)
+		if !ok {
+			break
+		}
+		if v.Kind() == reflect.String {
+			runes := []rune(v.String())
+			if index < len(runes) {
+				return string(runes[index]), true
+			}
+			return nil, false
+		}
+		if index < v.Len() {
+			return v.Index(index).Interface(), true
+		}
+		return nil, false
+	}
+
+	return lookupProperty(object, ToString(key))
+}
+
+// arrayIndex returns a key which is a non-negative integer or a string of one.
+func arrayIndex(key interface{}) (int, bool) {
+	if TypeOf(key) != "number" && TypeOf(key) != "string" {
+		return 0, false
+	}
+	n := ToNumber(key)
+	if n < 0 || n != math.Trunc(n) || n > math.MaxInt32 {
+		return 0, false
+	}
+	if TypeOf(key) == "string" && ToString(key) != formatNumber(n) {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// lookupProperty reads a property of a Context, a map, a struct field or
+// method, or the length of an array or a string.
+func lookupProperty(object interface{}, name string) (interface{}, bool) {
+	if IsNullish(object) {
+		return nil, false
+	}
+	if context, ok := object.(Context); ok {
+		return context.Get(name)
+	}
+
+	v := reflect.ValueOf(object)
+	elem := indirect(v)
+
+	switch elem.Kind() {
+	case reflect.Map:
+		if elem.Type().Key().Kind() == reflect.String {
+			value := elem.MapIndex(reflect.ValueOf(name).Convert(elem.Type().Key()))
+			if value.IsValid() {
+				return value.Interface(), true
+			}
+		}
+	case reflect.Struct:
+		if field := structField(elem, name); field.IsValid() {
+			return field.Interface(), true
+		}
+	case reflect.Slice, reflect.Array, reflect.String:
+		if name == "length" {
+			if elem.Kind() == reflect.String {
+				return float64(utf8.RuneCountInString(elem.String())), true
+			}
+			return float64(elem.Len()), true
+		}
+	}
+
+	for _, methodName := range goNames(name) {
+		if method := v.MethodByName(methodName); method.IsValid() {
+			return method.Interface(), true
+		}
+	}
+
+	return nil, false
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// goNames returns the names a Go field or method of an expression name can
+// have, i.e. the name itself and with an upper case first letter.
+func goNames(name string) []string {
+	first, size := utf8.DecodeRuneInString(name)
+	if unicode.IsUpper(first) || size == 0 {
+		return []string{name}
+	}
+	return []string{name, string(unicode.ToUpper(first)) + name[size:]}
+}
+
+// structField returns the exported field of a struct by its name, its name
+// with an upper case first letter or its json tag.
+func structField(v reflect.Value, name string) reflect.Value {
+	for _, fieldName := range goNames(name) {
+		if field, ok := v.Type().FieldByName(fieldName); ok && field.PkgPath == "" {
+			return v.FieldByIndex(field.Index)
+		}
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath == "" && strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return v.Field(i)
+		}
+	}
+
+	return reflect.Value{}
+}
+
+// setProperty assigns a property of a WritableContext, a map or a struct
+// passed by pointer.
+func setProperty(object interface{}, name string, value interface{}) error {
+	if IsNullish(object) {
+		return fmt.Errorf("Cannot set properties of %s (setting '%s')", ToString(object), name)
+	}
+	if context, ok := object.(WritableContext); ok {
+		return context.Set(name, value)
+	}
+
+	v := reflect.ValueOf(object)
+	elem := indirect(v)
+
+	switch elem.Kind() {
+	case reflect.Map:
+		if elem.Type().Key().Kind() == reflect.String && !elem.IsNil() {
+			converted, err := convertValue(value, elem.Type().Elem())
+			if err != nil {
+				return err
+			}
+			elem.SetMapIndex(reflect.ValueOf(name).Convert(elem.Type().Key()), converted)
+			return nil
+		}
+	case reflect.Struct:
+		if field := structField(elem, name); field.IsValid() && field.CanSet() {
+			converted, err := convertValue(value, field.Type())
+			if err != nil {
+				return err
+			}
+			field.Set(converted)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Cannot set property '%s' of %s", name, ToString(object))
+}
+
+// setKey assigns `object[key]`, numeric keys index arrays.
+func setKey(object interface{}, key interface{}, value interface{}) error {
+	v := indirect(reflect.ValueOf(object))
+	if index, ok := arrayIndex(key); ok && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+		if index >= v.Len() || !v.Index(index).CanSet() {
+			return fmt.Errorf("Cannot set index %d of %s", index, ToString(object))
+		}
+		converted, err := convertValue(value, v.Type().Elem())
+		if err != nil {
+			return err
+		}
+		v.Index(index).Set(converted)
+		return nil
+	}
+
+	return setProperty(object, ToString(key), value)
+}
+
+// convertValue converts a value for a Go variable of type t, null and
+// undefined become the zero value and numbers are converted between kinds.
+func convertValue(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if IsNullish(value) {
+		return reflect.Zero(t), nil
+	}
+	if isNumber(value) && isNumber(reflect.Zero(t).Interface()) {
+		return v.Convert(t), nil
+	}
+	if v.Kind() == reflect.Slice && t.Kind() == reflect.Slice {
+		converted := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			element, err := convertValue(v.Index(i).Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			converted.Index(i).Set(element)
+		}
+		return converted, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", ToString(value), t)
+}
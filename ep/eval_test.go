@@ -0,0 +1,262 @@
+package ep
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+type evalAddress struct {
+	City string
+}
+
+type evalPerson struct {
+	FirstName string `json:"first_name"`
+	Age       int
+	Address   *evalAddress
+	Tags      []string
+}
+
+func (p *evalPerson) Greet(greeting string) string {
+	return greeting + ", " + p.FirstName
+}
+
+func (p *evalPerson) Fail() (string, error) {
+	return "", errors.New("failed")
+}
+
+func evalContext() map[string]interface{} {
+	return map[string]interface{}{
+		"person":  &evalPerson{FirstName: "Ada", Age: 36, Address: &evalAddress{City: "London"}},
+		"nobody":  (*evalPerson)(nil),
+		"empty":   []string{},
+		"items":   []interface{}{1.0, "two", nil},
+		"zero":    0.0,
+		"count":   1.0,
+		"name":    nil,
+		"flag":    false,
+		"lookup":  map[string]interface{}{"a": 1.0},
+		"concat":  func(a string, b string) string { return a + b },
+		"compute": func(values ...float64) float64 { return values[0] * values[1] },
+	}
+}
+
+func testEvaluator() *Evaluator {
+	evaluator := NewEvaluator()
+	evaluator.RegisterPipe("double", PipeFunc(func(value interface{}, args ...interface{}) (interface{}, error) {
+		return ToNumber(value) * 2, nil
+	}))
+	evaluator.RegisterPipe("wrap", PipeFunc(func(value interface{}, args ...interface{}) (interface{}, error) {
+		return ToString(args[0]) + ToString(value) + ToString(args[1]), nil
+	}))
+	return evaluator
+}
+
+// describeValue returns the JS type and the string of a value, e.g. "number 3".
+func describeValue(value interface{}) string {
+	return TypeOf(value) + " " + ToString(value)
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		// truthiness
+		{"!!''", "boolean false"},
+		{"!!'0'", "boolean true"},
+		{"!!0", "boolean false"},
+		{"!!(0 / 0)", "boolean false"},
+		{"!!null", "boolean false"},
+		{"!!undefined", "boolean false"},
+		{"!!empty", "boolean true"},
+		{"!!{}", "boolean true"},
+		{"!!nobody", "boolean false"},
+		{"zero || 'default'", "string default"},
+		{"flag && missing.property", "boolean false"},
+
+		// equality
+		{"1 == '1'", "boolean true"},
+		{"1 === '1'", "boolean false"},
+		{"null == undefined", "boolean true"},
+		{"null === undefined", "boolean false"},
+		{"0 == ''", "boolean true"},
+		{"'0' == false", "boolean true"},
+		{"0 / 0 == 0 / 0", "boolean false"},
+		{"person == person", "boolean true"},
+		{"person === nobody", "boolean false"},
+		{"nobody == null", "boolean true"},
+		{"items == '1,two,'", "boolean true"},
+
+		// + coercion
+		{"1 + 2", "number 3"},
+		{"1 + '2'", "string 12"},
+		{"'a' + null", "string anull"},
+		{"'a' + undefined", "string aundefined"},
+		{"1 + null", "number 1"},
+		{"1 + undefined", "number NaN"},
+		{"true + 1", "number 2"},
+		{"'' + items", "string 1,two,"},
+		{"'' + -0", "string 0"},
+		{"person.age + 1", "number 37"},
+
+		// ??
+		{"null ?? 'a'", "string a"},
+		{"zero ?? 'a'", "number 0"},
+		{"'' ?? 1", "string "},
+		{"undefined ?? null ?? 3", "number 3"},
+		{"name ?? missing", "undefined undefined"},
+
+		// comparison
+		{"'a' < 'b'", "boolean true"},
+		{"'10' < '9'", "boolean true"},
+		{"'10' < 9", "boolean false"},
+		{"'｡' > '😀'", "boolean true"},
+		{"'😀' < '｡'", "boolean true"},
+		{"'ab' >= 'a'", "boolean true"},
+		{"1 < 0 / 0", "boolean false"},
+
+		// safe navigation and calls
+		{"nobody?.address.city", "undefined undefined"},
+		{"person?.address?.city", "string London"},
+		{"nobody?.greet('Hi')", "undefined undefined"},
+		{"person.greet('Hi')", "string Hi, Ada"},
+		{"person?.greet?.('Hello')", "string Hello, Ada"},
+		{"items?.[1]", "string two"},
+		{"nobody?.tags[0]", "undefined undefined"},
+		{"concat('a', 'b')", "string ab"},
+		{"compute(2, 3)", "number 6"},
+		{"lookup['a']", "number 1"},
+		{"lookup.b", "undefined undefined"},
+		{"items.length", "number 3"},
+
+		// struct fields by name, lower case name and json tag
+		{"person.FirstName", "string Ada"},
+		{"person.firstName", "string Ada"},
+		{"person.first_name", "string Ada"},
+		{"person.age", "number 36"},
+		{"person.address.city", "string London"},
+
+		// pipes
+		{"2 | double", "number 4"},
+		{"2 | double | double", "number 8"},
+		{"person.firstName | wrap:'<':'>'", "string <Ada>"},
+		{"(1 + 2 | double) + 1", "number 7"},
+	}
+
+	evaluator := testEvaluator()
+	for _, test := range tests {
+		ast := NewParser().ParseBinding(test.input, "", 0)
+		if len(ast.Errors) > 0 {
+			t.Errorf("ParseBinding(%q): %v", test.input, ast.Errors)
+			continue
+		}
+		value, err := evaluator.Evaluate(ast, evalContext())
+		if err != nil {
+			t.Errorf("Evaluate(%q): %v", test.input, err)
+			continue
+		}
+		if got := describeValue(value); got != test.want {
+			t.Errorf("Evaluate(%q) = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"missing.property", "Cannot read properties of undefined (reading 'property')"},
+		{"nobody.address", "Cannot read properties of null (reading 'address')"},
+		{"person.missing()", "person.missing is not a function"},
+		{"person.fail()", "failed"},
+		{"1 | unknown", "The pipe 'unknown' could not be found"},
+	}
+
+	evaluator := testEvaluator()
+	for _, test := range tests {
+		ast := NewParser().ParseBinding(test.input, "", 0)
+		if _, err := evaluator.Evaluate(ast, evalContext()); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Evaluate(%q) = %v, want the error %q", test.input, err, test.want)
+		}
+	}
+}
+
+func TestEvaluateAssignments(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+		want  string
+	}{
+		{"count = 5", "count", "number 5"},
+		{"count += 2", "count", "number 3"},
+		{"count -= 2", "count", "number -1"},
+		{"count *= 4", "count", "number 4"},
+		{"count /= 4", "count", "number 0.25"},
+		{"count %= 1", "count", "number 0"},
+		{"count **= 3", "count", "number 1"},
+		{"count += 'px'", "count", "string 1px"},
+		{"name ??= 'x'", "name", "string x"},
+		{"count ??= 7", "count", "number 1"},
+		{"flag ||= true", "flag", "boolean true"},
+		{"count &&= 9", "count", "number 9"},
+		{"zero &&= 9", "zero", "number 0"},
+	}
+
+	evaluator := testEvaluator()
+	for _, test := range tests {
+		context := evalContext()
+		ast := NewParser().ParseAction(test.input, "", 0)
+		if len(ast.Errors) > 0 {
+			t.Errorf("ParseAction(%q): %v", test.input, ast.Errors)
+			continue
+		}
+		if _, err := evaluator.Evaluate(ast, context); err != nil {
+			t.Errorf("Evaluate(%q): %v", test.input, err)
+			continue
+		}
+		if got := describeValue(context[test.name]); got != test.want {
+			t.Errorf("Evaluate(%q) set %s to %s, want %s", test.input, test.name, got, test.want)
+		}
+	}
+
+	context := evalContext()
+	for _, input := range []string{"lookup.a += 1", "person.age += 1", "person.firstName += '!'"} {
+		if _, err := evaluator.Evaluate(NewParser().ParseAction(input, "", 0), context); err != nil {
+			t.Errorf("Evaluate(%q): %v", input, err)
+		}
+	}
+	person := context["person"].(*evalPerson)
+	if got := fmt.Sprintf("%v %v %v", context["lookup"].(map[string]interface{})["a"], person.Age, person.FirstName); got != "2 37 Ada!" {
+		t.Errorf("the assignments set %s, want 2 37 Ada!", got)
+	}
+}
+
+func TestToString(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{math.Copysign(0, -1), "0"},
+		{0.0, "0"},
+		{1.5, "1.5"},
+		{-2.0, "-2"},
+		{1e21, "1e+21"},
+		{1e-7, "1e-7"},
+		{math.Inf(-1), "-Infinity"},
+		{math.NaN(), "NaN"},
+		{nil, "null"},
+		{Undefined, "undefined"},
+		{[]interface{}{1.0, nil, "a"}, "1,,a"},
+		{map[string]interface{}{}, "[object Object]"},
+	}
+
+	for _, test := range tests {
+		if got := ToString(test.value); got != test.want {
+			t.Errorf("ToString(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
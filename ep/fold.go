@@ -0,0 +1,370 @@
+package ep
+
+import (
+	"math"
+)
+
+// FoldWarning reports a condition whose value is known without evaluating
+// the expression, like the `false` in `false && user`.
+type FoldWarning struct {
+	Message    string
+	SourceSpan AbsoluteSourceSpan
+}
+
+func (w FoldWarning) Error() string {
+	return w.Message
+}
+
+// Fold returns ast with its constant subexpressions replaced by their value:
+// arithmetic and comparisons of literals, string concatenation, template
+// literals, `!`, `typeof` and unary operators of literals, and `&&`, `||`,
+// `??` and conditionals whose left operand or condition is a literal. The
+// replacements keep the spans of the expressions they replace, ast itself is
+// not modified. Conditions which are always true or false are reported.
+func Fold(ast AST) (AST, []FoldWarning) {
+	f := newFolder()
+	return f.fold(ast), f.warnings
+}
+
+// FoldCondition folds an expression used as a condition, like the value of
+// `*ngIf` or `@if`, and also reports it if it is always true or false unless
+// a part of it was reported already.
+func FoldCondition(ast AST) (AST, []FoldWarning) {
+	f := newFolder()
+	folded := f.fold(ast)
+	if len(f.warnings) == 0 {
+		f.checkCondition(folded, ast)
+	}
+	return folded, f.warnings
+}
+
+// ConstantValue returns the value of an expression which is a literal, e.g.
+// after folding.
+func ConstantValue(ast AST) (interface{}, bool) {
+	switch ast := ast.(type) {
+	case *ASTWithSource:
+		return ConstantValue(ast.Ast)
+	case *LiteralPrimitive:
+		return ast.Value, true
+	}
+	return nil, false
+}
+
+type folder struct {
+	warnings []FoldWarning
+	// constants are the values of folded expressions which have no literal,
+	// like `1/0`, they stay in the tree but are still known.
+	constants map[AST]interface{}
+}
+
+func newFolder() *folder {
+	return &folder{constants: map[AST]interface{}{}}
+}
+
+// constant returns the value of a folded expression if it is known.
+func (f *folder) constant(ast AST) (interface{}, bool) {
+	if value, ok := ConstantValue(ast); ok {
+		return value, true
+	}
+	if source, ok := ast.(*ASTWithSource); ok {
+		ast = source.Ast
+	}
+	value, ok := f.constants[ast]
+	return value, ok
+}
+
+// replace returns the literal which replaces original, or folded with its
+// value remembered if value has no literal.
+func (f *folder) replace(original AST, folded AST, value interface{}) AST {
+	if replaced := literal(original, value); replaced != nil {
+		return replaced
+	}
+	f.constants[folded] = value
+	return folded
+}
+
+// literal returns the literal which replaces original, or nil if value has
+// no literal, like NaN.
+func literal(original AST, value interface{}) AST {
+	if number, ok := value.(float64); ok && (math.IsNaN(number) || math.IsInf(number, 0)) {
+		return nil
+	}
+	return &LiteralPrimitive{ASTBase: ASTBase{Span: original.GetSpan(), SourceSpan: original.GetSourceSpan()}, Value: value}
+}
+
+// truthiness returns whether a folded expression is truthy if that is known
+// and it can be dropped without losing side effects: literals, and array and
+// object literals of literals, which are always truthy.
+func (f *folder) truthiness(ast AST) (bool, bool) {
+	if value, ok := f.constant(ast); ok {
+		return IsTruthy(value), true
+	}
+
+	switch ast := ast.(type) {
+	case *ASTWithSource:
+		return f.truthiness(ast.Ast)
+	case *LiteralArray:
+		for _, expression := range ast.Expressions {
+			if _, ok := ConstantValue(expression); !ok {
+				return false, false
+			}
+		}
+		return true, true
+	case *LiteralMap:
+		for _, value := range ast.Values {
+			if _, ok := ConstantValue(value); !ok {
+				return false, false
+			}
+		}
+		return true, true
+	}
+	return false, false
+}
+
+// conditionTruthiness is like truthiness but also knows conditions which
+// can't be folded, like `user && false`, which is falsy but reads user.
+func (f *folder) conditionTruthiness(ast AST) (bool, bool) {
+	if truthy, ok := f.truthiness(ast); ok {
+		return truthy, true
+	}
+
+	if source, ok := ast.(*ASTWithSource); ok {
+		return f.conditionTruthiness(source.Ast)
+	}
+	binary, ok := ast.(*Binary)
+	if !ok || (binary.Operation != "&&" && binary.Operation != "||") {
+		return false, false
+	}
+	// `a && falsy` is falsy and `a || truthy` is truthy
+	decisive := binary.Operation == "||"
+	if truthy, ok := f.conditionTruthiness(binary.Right); ok && truthy == decisive {
+		return decisive, true
+	}
+	return false, false
+}
+
+// checkCondition reports a folded condition with a known value, original is
+// the condition before folding, for the message.
+func (f *folder) checkCondition(condition AST, original AST) {
+	truthy, ok := f.conditionTruthiness(condition)
+	if !ok {
+		return
+	}
+
+	always := "false"
+	if truthy {
+		always = "true"
+	}
+	f.warnings = append(f.warnings, FoldWarning{
+		Message:    "Condition [" + Print(original) + "] is always " + always,
+		SourceSpan: original.GetSourceSpan(),
+	})
+}
+
+func (f *folder) folds(asts []AST) []AST {
+	folded := make([]AST, len(asts))
+	for i, ast := range asts {
+		folded[i] = f.fold(ast)
+	}
+	return folded
+}
+
+func (f *folder) fold(ast AST) AST {
+	switch ast := ast.(type) {
+	case nil:
+		return nil
+	case *ASTWithSource:
+		folded := *ast
+		folded.Ast = f.fold(ast.Ast)
+		return &folded
+	case *LiteralArray:
+		folded := *ast
+		folded.Expressions = f.folds(ast.Expressions)
+		return &folded
+	case *LiteralMap:
+		folded := *ast
+		folded.Values = f.folds(ast.Values)
+		return &folded
+	case *Interpolation:
+		folded := *ast
+		folded.Expressions = f.folds(ast.Expressions)
+		return &folded
+	case *TemplateLiteral:
+		folded := *ast
+		folded.Expressions = f.folds(ast.Expressions)
+		text := ""
+		for i, element := range folded.Elements {
+			text += element.Text
+			if i < len(folded.Expressions) {
+				value, ok := f.constant(folded.Expressions[i])
+				if !ok {
+					return &folded
+				}
+				text += ToString(value)
+			}
+		}
+		return literal(ast, text)
+	case *TaggedTemplateLiteral:
+		folded := *ast
+		folded.Tag = f.fold(ast.Tag)
+		template := *ast.Template
+		template.Expressions = f.folds(ast.Template.Expressions)
+		folded.Template = &template
+		return &folded
+	case *PropertyRead:
+		folded := *ast
+		folded.Receiver = f.fold(ast.Receiver)
+		return &folded
+	case *SafePropertyRead:
+		folded := *ast
+		folded.Receiver = f.fold(ast.Receiver)
+		return &folded
+	case *KeyedRead:
+		folded := *ast
+		folded.Receiver = f.fold(ast.Receiver)
+		folded.Key = f.fold(ast.Key)
+		return &folded
+	case *SafeKeyedRead:
+		folded := *ast
+		folded.Receiver = f.fold(ast.Receiver)
+		folded.Key = f.fold(ast.Key)
+		return &folded
+	case *PropertyWrite:
+		folded := *ast
+		folded.Receiver = f.fold(ast.Receiver)
+		folded.Value = f.fold(ast.Value)
+		return &folded
+	case *KeyedWrite:
+		folded := *ast
+		folded.Receiver = f.fold(ast.Receiver)
+		folded.Key = f.fold(ast.Key)
+		folded.Value = f.fold(ast.Value)
+		return &folded
+	case *CompoundAssignment:
+		// the target is written, it stays as it is
+		folded := *ast
+		folded.Value = f.fold(ast.Value)
+		return &folded
+	case *Call:
+		folded := *ast
+		folded.Receiver = f.fold(ast.Receiver)
+		folded.Args = f.folds(ast.Args)
+		return &folded
+	case *SafeCall:
+		folded := *ast
+		folded.Receiver = f.fold(ast.Receiver)
+		folded.Args = f.folds(ast.Args)
+		return &folded
+	case *BindingPipe:
+		// pipes run at runtime, only their input and arguments are folded
+		folded := *ast
+		folded.Exp = f.fold(ast.Exp)
+		folded.Args = f.folds(ast.Args)
+		return &folded
+	case *Chain:
+		folded := *ast
+		folded.Expressions = f.folds(ast.Expressions)
+		return &folded
+	case *NonNullAssert:
+		folded := *ast
+		folded.Expression = f.fold(ast.Expression)
+		if _, ok := f.constant(folded.Expression); ok {
+			return folded.Expression
+		}
+		return &folded
+	case *PrefixNot:
+		folded := *ast
+		folded.Expression = f.fold(ast.Expression)
+		if value, ok := f.constant(folded.Expression); ok {
+			return literal(ast, !IsTruthy(value))
+		}
+		return &folded
+	case *TypeofExpression:
+		folded := *ast
+		folded.Expression = f.fold(ast.Expression)
+		if value, ok := f.constant(folded.Expression); ok {
+			return literal(ast, TypeOf(value))
+		}
+		return &folded
+	case *VoidExpression:
+		folded := *ast
+		folded.Expression = f.fold(ast.Expression)
+		if _, ok := f.constant(folded.Expression); ok {
+			return literal(ast, Undefined)
+		}
+		return &folded
+	case *Unary:
+		folded := *ast
+		folded.Expr = f.fold(ast.Expr)
+		if value, ok := f.constant(folded.Expr); ok {
+			number := ToNumber(value)
+			if ast.Operator == "-" {
+				number = -number
+			}
+			return f.replace(ast, &folded, number)
+		}
+		return &folded
+	case *Binary:
+		return f.foldBinary(ast)
+	case *Conditional:
+		folded := *ast
+		folded.Condition = f.fold(ast.Condition)
+		folded.TrueExp = f.fold(ast.TrueExp)
+		folded.FalseExp = f.fold(ast.FalseExp)
+
+		f.checkCondition(folded.Condition, ast.Condition)
+		if truthy, ok := f.truthiness(folded.Condition); ok {
+			if truthy {
+				return folded.TrueExp
+			}
+			return folded.FalseExp
+		}
+		return &folded
+	default:
+		// receivers, literals and empty expressions
+		return ast
+	}
+}
+
+func (f *folder) foldBinary(ast *Binary) AST {
+	folded := *ast
+	folded.Left = f.fold(ast.Left)
+	folded.Right = f.fold(ast.Right)
+
+	switch ast.Operation {
+	case "&&", "||":
+		f.checkCondition(folded.Left, ast.Left)
+		truthy, ok := f.truthiness(folded.Left)
+		if !ok {
+			return &folded
+		}
+		// the left operand is the value if it decides the result
+		if truthy == (ast.Operation == "||") {
+			return folded.Left
+		}
+		return folded.Right
+	case "??":
+		left, ok := f.constant(folded.Left)
+		if !ok {
+			return &folded
+		}
+		if IsNullish(left) {
+			return folded.Right
+		}
+		return folded.Left
+	case "in":
+		// needs an object on the right, which isn't a literal primitive
+		return &folded
+	}
+
+	left, ok := f.constant(folded.Left)
+	if !ok {
+		return &folded
+	}
+	right, ok := f.constant(folded.Right)
+	if !ok {
+		return &folded
+	}
+
+	return f.replace(ast, &folded, binaryOperation(ast.Operation, left, right))
+}
@@ -0,0 +1,36 @@
+package ep
+
+import "testing"
+
+func TestFoldCondition(t *testing.T) {
+	tests := []struct {
+		input    string
+		folded   string
+		warnings []string
+	}{
+		{input: "1 + 2 > 2", folded: "true", warnings: []string{"Condition [1 + 2 > 2] is always true"}},
+		{input: "1 / 0", folded: "1 / 0", warnings: []string{"Condition [1 / 0] is always true"}},
+		{input: "1 / 0 > 5", folded: "true", warnings: []string{"Condition [1 / 0 > 5] is always true"}},
+		{input: "0 / 0 ? a : b", folded: "b", warnings: []string{"Condition [0 / 0] is always false"}},
+		{input: "'' || x", folded: "x", warnings: []string{"Condition [''] is always false"}},
+		{input: "user && false", folded: "user && false", warnings: []string{"Condition [user && false] is always false"}},
+		{input: "user", folded: "user"},
+	}
+
+	for _, test := range tests {
+		ast := NewParser().ParseBinding(test.input, "", 0)
+		folded, warnings := FoldCondition(ast)
+		if got := Print(folded); got != test.folded {
+			t.Errorf("FoldCondition(%q) = %q, want %q", test.input, got, test.folded)
+		}
+		if len(warnings) != len(test.warnings) {
+			t.Errorf("FoldCondition(%q) warnings = %v, want %q", test.input, warnings, test.warnings)
+			continue
+		}
+		for i, warning := range warnings {
+			if warning.Message != test.warnings[i] {
+				t.Errorf("FoldCondition(%q) warning = %q, want %q", test.input, warning.Message, test.warnings[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package ep
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Expressions are encoded as JSON objects with their exported fields and a
+// "kind" naming their type, e.g. {"kind":"PropertyRead","Name":"a",...}, so
+// the AST fields can be decoded again.
+
+var astKinds = map[string]func() AST{
+	"EmptyExpr":              func() AST { return &EmptyExpr{} },
+	"ImplicitReceiver":       func() AST { return &ImplicitReceiver{} },
+	"ThisReceiver":           func() AST { return &ThisReceiver{} },
+	"Chain":                  func() AST { return &Chain{} },
+	"Conditional":            func() AST { return &Conditional{} },
+	"PropertyRead":           func() AST { return &PropertyRead{} },
+	"PropertyWrite":          func() AST { return &PropertyWrite{} },
+	"SafePropertyRead":       func() AST { return &SafePropertyRead{} },
+	"KeyedRead":              func() AST { return &KeyedRead{} },
+	"SafeKeyedRead":          func() AST { return &SafeKeyedRead{} },
+	"KeyedWrite":             func() AST { return &KeyedWrite{} },
+	"BindingPipe":            func() AST { return &BindingPipe{} },
+	"LiteralPrimitive":       func() AST { return &LiteralPrimitive{} },
+	"LiteralArray":           func() AST { return &LiteralArray{} },
+	"LiteralMap":             func() AST { return &LiteralMap{} },
+	"Interpolation":          func() AST { return &Interpolation{} },
+	"Binary":                 func() AST { return &Binary{} },
+	"Unary":                  func() AST { return &Unary{} },
+	"PrefixNot":              func() AST { return &PrefixNot{} },
+	"TypeofExpression":       func() AST { return &TypeofExpression{} },
+	"VoidExpression":         func() AST { return &VoidExpression{} },
+	"CompoundAssignment":     func() AST { return &CompoundAssignment{} },
+	"NonNullAssert":          func() AST { return &NonNullAssert{} },
+	"Call":                   func() AST { return &Call{} },
+	"SafeCall":               func() AST { return &SafeCall{} },
+	"TemplateLiteral":        func() AST { return &TemplateLiteral{} },
+	"TemplateLiteralElement": func() AST { return &TemplateLiteralElement{} },
+	"TaggedTemplateLiteral":  func() AST { return &TaggedTemplateLiteral{} },
+	"ASTWithSource":          func() AST { return &ASTWithSource{} },
+}
+
+// UnmarshalAST decodes an expression encoded by the MarshalJSON method of
+// its type, null is decoded as nil.
+func UnmarshalAST(data []byte) (AST, error) {
+	var tagged struct {
+		Kind *string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return nil, err
+	}
+	if tagged.Kind == nil {
+		if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ep: expression without kind")
+	}
+
+	create, ok := astKinds[*tagged.Kind]
+	if !ok {
+		return nil, fmt.Errorf("ep: unknown expression kind %q", *tagged.Kind)
+	}
+	ast := create()
+	if err := json.Unmarshal(data, ast); err != nil {
+		return nil, err
+	}
+	return ast, nil
+}
+
+// MarshalKind encodes v, a struct without MarshalJSON method, as a JSON object
+// with "kind" as its first key.
+func MarshalKind(kind string, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("ep: %s is not encoded as an object", kind)
+	}
+
+	encoded := []byte(`{"kind":"` + kind + `"`)
+	if len(data) > 2 {
+		encoded = append(encoded, ',')
+	}
+	return append(encoded, data[1:]...), nil
+}
+
+var (
+	astType  = reflect.TypeOf((*AST)(nil)).Elem()
+	astsType = reflect.TypeOf([]AST(nil))
+)
+
+// unmarshalFields decodes the fields of an expression, the fields of type AST
+// and []AST with UnmarshalAST and the others with encoding/json.
+func unmarshalFields(data []byte, ast AST) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	return unmarshalStruct(fields, reflect.ValueOf(ast).Elem())
+}
+
+func unmarshalStruct(fields map[string]json.RawMessage, v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			// the fields of ASTBase and ASTWithName are promoted
+			if err := unmarshalStruct(fields, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := fields[field.Name]
+		if !ok {
+			continue
+		}
+
+		switch field.Type {
+		case astType:
+			ast, err := UnmarshalAST(raw)
+			if err != nil {
+				return err
+			}
+			if ast != nil {
+				v.Field(i).Set(reflect.ValueOf(ast))
+			}
+		case astsType:
+			var items []json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return err
+			}
+			if items == nil {
+				continue
+			}
+			asts := make([]AST, len(items))
+			for j, item := range items {
+				ast, err := UnmarshalAST(item)
+				if err != nil {
+					return err
+				}
+				asts[j] = ast
+			}
+			v.Field(i).Set(reflect.ValueOf(asts))
+		default:
+			if err := json.Unmarshal(raw, v.Field(i).Addr().Interface()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *EmptyExpr) MarshalJSON() ([]byte, error) {
+	type ast EmptyExpr
+	return MarshalKind("EmptyExpr", (*ast)(a))
+}
+
+func (a *EmptyExpr) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *ImplicitReceiver) MarshalJSON() ([]byte, error) {
+	type ast ImplicitReceiver
+	return MarshalKind("ImplicitReceiver", (*ast)(a))
+}
+
+func (a *ImplicitReceiver) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *ThisReceiver) MarshalJSON() ([]byte, error) {
+	// the embedded ImplicitReceiver would encode its own kind
+	return MarshalKind("ThisReceiver", &a.ASTBase)
+}
+
+func (a *ThisReceiver) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *Chain) MarshalJSON() ([]byte, error) {
+	type ast Chain
+	return MarshalKind("Chain", (*ast)(a))
+}
+
+func (a *Chain) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *Conditional) MarshalJSON() ([]byte, error) {
+	type ast Conditional
+	return MarshalKind("Conditional", (*ast)(a))
+}
+
+func (a *Conditional) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *PropertyRead) MarshalJSON() ([]byte, error) {
+	type ast PropertyRead
+	return MarshalKind("PropertyRead", (*ast)(a))
+}
+
+func (a *PropertyRead) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *PropertyWrite) MarshalJSON() ([]byte, error) {
+	type ast PropertyWrite
+	return MarshalKind("PropertyWrite", (*ast)(a))
+}
+
+func (a *PropertyWrite) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *SafePropertyRead) MarshalJSON() ([]byte, error) {
+	type ast SafePropertyRead
+	return MarshalKind("SafePropertyRead", (*ast)(a))
+}
+
+func (a *SafePropertyRead) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *KeyedRead) MarshalJSON() ([]byte, error) {
+	type ast KeyedRead
+	return MarshalKind("KeyedRead", (*ast)(a))
+}
+
+func (a *KeyedRead) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *SafeKeyedRead) MarshalJSON() ([]byte, error) {
+	type ast SafeKeyedRead
+	return MarshalKind("SafeKeyedRead", (*ast)(a))
+}
+
+func (a *SafeKeyedRead) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *KeyedWrite) MarshalJSON() ([]byte, error) {
+	type ast KeyedWrite
+	return MarshalKind("KeyedWrite", (*ast)(a))
+}
+
+func (a *KeyedWrite) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *BindingPipe) MarshalJSON() ([]byte, error) {
+	type ast BindingPipe
+	return MarshalKind("BindingPipe", (*ast)(a))
+}
+
+func (a *BindingPipe) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+// MarshalJSON leaves out the Value of the `undefined` literal, null is
+// encoded as a null Value. Infinity and NaN, which JSON has no numbers for,
+// are encoded as a Number string like "Infinity" instead of the Value.
+func (a *LiteralPrimitive) MarshalJSON() ([]byte, error) {
+	if a.Value == Undefined {
+		return MarshalKind("LiteralPrimitive", &a.ASTBase)
+	}
+	if number, ok := a.Value.(float64); ok && (math.IsInf(number, 0) || math.IsNaN(number)) {
+		return MarshalKind("LiteralPrimitive", &struct {
+			ASTBase
+			Number string
+		}{a.ASTBase, formatNumber(number)})
+	}
+	type ast LiteralPrimitive
+	return MarshalKind("LiteralPrimitive", (*ast)(a))
+}
+
+func (a *LiteralPrimitive) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if err := unmarshalStruct(fields, reflect.ValueOf(&a.ASTBase).Elem()); err != nil {
+		return err
+	}
+
+	a.Value = Undefined
+	if raw, ok := fields["Number"]; ok {
+		var number string
+		if err := json.Unmarshal(raw, &number); err != nil {
+			return err
+		}
+		switch number {
+		case "Infinity":
+			a.Value = math.Inf(1)
+		case "-Infinity":
+			a.Value = math.Inf(-1)
+		case "NaN":
+			a.Value = math.NaN()
+		default:
+			return fmt.Errorf("ep: invalid number %q", number)
+		}
+		return nil
+	}
+	if raw, ok := fields["Value"]; ok {
+		a.Value = nil
+		return json.Unmarshal(raw, &a.Value)
+	}
+	return nil
+}
+
+func (a *LiteralArray) MarshalJSON() ([]byte, error) {
+	type ast LiteralArray
+	return MarshalKind("LiteralArray", (*ast)(a))
+}
+
+func (a *LiteralArray) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *LiteralMap) MarshalJSON() ([]byte, error) {
+	type ast LiteralMap
+	return MarshalKind("LiteralMap", (*ast)(a))
+}
+
+func (a *LiteralMap) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *Interpolation) MarshalJSON() ([]byte, error) {
+	type ast Interpolation
+	return MarshalKind("Interpolation", (*ast)(a))
+}
+
+func (a *Interpolation) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *Binary) MarshalJSON() ([]byte, error) {
+	type ast Binary
+	return MarshalKind("Binary", (*ast)(a))
+}
+
+func (a *Binary) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *Unary) MarshalJSON() ([]byte, error) {
+	type ast Unary
+	return MarshalKind("Unary", (*ast)(a))
+}
+
+func (a *Unary) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *PrefixNot) MarshalJSON() ([]byte, error) {
+	type ast PrefixNot
+	return MarshalKind("PrefixNot", (*ast)(a))
+}
+
+func (a *PrefixNot) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *TypeofExpression) MarshalJSON() ([]byte, error) {
+	type ast TypeofExpression
+	return MarshalKind("TypeofExpression", (*ast)(a))
+}
+
+func (a *TypeofExpression) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *VoidExpression) MarshalJSON() ([]byte, error) {
+	type ast VoidExpression
+	return MarshalKind("VoidExpression", (*ast)(a))
+}
+
+func (a *VoidExpression) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *CompoundAssignment) MarshalJSON() ([]byte, error) {
+	type ast CompoundAssignment
+	return MarshalKind("CompoundAssignment", (*ast)(a))
+}
+
+func (a *CompoundAssignment) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *NonNullAssert) MarshalJSON() ([]byte, error) {
+	type ast NonNullAssert
+	return MarshalKind("NonNullAssert", (*ast)(a))
+}
+
+func (a *NonNullAssert) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *Call) MarshalJSON() ([]byte, error) {
+	type ast Call
+	return MarshalKind("Call", (*ast)(a))
+}
+
+func (a *Call) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *SafeCall) MarshalJSON() ([]byte, error) {
+	type ast SafeCall
+	return MarshalKind("SafeCall", (*ast)(a))
+}
+
+func (a *SafeCall) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *TemplateLiteral) MarshalJSON() ([]byte, error) {
+	type ast TemplateLiteral
+	return MarshalKind("TemplateLiteral", (*ast)(a))
+}
+
+func (a *TemplateLiteral) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *TemplateLiteralElement) MarshalJSON() ([]byte, error) {
+	type ast TemplateLiteralElement
+	return MarshalKind("TemplateLiteralElement", (*ast)(a))
+}
+
+func (a *TemplateLiteralElement) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *TaggedTemplateLiteral) MarshalJSON() ([]byte, error) {
+	type ast TaggedTemplateLiteral
+	return MarshalKind("TaggedTemplateLiteral", (*ast)(a))
+}
+
+func (a *TaggedTemplateLiteral) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
+
+func (a *ASTWithSource) MarshalJSON() ([]byte, error) {
+	type ast ASTWithSource
+	return MarshalKind("ASTWithSource", (*ast)(a))
+}
+
+func (a *ASTWithSource) UnmarshalJSON(data []byte) error { return unmarshalFields(data, a) }
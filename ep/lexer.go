@@ -3,10 +3,13 @@ package ep
 import (
 	"errors"
 	"github.com/irustm/ng-template-parser/chars"
+	"math"
 	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // https://github.com/angular/angular/blob/master/packages/compiler/src/expression_parser/lexer.ts
@@ -24,7 +27,18 @@ const (
 	Error
 )
 
-var KEYWORDS = []string{"var", "let", "as", "null", "undefined", "true", "false", "if", "else", "this"}
+// StringTokenKind tells plain string literals apart from the parts of a
+// template literal, `a${b}c` is lexed as TemplateLiteralPart "a", operator
+// "${", identifier b, operator "}" and TemplateLiteralEnd "c".
+type StringTokenKind int
+
+const (
+	StringTokenKindPlain StringTokenKind = iota
+	StringTokenKindTemplateLiteralPart
+	StringTokenKindTemplateLiteralEnd
+)
+
+var KEYWORDS = []string{"var", "let", "as", "null", "undefined", "true", "false", "if", "else", "this", "typeof", "void", "in"}
 
 func contains(s []string, e string) bool {
 	for _, a := range s {
@@ -36,32 +50,52 @@ func contains(s []string, e string) bool {
 }
 
 type Lexer struct {
+	// StopOnError stops scanning after the first Error token, by default the
+	// scanner continues after errors so that a parser can recover.
+	StopOnError bool
 }
 
 func (l Lexer) Tokenize(text string) []Token {
 	var scanner = newScanner(text)
 	var tokens []Token
 
-	var token, err = scanner.scanToken()
+	var token, ok = scanner.scanToken()
 
-	for err == nil {
+	for ok {
+		token.UTF16Index = scanner.utf16Offset(token.Index)
+		token.UTF16End = scanner.utf16Offset(token.End)
 		tokens = append(tokens, token)
-		token, err = scanner.scanToken()
 
+		if l.StopOnError && token.isError() {
+			break
+		}
+
+		token, ok = scanner.scanToken()
 	}
 	return tokens
 }
 
+// Token is a lexed piece of an expression. Index and End are byte offsets in
+// the input, UTF16Index and UTF16End the same offsets in UTF-16 code units.
+// Number tokens carry their value in NumValue and the original source text in
+// StrValue, character tokens carry the character code in NumValue. Error
+// tokens carry the message in StrValue and the zero-based Line and Column of
+// Index.
 type Token struct {
-	Index     int
-	End       int
-	TypeToken TokenType
-	NumValue  int
-	StrValue  string
+	Index      int
+	End        int
+	UTF16Index int
+	UTF16End   int
+	TypeToken  TokenType
+	NumValue   float64
+	StrValue   string
+	Kind       StringTokenKind
+	Line       int
+	Column     int
 }
 
 func (t Token) isCharacter(code int) bool {
-	return t.TypeToken == Character && t.NumValue == code
+	return t.TypeToken == Character && t.NumValue == float64(code)
 }
 
 func (t Token) isNumber() bool {
@@ -72,6 +106,22 @@ func (t Token) isString() bool {
 	return t.TypeToken == String
 }
 
+func (t Token) isTemplateLiteralPart() bool {
+	return t.TypeToken == String && t.Kind == StringTokenKindTemplateLiteralPart
+}
+
+func (t Token) isTemplateLiteralEnd() bool {
+	return t.TypeToken == String && t.Kind == StringTokenKindTemplateLiteralEnd
+}
+
+func (t Token) isTemplateLiteralInterpolationStart() bool {
+	return t.isOperator("${")
+}
+
+func (t Token) isTemplateLiteralInterpolationEnd() bool {
+	return t.isOperator("}")
+}
+
 func (t Token) isOperator(operator string) bool {
 	return t.TypeToken == Operator && t.StrValue == operator
 }
@@ -116,11 +166,23 @@ func (t Token) isKeywordThis() bool {
 	return t.TypeToken == Keyword && t.StrValue == "this"
 }
 
+func (t Token) isKeywordTypeof() bool {
+	return t.TypeToken == Keyword && t.StrValue == "typeof"
+}
+
+func (t Token) isKeywordVoid() bool {
+	return t.TypeToken == Keyword && t.StrValue == "void"
+}
+
+func (t Token) isKeywordIn() bool {
+	return t.TypeToken == Keyword && t.StrValue == "in"
+}
+
 func (t Token) isError() bool {
 	return t.TypeToken == Error
 }
 
-func (t Token) toNumber() int {
+func (t Token) toNumber() float64 {
 	if t.TypeToken == Number {
 		return t.NumValue
 	}
@@ -142,7 +204,7 @@ func (t Token) toString() string {
 		}
 	case Number:
 		{
-			return string(t.NumValue)
+			return formatNumber(t.NumValue)
 		}
 	default:
 		{
@@ -154,98 +216,126 @@ func (t Token) toString() string {
 
 func newCharacterToken(index int, end int, code int) Token {
 	return Token{
-		index,
-		end,
-		Character,
-		code,
-		string(code),
+		Index:     index,
+		End:       end,
+		TypeToken: Character,
+		NumValue:  float64(code),
+		StrValue:  string(rune(code)),
 	}
 }
 
 func newIdentifierToken(index int, end int, text string) Token {
-	return Token{index, end, Identifier, 0, text}
+	return Token{Index: index, End: end, TypeToken: Identifier, StrValue: text}
 }
 
 func newPrivateIdentifierToken(index int, end int, text string) Token {
-	return Token{index, end, PrivateIdentifier, 0, text}
+	return Token{Index: index, End: end, TypeToken: PrivateIdentifier, StrValue: text}
 }
 
 func newKeywordToken(index int, end int, text string) Token {
-	return Token{index, end, Keyword, 0, text}
+	return Token{Index: index, End: end, TypeToken: Keyword, StrValue: text}
 }
 
 func newOperatorToken(index int, end int, text string) Token {
-	return Token{index, end, Operator, 0, text}
+	return Token{Index: index, End: end, TypeToken: Operator, StrValue: text}
 }
 
-func newStringToken(index int, end int, text string) Token {
-	return Token{index, end, String, 0, text}
+func newStringToken(index int, end int, text string, kind StringTokenKind) Token {
+	return Token{Index: index, End: end, TypeToken: String, StrValue: text, Kind: kind}
 }
 
-func newNumberToken(index int, end int, n int) Token {
-	return Token{index, end, Number, n, ""}
+func newNumberToken(index int, end int, n float64, text string) Token {
+	return Token{Index: index, End: end, TypeToken: Number, NumValue: n, StrValue: text}
 }
 
 func newErrorToken(index int, end int, message string) Token {
-	return Token{index, end, Error, 0, message}
+	return Token{Index: index, End: end, TypeToken: Error, StrValue: message}
 }
 
-var EOF Token = Token{-1, -1, Character, 0, ""}
+var EOF Token = Token{Index: -1, End: -1, TypeToken: Character}
 
+// scanner reads the input rune by rune, index is the byte offset of peek and
+// width its length in bytes.
 type scanner struct {
 	length int
 	peek   int
 	index  int
+	width  int
 	input  string
+
+	// byte offset and UTF-16 offset of the last utf16Offset lookup
+	utf16ByteOffset int
+	utf16Index      int
+
+	// tokens scanned ahead, template literals produce several tokens at once
+	pending []Token
+	// open braces, "interpolation" for `${` and "expression" for `{`
+	braceStack []string
 }
 
 func newScanner(input string) scanner {
-	res := scanner{length: len(input), peek: 0, index: -1, input: input}
+	res := scanner{length: len(input), peek: 0, index: -1, width: 1, input: input}
+	res.advance()
 
 	return res
 }
 
 func (s *scanner) advance() {
-	s.index++
+	s.index += s.width
 
 	if s.index >= s.length {
+		s.index = s.length
 		s.peek = chars.VEOF
+		s.width = 0
 	} else {
-		s.peek = int(s.input[s.index])
+		r, width := utf8.DecodeRuneInString(s.input[s.index:])
+		s.peek = int(r)
+		s.width = width
 	}
 }
 
-func (s *scanner) scanToken() (Token, error) {
-	var input = s.input
-	var length = s.length
-	var peek = s.peek
-	var index = s.index
+// utf16Offset converts a byte offset of the input to an offset in UTF-16 code
+// units as used by JavaScript and TypeScript tooling. Offsets are expected in
+// increasing order, going back restarts the count from the beginning.
+func (s *scanner) utf16Offset(byteOffset int) int {
+	if byteOffset < s.utf16ByteOffset {
+		s.utf16ByteOffset = 0
+		s.utf16Index = 0
+	}
 
-	for peek <= chars.VSPACE {
+	for _, r := range s.input[s.utf16ByteOffset:byteOffset] {
+		s.utf16Index += chars.UTF16Len(r)
+	}
+	s.utf16ByteOffset = byteOffset
 
-		index++
+	return s.utf16Index
+}
 
-		if index >= length {
-			peek = chars.VEOF
-			break
-		} else {
-			peek = int(input[index])
-		}
+// scanToken returns the next token, ok is false at the end of the input.
+func (s *scanner) scanToken() (token Token, ok bool) {
+	if len(s.pending) > 0 {
+		token = s.pending[0]
+		s.pending = s.pending[1:]
+		return token, true
 	}
 
-	s.peek = peek
-	s.index = index
+	for s.index < s.length && (s.peek <= chars.VSPACE || chars.IsWhitespace(s.peek)) {
+		s.advance()
+	}
+
+	var peek = s.peek
+	var index = s.index
 
-	if index >= length {
-		return Token{}, errors.New("null")
+	if index >= s.length {
+		return Token{}, false
 	}
 
 	// Handle identifiers and numbers.
 	if isIdentifierStart(peek) {
-		return s.scanIdentifier()
+		return s.scanIdentifier(), true
 	}
 	if chars.IsDigit(peek) {
-		return s.scanNumber(index)
+		return s.scanNumber(index), true
 	}
 
 	var start = index
@@ -255,78 +345,93 @@ func (s *scanner) scanToken() (Token, error) {
 		{
 			s.advance()
 			if chars.IsDigit(s.peek) {
-				return s.scanNumber(start)
+				return s.scanNumber(start), true
 			} else {
-				return newCharacterToken(start, s.index, chars.VPERIOD), nil
+				return newCharacterToken(start, s.index, chars.VPERIOD), true
 			}
 		}
-	case chars.VLPAREN, chars.VRPAREN, chars.VLBRACE, chars.VRBRACE, chars.VLBRACKET, chars.VRBRACKET, chars.VCOMMA, chars.VCOLON, chars.VSEMICOLON:
+	case chars.VLPAREN, chars.VRPAREN, chars.VLBRACKET, chars.VRBRACKET, chars.VCOMMA, chars.VCOLON, chars.VSEMICOLON:
+		{
+			return s.scanCharacter(start, peek), true
+		}
+	case chars.VLBRACE:
 		{
-			return s.scanCharacter(start, peek)
+			s.braceStack = append(s.braceStack, "expression")
+			return s.scanCharacter(start, peek), true
+		}
+	case chars.VRBRACE:
+		{
+			return s.scanCloseBrace(start, peek), true
 		}
 	case chars.VSQ, chars.VDQ:
 		{
-			return s.scanString()
+			return s.scanString(), true
+		}
+	case chars.VBT:
+		{
+			s.advance()
+			tokens := s.scanTemplateLiteralPart(start)
+			s.pending = append(s.pending, tokens[1:]...)
+			return tokens[0], true
 		}
 	case chars.VHASH:
 		{
-			return s.scanPrivateIdentifier()
+			return s.scanPrivateIdentifier(), true
 		}
 
-	case chars.VPLUS, chars.VMINUS, chars.VSTAR, chars.VSLASH, chars.VPERCENT, chars.VCARET:
+	case chars.VPLUS, chars.VMINUS, chars.VSLASH, chars.VPERCENT:
+		{
+			return s.scanComplexOperator(start, string(rune(peek)), chars.VEQ, "="), true
+		}
+	case chars.VCARET:
 		{
-			return s.scanOperator(start, string(peek))
+			return s.scanOperator(start, string(rune(peek))), true
+		}
+	case chars.VSTAR:
+		{
+			return s.scanStar(start), true
 		}
 
 	case chars.VQUESTION:
 		{
-			return s.scanQuestion(start)
+			return s.scanQuestion(start), true
 		}
 	case chars.VLT, chars.VGT:
 		{
-			return s.scanComplexOperator(start, string(peek), chars.VEQ, "=")
+			return s.scanComplexOperator(start, string(rune(peek)), chars.VEQ, "="), true
 
 		}
 	case chars.VBANG, chars.VEQ:
 		{
-			return s.scanComplexOperatorThree(start, string(peek), chars.VEQ, "=", chars.VEQ, "=")
+			return s.scanComplexOperatorThree(start, string(rune(peek)), chars.VEQ, "=", chars.VEQ, "="), true
 		}
 	case chars.VAMPERSAND:
 		{
-			return s.scanComplexOperator(start, "&", chars.VAMPERSAND, "&")
+			return s.scanComplexOperatorThree(start, "&", chars.VAMPERSAND, "&", chars.VEQ, "="), true
 		}
 	case chars.VBAR:
 		{
-			return s.scanComplexOperator(start, "|", chars.VBAR, "|")
+			return s.scanComplexOperatorThree(start, "|", chars.VBAR, "|", chars.VEQ, "="), true
 
 		}
-	case chars.VNBSP:
-		{
-
-			for chars.IsWhitespace(s.peek) {
-				s.advance()
-			}
-
-			return s.scanToken()
-		}
 	}
 
 	s.advance()
 
-	return Token{}, errors.New("Unexpected character [" + string(peek) + "]")
+	return s.error("Unexpected character ["+string(rune(peek))+"]", 0), true
 }
 
-func (s *scanner) scanCharacter(start int, code int) (Token, error) {
+func (s *scanner) scanCharacter(start int, code int) Token {
 	s.advance()
-	return newCharacterToken(start, s.index, code), nil
+	return newCharacterToken(start, s.index, code)
 }
 
-func (s *scanner) scanOperator(start int, str string) (Token, error) {
+func (s *scanner) scanOperator(start int, str string) Token {
 	s.advance()
-	return newOperatorToken(start, s.index, str), nil
+	return newOperatorToken(start, s.index, str)
 }
 
-func (s *scanner) scanComplexOperator(start int, one string, twoCode int, two string) (Token, error) {
+func (s *scanner) scanComplexOperator(start int, one string, twoCode int, two string) Token {
 	s.advance()
 
 	var str string = one
@@ -336,10 +441,10 @@ func (s *scanner) scanComplexOperator(start int, one string, twoCode int, two st
 		str += two
 	}
 
-	return newOperatorToken(start, s.index, str), nil
+	return newOperatorToken(start, s.index, str)
 }
 
-func (s *scanner) scanComplexOperatorThree(start int, one string, twoCode int, two string, threeCode int, three string) (Token, error) {
+func (s *scanner) scanComplexOperatorThree(start int, one string, twoCode int, two string, threeCode int, three string) Token {
 	s.advance()
 
 	var str string = one
@@ -347,17 +452,35 @@ func (s *scanner) scanComplexOperatorThree(start int, one string, twoCode int, t
 	if s.peek == twoCode {
 		s.advance()
 		str += two
+
+		if s.peek == threeCode {
+			s.advance()
+			str += three
+		}
 	}
 
-	if s.peek == threeCode {
+	return newOperatorToken(start, s.index, str)
+}
+
+// scanStar scans `*`, `*=`, `**` and `**=`.
+func (s *scanner) scanStar(start int) Token {
+	s.advance()
+	var str = "*"
+
+	if s.peek == chars.VSTAR {
+		s.advance()
+		str += "*"
+	}
+
+	if s.peek == chars.VEQ {
 		s.advance()
-		str += three
+		str += "="
 	}
 
-	return newOperatorToken(start, s.index, str), nil
+	return newOperatorToken(start, s.index, str)
 }
 
-func (s *scanner) scanIdentifier() (Token, error) {
+func (s *scanner) scanIdentifier() Token {
 	var start = s.index
 	s.advance()
 	for isIdentifierPart(s.peek) {
@@ -366,18 +489,18 @@ func (s *scanner) scanIdentifier() (Token, error) {
 	var str = s.input[start:s.index]
 
 	if contains(KEYWORDS, str) {
-		return newKeywordToken(start, s.index, str), nil
+		return newKeywordToken(start, s.index, str)
 	} else {
-		return newIdentifierToken(start, s.index, str), nil
+		return newIdentifierToken(start, s.index, str)
 	}
 }
 
 /** Scans an ECMAScript private identifier. */
-func (s *scanner) scanPrivateIdentifier() (Token, error) {
+func (s *scanner) scanPrivateIdentifier() Token {
 	var start = s.index
 	s.advance()
-	for isIdentifierStart(s.peek) {
-		return Token{}, errors.New("Invalid character [#]")
+	if !isIdentifierStart(s.peek) {
+		return s.error("Invalid character [#]", -1)
 	}
 
 	for isIdentifierPart(s.peek) {
@@ -385,12 +508,24 @@ func (s *scanner) scanPrivateIdentifier() (Token, error) {
 	}
 
 	var identifierName = s.input[start:s.index]
-	return newPrivateIdentifierToken(start, s.index, identifierName), nil
+	return newPrivateIdentifierToken(start, s.index, identifierName)
 }
 
-func (s *scanner) scanNumber(start int) (Token, error) {
+func (s *scanner) scanNumber(start int) Token {
 	var simple = s.index == start
 	var hasSeparators = false
+
+	if simple && s.peek == chars.V0 && s.index+1 < s.length {
+		switch s.input[s.index+1] {
+		case 'x', 'X':
+			return s.scanRadixNumber(start, 16)
+		case 'o', 'O':
+			return s.scanRadixNumber(start, 8)
+		case 'b', 'B':
+			return s.scanRadixNumber(start, 2)
+		}
+	}
+
 	s.advance()
 
 	for {
@@ -402,8 +537,8 @@ func (s *scanner) scanNumber(start int) (Token, error) {
 			// point or another separator either. Note that it's unlikely that we'll hit a case where
 			// the underscore is at the start, because that's a valid identifier and it will be picked
 			// up earlier in the parsing. We validate for it anyway just in case.
-			if !chars.IsDigit(int(s.input[s.index-1])) || !chars.IsDigit(int(s.input[s.index+1])) {
-				return Token{}, errors.New("Invalid numeric separator")
+			if !chars.IsDigit(int(s.input[s.index-1])) || s.index+1 >= s.length || !chars.IsDigit(int(s.input[s.index+1])) {
+				return s.error("Invalid numeric separator", 0)
 			}
 			hasSeparators = true
 		} else if s.peek == chars.VPERIOD {
@@ -415,7 +550,7 @@ func (s *scanner) scanNumber(start int) (Token, error) {
 				s.advance()
 			}
 			if !chars.IsDigit(s.peek) {
-				return Token{}, errors.New("Invalid exponent")
+				return s.error("Invalid exponent", -1)
 			}
 			simple = false
 		} else {
@@ -425,22 +560,69 @@ func (s *scanner) scanNumber(start int) (Token, error) {
 		s.advance()
 	}
 
-	var str = s.input[start:s.index]
+	var text = s.input[start:s.index]
+	var str = text
 	if hasSeparators {
 		str = strings.ReplaceAll(str, "_", "")
 	}
 
-	var value int
+	var value float64
+	var err error
 	if simple {
-		value = parseIntAutoRadix(str)
+		value, err = parseIntAutoRadix(str)
 	} else {
-		value = parseFloat(str)
+		value, err = parseFloat(str)
+	}
+
+	if err != nil {
+		return s.error("Invalid number "+text, start-s.index)
 	}
 
-	return newNumberToken(start, s.index, value), nil
+	return newNumberToken(start, s.index, value, text)
 }
 
-func (s *scanner) scanString() (Token, error) {
+// scanRadixNumber scans `0x`, `0o` and `0b` prefixed integer literals.
+func (s *scanner) scanRadixNumber(start int, radix int) Token {
+	var hasSeparators = false
+
+	// skip `0` and the radix prefix
+	s.advance()
+	s.advance()
+
+	for {
+		if isRadixDigit(s.peek, radix) {
+			// Do nothing.
+		} else if s.peek == chars.V_ {
+			if !isRadixDigit(int(s.input[s.index-1]), radix) || s.index+1 >= s.length || !isRadixDigit(int(s.input[s.index+1]), radix) {
+				return s.error("Invalid numeric separator", 0)
+			}
+			hasSeparators = true
+		} else {
+			break
+		}
+
+		s.advance()
+	}
+
+	var text = s.input[start:s.index]
+	var str = text
+	if hasSeparators {
+		str = strings.ReplaceAll(str, "_", "")
+	}
+
+	if len(str) == 2 || isIdentifierPart(s.peek) {
+		return s.error("Invalid number "+text, start-s.index)
+	}
+
+	value, err := parseIntAutoRadix(str)
+	if err != nil {
+		return s.error("Invalid number "+text, start-s.index)
+	}
+
+	return newNumberToken(start, s.index, value, text)
+}
+
+func (s *scanner) scanString() Token {
 	var start = s.index
 	var quote = s.peek
 	s.advance()
@@ -452,34 +634,17 @@ func (s *scanner) scanString() (Token, error) {
 	for s.peek != quote {
 		if s.peek == chars.VBACKSLASH {
 			buffer += input[marker:s.index]
-			s.advance()
-			var unescapedCode int
-			// Workaround for TS2.1-introduced type strictness
-			s.peek = s.peek // ??
-			if s.peek == chars.Vu {
-				var hex = input[s.index+1 : s.index+5]
-				matcher, _ := regexp.Compile("^[0-9a-f]+$")
-
-				if matcher.MatchString(hex) {
-					n := new(big.Int)
-					n.SetString(hex, 16)
-					unescapedCode = int(n.Uint64())
-				} else {
-					return Token{}, errors.New("Invalid unicode escape " + hex)
-				}
-
-				for i := 0; i < 5; i++ {
-					s.advance()
-				}
-			} else {
-				unescapedCode = unescape(s.peek)
-				s.advance()
+
+			unescaped, errorToken := s.scanEscape()
+			if errorToken != nil {
+				s.skipString(quote)
+				return *errorToken
 			}
 
-			buffer += string(unescapedCode)
+			buffer += unescaped
 			marker = s.index
-		} else if s.peek == chars.VEOF {
-			return Token{}, errors.New("Unterminated quote")
+		} else if s.index >= s.length {
+			return s.error("Unterminated quote", 0)
 		} else {
 			s.advance()
 		}
@@ -488,13 +653,150 @@ func (s *scanner) scanString() (Token, error) {
 	var last = input[marker:s.index]
 	s.advance()
 
-	return newStringToken(start, s.index, buffer+last), nil
+	return newStringToken(start, s.index, buffer+last, StringTokenKindPlain)
+}
+
+// skipString advances past the closing quote of a string with an invalid
+// escape, so scanning continues after it.
+func (s *scanner) skipString(quote int) {
+	for s.index < s.length && s.peek != quote {
+		if s.peek == chars.VBACKSLASH {
+			s.advance()
+		}
+		s.advance()
+	}
+	s.advance()
+}
+
+// scanTemplateLiteralPart scans a template literal from after the backtick or
+// the `}` closing an interpolation up to the closing backtick or the next
+// `${`, which is returned as a second token.
+func (s *scanner) scanTemplateLiteralPart(start int) []Token {
+	var buffer string = ""
+	var marker int = s.index
+	var input string = s.input
+
+	for s.peek != chars.VBT {
+		if s.peek == chars.VBACKSLASH {
+			buffer += input[marker:s.index]
+
+			unescaped, errorToken := s.scanEscape()
+			if errorToken != nil {
+				return []Token{*errorToken}
+			}
+
+			buffer += unescaped
+			marker = s.index
+		} else if s.peek == chars.VDOLLAR {
+			var dollar = s.index
+			s.advance()
+
+			if s.peek == chars.VLBRACE {
+				s.braceStack = append(s.braceStack, "interpolation")
+				part := newStringToken(start, dollar, buffer+input[marker:dollar], StringTokenKindTemplateLiteralPart)
+				s.advance()
+
+				return []Token{part, newOperatorToken(dollar, s.index, "${")}
+			}
+		} else if s.index >= s.length {
+			return []Token{s.error("Unterminated template string", 0)}
+		} else {
+			s.advance()
+		}
+	}
+
+	var suffix = input[marker:s.index]
+	s.advance()
+
+	return []Token{newStringToken(start, s.index, buffer+suffix, StringTokenKindTemplateLiteralEnd)}
+}
+
+func (s *scanner) scanCloseBrace(start int, code int) Token {
+	s.advance()
+	end := s.index
+
+	var currentBrace string
+	if len(s.braceStack) > 0 {
+		currentBrace = s.braceStack[len(s.braceStack)-1]
+		s.braceStack = s.braceStack[:len(s.braceStack)-1]
+	}
+
+	if currentBrace == "interpolation" {
+		// the literal text after the `}` is a token of its own
+		s.pending = append(s.pending, s.scanTemplateLiteralPart(s.index)...)
+		return newOperatorToken(start, end, "}")
+	}
+
+	return newCharacterToken(start, end, code)
+}
+
+// scanEscape reads an escape sequence of a string or template literal, peek
+// is expected at the backslash.
+func (s *scanner) scanEscape() (string, *Token) {
+	var input = s.input
+	s.advance()
+
+	var unescapedCode int
+	if s.peek == chars.Vu {
+		escapeLength := 5
+		if s.index+escapeLength > s.length {
+			escapeLength = s.length - s.index
+		}
+
+		code, ok := s.scanUnicodeEscape()
+		if !ok {
+			errorToken := s.error("Invalid unicode escape [\\u"+input[s.index+1:s.index+escapeLength]+"]", 0)
+			return "", &errorToken
+		}
+		unescapedCode = code
+
+		// a surrogate pair written as two escapes, e.g. '\uD83D\uDE00'
+		if utf16.IsSurrogate(rune(code)) && s.peek == chars.VBACKSLASH && s.index+1 < s.length && input[s.index+1] == 'u' {
+			save := *s
+			s.advance()
+
+			if low, ok := s.scanUnicodeEscape(); ok && utf16.DecodeRune(rune(code), rune(low)) != utf8.RuneError {
+				unescapedCode = int(utf16.DecodeRune(rune(code), rune(low)))
+			} else {
+				*s = save
+			}
+		}
+	} else {
+		unescapedCode = unescape(s.peek)
+		s.advance()
+	}
+
+	return string(rune(unescapedCode)), nil
+}
+
+// scanUnicodeEscape reads the four hex digits of a `\u` escape, peek is
+// expected at the `u`.
+func (s *scanner) scanUnicodeEscape() (int, bool) {
+	if s.index+5 > s.length {
+		return 0, false
+	}
+
+	var hex = s.input[s.index+1 : s.index+5]
+	matcher, _ := regexp.Compile("(?i)^[0-9a-f]+$")
+
+	if !matcher.MatchString(hex) {
+		return 0, false
+	}
+
+	n := new(big.Int)
+	n.SetString(hex, 16)
+
+	for i := 0; i < 5; i++ {
+		s.advance()
+	}
+
+	return int(n.Uint64()), true
 }
 
-func (s *scanner) scanQuestion(start int) (Token, error) {
+func (s *scanner) scanQuestion(start int) Token {
 	s.advance()
 	var str = "?"
-	// Either `a ?? b` or 'a?.b'.
+	// Either `a ?? b`, `a ??= b` or 'a?.b'.
 	if s.peek == chars.VQUESTION || s.peek == chars.VPERIOD {
 		if s.peek == chars.VPERIOD {
 			str += "."
@@ -502,19 +804,46 @@ func (s *scanner) scanQuestion(start int) (Token, error) {
 			str += "?"
 		}
 		s.advance()
+
+		if str == "??" && s.peek == chars.VEQ {
+			str += "="
+			s.advance()
+		}
 	}
-	return newOperatorToken(start, s.index, str), nil
+	return newOperatorToken(start, s.index, str)
 }
 
-func (s *scanner) error(message string, offset int) (Token, error) {
+func (s *scanner) error(message string, offset int) Token {
 	position := s.index + offset
+	line, column := lineAndColumn(s.input, position)
+
+	token := newErrorToken(position, s.index, `Lexer Error: `+message+` at column `+strconv.Itoa(s.utf16Offset(position))+` in expression [`+s.input+`]`)
+	token.Line = line
+	token.Column = column
 
-	return newErrorToken(position, s.index, `Lexer Error: `+message+` at column `+string(position)+` in expression [`+s.input+`]`), nil
+	return token
+}
+
+// lineAndColumn returns the zero-based line and column of a byte offset, the
+// column is counted in UTF-16 code units like in TypeScript tooling.
+func lineAndColumn(input string, offset int) (int, int) {
+	if offset > len(input) {
+		offset = len(input)
+	}
+
+	line := strings.Count(input[:offset], "\n")
+	lineStart := strings.LastIndexByte(input[:offset], '\n') + 1
+
+	column := 0
+	for _, r := range input[lineStart:offset] {
+		column += chars.UTF16Len(r)
+	}
+
+	return line, column
 }
 
 func isIdentifierStart(code int) bool {
-	return (chars.Va <= code && code <= chars.Vz) || (chars.VA <= code && code <= chars.VZ) ||
-		(code == chars.V_) || (code == chars.VDOLLAR)
+	return chars.IsIdentifierStart(code)
 }
 
 func IsIdentifier(input string) bool {
@@ -524,13 +853,13 @@ func IsIdentifier(input string) bool {
 
 	var scanner = newScanner(input)
 
-	if isIdentifierStart(scanner.peek) {
+	if !isIdentifierStart(scanner.peek) {
 		return false
 	}
 	scanner.advance()
 
-	for scanner.peek != chars.VEOF {
-		if isIdentifierPart(scanner.peek) {
+	for scanner.index < scanner.length {
+		if !isIdentifierPart(scanner.peek) {
 			return false
 		}
 		scanner.advance()
@@ -540,7 +869,7 @@ func IsIdentifier(input string) bool {
 }
 
 func isIdentifierPart(code int) bool {
-	return chars.IsAsciiLetter(code) || chars.IsDigit(code) || (code == chars.V_) || (code == chars.VDOLLAR)
+	return chars.IsIdentifierPart(code)
 }
 
 func isExponentStart(code int) bool {
@@ -568,23 +897,83 @@ func unescape(code int) int {
 	}
 }
 
-func parseIntAutoRadix(text string) int {
-	result, err := strconv.Atoi(text)
+func isRadixDigit(code int, radix int) bool {
+	switch radix {
+	case 2:
+		return code == chars.V0 || code == chars.V0+1
+	case 8:
+		return chars.IsOctalDigit(code)
+	default:
+		return chars.IsAsciiHexDigit(code)
+	}
+}
 
-	if err != nil {
-		// error
+// parseIntAutoRadix parses decimal, `0x`, `0o` and `0b` integer literals. Like
+// JavaScript numbers, integers beyond 2^53 lose precision instead of failing.
+func parseIntAutoRadix(text string) (float64, error) {
+	if len(text) > 2 && text[0] == '0' {
+		var radix int
+		switch text[1] {
+		case 'x', 'X':
+			radix = 16
+		case 'o', 'O':
+			radix = 8
+		case 'b', 'B':
+			radix = 2
+		}
+
+		if radix != 0 {
+			n, ok := new(big.Int).SetString(text[2:], radix)
+			if !ok {
+				return 0, errors.New("invalid integer " + text)
+			}
+
+			value, _ := new(big.Float).SetInt(n).Float64()
+			return value, nil
+		}
 	}
 
-	return result
+	return parseFloat(text)
 }
 
-func parseFloat(text string) int {
-	result, err := strconv.ParseFloat(text, 10)
+// parseFloat parses a decimal literal, values out of range become +Inf like in
+// JavaScript.
+func parseFloat(text string) (float64, error) {
+	result, err := strconv.ParseFloat(text, 64)
 
-	if err != nil {
-		// error
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		return result, nil
+	}
+
+	return result, err
+}
+
+// formatNumber formats a number the way JavaScript's Number.prototype.toString does.
+func formatNumber(value float64) string {
+	if math.IsInf(value, 1) {
+		return "Infinity"
+	}
+	if math.IsInf(value, -1) {
+		return "-Infinity"
 	}
+	if math.IsNaN(value) {
+		return "NaN"
+	}
+
+	// -0 is printed as 0 like in JS
+	if value == 0 {
+		return "0"
+	}
+
+	abs := math.Abs(value)
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	str := strconv.FormatFloat(value, 'e', -1, 64)
+	mantissa, exponent := str[:strings.IndexByte(str, 'e')], str[strings.IndexByte(str, 'e')+1:]
+	sign := exponent[0]
+	exponent = strings.TrimLeft(exponent[1:], "0")
 
-	// TODO,
-	return int(result)
+	return mantissa + "e" + string(sign) + exponent
 }
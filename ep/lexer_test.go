@@ -0,0 +1,244 @@
+package ep
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// describeTokens returns the type, kind and value of tokens, e.g. "part `a`".
+func describeTokens(tokens []Token) []string {
+	var described []string
+	for _, token := range tokens {
+		switch {
+		case token.isTemplateLiteralPart():
+			described = append(described, fmt.Sprintf("part %q", token.StrValue))
+		case token.isTemplateLiteralEnd():
+			described = append(described, fmt.Sprintf("end %q", token.StrValue))
+		case token.TypeToken == String:
+			described = append(described, fmt.Sprintf("string %q", token.StrValue))
+		case token.TypeToken == Character:
+			described = append(described, fmt.Sprintf("char %q", rune(token.NumValue)))
+		case token.TypeToken == Error:
+			described = append(described, "error "+token.StrValue)
+		default:
+			described = append(described, token.StrValue)
+		}
+	}
+	return described
+}
+
+func TestTemplateLiteralTokens(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"`abc`", []string{`end "abc"`}},
+		{"`a${b}c`", []string{`part "a"`, "${", "b", "}", `end "c"`}},
+		{"`${a}${b}`", []string{`part ""`, "${", "a", "}", `part ""`, "${", "b", "}", `end ""`}},
+		{"`a${ {x: 1}.x }b`", []string{`part "a"`, "${", "char '{'", "x", "char ':'", "1", "char '}'", "char '.'", "x", "}", `end "b"`}},
+		{"`a${`b${c}`}d`", []string{`part "a"`, "${", `part "b"`, "${", "c", "}", `end ""`, "}", `end "d"`}},
+		{"`\\u0041\\`$x`", []string{"end \"A`$x\""}},
+		{"tag`a`", []string{"tag", `end "a"`}},
+		{"{a: 'b'}", []string{"char '{'", "a", "char ':'", `string "b"`, "char '}'"}},
+	}
+
+	for _, test := range tests {
+		if got := describeTokens(Lexer{}.Tokenize(test.input)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestUnterminatedTemplateLiteral(t *testing.T) {
+	for _, input := range []string{"`abc", "`a${b}c", "`a\\u00`"} {
+		tokens := Lexer{}.Tokenize(input)
+		if len(tokens) == 0 || !tokens[len(tokens)-1].isError() {
+			t.Errorf("Tokenize(%q) = %q, want an error last", input, describeTokens(tokens))
+		}
+	}
+}
+
+func TestTemplateLiteralTokenSpans(t *testing.T) {
+	input := "`a${b}c${d}`"
+	want := []string{"`a", "${", "b", "}", "c", "${", "d", "}", "`"}
+
+	tokens := Lexer{}.Tokenize(input)
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, token := range tokens {
+		if text := input[token.Index:token.End]; text != want[i] {
+			t.Errorf("token %d spans %q, want %q", i, text, want[i])
+		}
+	}
+}
+
+func TestNumberTokens(t *testing.T) {
+	tests := []struct {
+		input string
+		value float64
+	}{
+		{"0", 0},
+		{"42", 42},
+		{"0.5", 0.5},
+		{".5", 0.5},
+		{"3.14", 3.14},
+		{"1e-3", 0.001},
+		{"1E3", 1000},
+		{"1.e3", 1000},
+		{"2e+2", 200},
+		{"0x1F", 31},
+		{"0xff", 255},
+		{"0xFF_FF", 65535},
+		{"1_000", 1000},
+		{"1_000.000_5", 1000.0005},
+		{"9007199254740993", 9007199254740992},
+	}
+
+	for _, test := range tests {
+		tokens := Lexer{}.Tokenize(test.input)
+		if len(tokens) != 1 || !tokens[0].isNumber() {
+			t.Errorf("Tokenize(%q) = %q, want one number", test.input, describeTokens(tokens))
+			continue
+		}
+		if tokens[0].NumValue != test.value || tokens[0].StrValue != test.input {
+			t.Errorf("Tokenize(%q) = %v %q, want %v %q", test.input, tokens[0].NumValue, tokens[0].StrValue, test.value, test.input)
+		}
+	}
+}
+
+func TestInvalidNumberTokens(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"0x", "Lexer Error: Invalid number 0x at column 0 in expression [0x]"},
+		{"1e", "Lexer Error: Invalid exponent at column 1 in expression [1e]"},
+		{"1e+", "Lexer Error: Invalid exponent at column 2 in expression [1e+]"},
+		{"1__0", "Lexer Error: Invalid numeric separator at column 1 in expression [1__0]"},
+		{"1_", "Lexer Error: Invalid numeric separator at column 1 in expression [1_]"},
+	}
+
+	for _, test := range tests {
+		tokens := Lexer{}.Tokenize(test.input)
+		if len(tokens) == 0 || !tokens[0].isError() || tokens[0].StrValue != test.want {
+			t.Errorf("Tokenize(%q) = %q, want the error %q", test.input, describeTokens(tokens), test.want)
+		}
+	}
+}
+
+func TestErrorTokens(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		index   int
+		line    int
+		column  int
+	}{
+		{"a # b", "Lexer Error: Invalid character [#] at column 2 in expression [a # b]", 2, 0, 2},
+		{"a ~ b", "Lexer Error: Unexpected character [~] at column 3 in expression [a ~ b]", 3, 0, 3},
+		{"a\n  'x", "Lexer Error: Unterminated quote at column 6 in expression [a\n  'x]", 6, 1, 4},
+		{"a +\n\n1e", "Lexer Error: Invalid exponent at column 6 in expression [a +\n\n1e]", 6, 2, 1},
+		{`'\u12\'' + a`, `Lexer Error: Invalid unicode escape [\u12\'] at column 2 in expression ['\u12\'' + a]`, 2, 0, 2},
+	}
+
+	for _, test := range tests {
+		var errors []Token
+		for _, token := range (Lexer{}).Tokenize(test.input) {
+			if token.isError() {
+				errors = append(errors, token)
+			}
+		}
+		if len(errors) != 1 {
+			t.Errorf("Tokenize(%q) has %d errors, want 1", test.input, len(errors))
+			continue
+		}
+		got := errors[0]
+		if got.StrValue != test.message || got.Index != test.index || got.Line != test.line || got.Column != test.column {
+			t.Errorf("Tokenize(%q) = %q at %d (%d:%d), want %q at %d (%d:%d)", test.input,
+				got.StrValue, got.Index, got.Line, got.Column, test.message, test.index, test.line, test.column)
+		}
+	}
+}
+
+// TestStopOnError checks that the lexer continues after an error unless
+// StopOnError is set, where the error is the last token.
+func TestStopOnError(t *testing.T) {
+	input := "a # b ~ c"
+
+	all := describeTokens(Lexer{}.Tokenize(input))
+	want := []string{"a", "error Lexer Error: Invalid character [#] at column 2 in expression [a # b ~ c]", "b",
+		"error Lexer Error: Unexpected character [~] at column 7 in expression [a # b ~ c]", "c"}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("Tokenize(%q) = %q, want %q", input, all, want)
+	}
+
+	if got := describeTokens(Lexer{StopOnError: true}.Tokenize(input)); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("Tokenize(%q) with StopOnError = %q, want %q", input, got, want[:2])
+	}
+}
+
+func TestUnicodeTokenOffsets(t *testing.T) {
+	type span struct {
+		text                 string
+		index, end           int
+		utf16Index, utf16End int
+	}
+	tests := []struct {
+		input string
+		want  []span
+	}{
+		{"привет + 1", []span{{"привет", 0, 12, 0, 6}, {"+", 13, 14, 7, 8}, {"1", 15, 16, 9, 10}}},
+		{"'😀' + x", []span{{"😀", 0, 6, 0, 4}, {"+", 7, 8, 5, 6}, {"x", 9, 10, 7, 8}}},
+		{"a\u00a0+\u00a0b", []span{{"a", 0, 1, 0, 1}, {"+", 3, 4, 2, 3}, {"b", 6, 7, 4, 5}}},
+		{"имя.длина", []span{{"имя", 0, 6, 0, 3}, {".", 6, 7, 3, 4}, {"длина", 7, 17, 4, 9}}},
+	}
+
+	for _, test := range tests {
+		var got []span
+		for _, token := range (Lexer{}).Tokenize(test.input) {
+			got = append(got, span{token.StrValue, token.Index, token.End, token.UTF16Index, token.UTF16End})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", test.input, got, test.want)
+		}
+	}
+
+	// the column of an error is counted in UTF-16 code units
+	tokens := Lexer{}.Tokenize("'😀' # a")
+	if len(tokens) != 3 || !tokens[1].isError() || tokens[1].Column != 5 || tokens[1].UTF16Index != 5 || tokens[1].Index != 7 {
+		t.Errorf("Tokenize(%q) = %q, want an error at byte 7 and column 5", "'😀' # a", describeTokens(tokens))
+	}
+}
+
+func TestOperatorTokens(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"a ** b", []string{"a", "**", "b"}},
+		{"a**-b", []string{"a", "**", "-", "b"}},
+		{"a *= b **= c", []string{"a", "*=", "b", "**=", "c"}},
+		{"a ?? b", []string{"a", "??", "b"}},
+		{"a ??= b", []string{"a", "??=", "b"}},
+		{"a ||= b &&= c", []string{"a", "||=", "b", "&&=", "c"}},
+		{"a?.b ?? c", []string{"a", "?.", "b", "??", "c"}},
+		{"a ? .5 : b", []string{"a", "?", "0.5", ":", "b"}},
+		{"a !== b != c", []string{"a", "!==", "b", "!=", "c"}},
+	}
+
+	for _, test := range tests {
+		var got []string
+		for _, token := range (Lexer{}).Tokenize(test.input) {
+			if token.isNumber() {
+				got = append(got, tokenText(token))
+			} else {
+				got = append(got, token.StrValue)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
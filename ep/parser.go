@@ -0,0 +1,1208 @@
+// Package ep lexes, parses, prints, folds and evaluates Angular binding
+// expressions.
+package ep
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/irustm/ng-template-parser/chars"
+)
+
+// https://github.com/angular/angular/blob/master/packages/compiler/src/expression_parser/parser.ts
+
+type InterpolationConfig struct {
+	Start string
+	End   string
+}
+
+var DefaultInterpolationConfig = InterpolationConfig{Start: "{{", End: "}}"}
+
+type InterpolationPiece struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// SplitInterpolation is an interpolated text split into its strings and
+// expressions, Offsets holds the byte offset of every expression text.
+type SplitInterpolation struct {
+	Strings     []InterpolationPiece
+	Expressions []InterpolationPiece
+	Offsets     []int
+}
+
+type Parser struct {
+	lexer  Lexer
+	errors []ParserError
+}
+
+func NewParser() *Parser {
+	return &Parser{lexer: Lexer{}}
+}
+
+// ParseAction parses an event handler, e.g. `onClick($event); x = 1`.
+func (p *Parser) ParseAction(input string, location string, absoluteOffset int) *ASTWithSource {
+	p.errors = nil
+	p.checkNoInterpolation(input, location, DefaultInterpolationConfig)
+
+	sourceToLex := p.stripComments(input)
+	tokens := p.lexer.Tokenize(sourceToLex)
+	ast := newParseAST(input, location, absoluteOffset, tokens, true, p, 0).parseChain()
+
+	return NewASTWithSource(ast, input, location, absoluteOffset, p.errors)
+}
+
+// ParseBinding parses a property binding, e.g. `user.name | uppercase`.
+func (p *Parser) ParseBinding(input string, location string, absoluteOffset int) *ASTWithSource {
+	p.errors = nil
+	ast := p.parseBindingAst(input, location, absoluteOffset)
+
+	return NewASTWithSource(ast, input, location, absoluteOffset, p.errors)
+}
+
+func (p *Parser) parseBindingAst(input string, location string, absoluteOffset int) AST {
+	p.checkNoInterpolation(input, location, DefaultInterpolationConfig)
+
+	sourceToLex := p.stripComments(input)
+	tokens := p.lexer.Tokenize(sourceToLex)
+
+	return newParseAST(input, location, absoluteOffset, tokens, false, p, 0).parseChain()
+}
+
+// ParseInterpolation parses a text with `{{ }}` interpolations, it returns nil
+// if the text has no interpolation.
+func (p *Parser) ParseInterpolation(input string, location string, absoluteOffset int) *ASTWithSource {
+	p.errors = nil
+
+	split := p.SplitInterpolation(input, location, DefaultInterpolationConfig)
+	if len(split.Expressions) == 0 {
+		return nil
+	}
+
+	var expressionNodes []AST
+	for i, expression := range split.Expressions {
+		expressionText := expression.Text
+		sourceToLex := p.stripComments(expressionText)
+		tokens := p.lexer.Tokenize(sourceToLex)
+		ast := newParseAST(input, location, absoluteOffset, tokens, false, p, split.Offsets[i]).parseChain()
+		expressionNodes = append(expressionNodes, ast)
+	}
+
+	var strings []string
+	for _, piece := range split.Strings {
+		strings = append(strings, piece.Text)
+	}
+
+	return p.createInterpolationAst(strings, expressionNodes, input, location, absoluteOffset)
+}
+
+func (p *Parser) createInterpolationAst(strings []string, expressions []AST, input string, location string, absoluteOffset int) *ASTWithSource {
+	span := ParseSpan{0, len(input)}
+	interpolation := &Interpolation{
+		ASTBase:     ASTBase{span, span.ToAbsolute(absoluteOffset)},
+		Strings:     strings,
+		Expressions: expressions,
+	}
+
+	return NewASTWithSource(interpolation, input, location, absoluteOffset, p.errors)
+}
+
+// SplitInterpolation splits input into the strings outside and the
+// expressions inside interpolation delimiters, delimiters inside quotes of
+// an expression are ignored.
+func (p *Parser) SplitInterpolation(input string, location string, config InterpolationConfig) SplitInterpolation {
+	var result SplitInterpolation
+	var i = 0
+	var atInterpolation = false
+	var extendLastString = false
+
+	for i < len(input) {
+		if !atInterpolation {
+			// parse until starting {{
+			start := i
+			i = strings.Index(input[i:], config.Start)
+			if i == -1 {
+				i = len(input)
+			} else {
+				i += start
+			}
+			result.Strings = append(result.Strings, InterpolationPiece{input[start:i], start, i})
+
+			atInterpolation = true
+		} else {
+			// parse from starting {{ to ending }} while ignoring content inside quotes.
+			fullStart := i
+			exprStart := fullStart + len(config.Start)
+			exprEnd := p.getInterpolationEndIndex(input, config.End, exprStart)
+			if exprEnd == -1 {
+				// Could not find the end of the interpolation; do not parse an expression.
+				// Instead we should extend the content on the last raw string.
+				atInterpolation = false
+				extendLastString = true
+				break
+			}
+			fullEnd := exprEnd + len(config.End)
+
+			text := input[exprStart:exprEnd]
+			if len(strings.TrimSpace(text)) == 0 {
+				p.reportError("Blank expressions are not allowed in interpolated strings", input,
+					"at column "+strconv.Itoa(i)+" in", location)
+			}
+			result.Expressions = append(result.Expressions, InterpolationPiece{text, fullStart, fullEnd})
+			result.Offsets = append(result.Offsets, exprStart)
+
+			i = fullEnd
+			atInterpolation = false
+		}
+	}
+
+	if !atInterpolation {
+		// If we are now at a text section, add the remaining content as a raw string.
+		if extendLastString {
+			piece := &result.Strings[len(result.Strings)-1]
+			piece.Text += input[i:]
+			piece.End = len(input)
+		} else {
+			result.Strings = append(result.Strings, InterpolationPiece{input[i:], i, len(input)})
+		}
+	}
+
+	return result
+}
+
+// getInterpolationEndIndex finds the end of an interpolation, skipping end
+// delimiters inside string literals of the expression.
+func (p *Parser) getInterpolationEndIndex(input string, expressionEnd string, start int) int {
+	var currentQuote byte
+	var escapeCount = 0
+
+	for i := start; i < len(input); i++ {
+		char := input[i]
+
+		if (char == '\'' || char == '"' || char == '`') && (currentQuote == 0 || currentQuote == char) && escapeCount%2 == 0 {
+			if currentQuote == 0 {
+				currentQuote = char
+			} else {
+				currentQuote = 0
+			}
+		} else if currentQuote == 0 && strings.HasPrefix(input[i:], expressionEnd) {
+			return i
+		}
+
+		if char == '\\' {
+			escapeCount++
+		} else {
+			escapeCount = 0
+		}
+	}
+
+	return -1
+}
+
+func (p *Parser) checkNoInterpolation(input string, location string, config InterpolationConfig) {
+	startIndex := -1
+	endIndex := -1
+
+	var currentQuote byte
+	for i := 0; i < len(input); i++ {
+		char := input[i]
+
+		if char == '\'' || char == '"' || char == '`' {
+			if currentQuote == 0 {
+				currentQuote = char
+			} else if currentQuote == char && (i == 0 || input[i-1] != '\\') {
+				currentQuote = 0
+			}
+		} else if currentQuote == 0 {
+			if startIndex == -1 && strings.HasPrefix(input[i:], config.Start) {
+				startIndex = i
+			} else if strings.HasPrefix(input[i:], config.End) && startIndex > -1 {
+				endIndex = i
+				break
+			}
+		}
+	}
+
+	if startIndex > -1 && endIndex > -1 {
+		p.reportError("Got interpolation ("+config.Start+config.End+") where expression was expected", input,
+			"at column "+strconv.Itoa(startIndex)+" in", location)
+	}
+}
+
+// stripComments removes a trailing `//` comment which is not inside quotes.
+func (p *Parser) stripComments(input string) string {
+	if i := p.commentStart(input); i != -1 {
+		return input[:i]
+	}
+	return input
+}
+
+func (p *Parser) commentStart(input string) int {
+	var outerQuote int = -1
+
+	for i := 0; i < len(input)-1; i++ {
+		char := int(input[i])
+		nextChar := int(input[i+1])
+
+		if char == chars.VSLASH && nextChar == chars.VSLASH && outerQuote == -1 {
+			return i
+		}
+
+		if outerQuote == char {
+			outerQuote = -1
+		} else if outerQuote == -1 && chars.IsQuote(char) {
+			outerQuote = char
+		}
+	}
+
+	return -1
+}
+
+func (p *Parser) reportError(message string, input string, errLocation string, ctxLocation string) {
+	p.errors = append(p.errors, NewParserError(message, input, errLocation, ctxLocation))
+}
+
+var assignmentOperators = []string{"=", "+=", "-=", "*=", "/=", "%=", "**=", "&&=", "||=", "??="}
+
+type parseContextFlags int
+
+const (
+	parseContextNone parseContextFlags = 0
+	// A Writable context is one in which a value may be written to an lvalue.
+	// For example, after we see a property access, we may expect a write to the
+	// property via the "=" operator.
+	//   prop
+	//        ^ possible "=" after
+	parseContextWritable parseContextFlags = 1
+)
+
+type parseAST struct {
+	input          string
+	location       string
+	absoluteOffset int
+	tokens         []Token
+	parseAction    bool
+	parser         *Parser
+	offset         int
+
+	rparensExpected   int
+	rbracketsExpected int
+	rbracesExpected   int
+	context           parseContextFlags
+
+	index int
+}
+
+func newParseAST(input string, location string, absoluteOffset int, tokens []Token, parseAction bool, parser *Parser, offset int) *parseAST {
+	return &parseAST{
+		input:          input,
+		location:       location,
+		absoluteOffset: absoluteOffset,
+		tokens:         tokens,
+		parseAction:    parseAction,
+		parser:         parser,
+		offset:         offset,
+	}
+}
+
+func (p *parseAST) peek(offset int) Token {
+	i := p.index + offset
+	if i >= 0 && i < len(p.tokens) {
+		return p.tokens[i]
+	}
+	return EOF
+}
+
+func (p *parseAST) next() Token {
+	return p.peek(0)
+}
+
+func (p *parseAST) atEOF() bool {
+	return p.index >= len(p.tokens)
+}
+
+// inputIndex is the index of the next token to be processed, or the end of
+// the last token if all have been processed.
+func (p *parseAST) inputIndex() int {
+	if p.atEOF() {
+		return p.currentEndIndex()
+	}
+	return p.next().Index + p.offset
+}
+
+// currentEndIndex is the end index of the last processed token, or the start
+// of the first token if none have been processed.
+func (p *parseAST) currentEndIndex() int {
+	if p.index > 0 {
+		curToken := p.peek(-1)
+		return curToken.End + p.offset
+	}
+
+	// No tokens have been processed yet; return the next token's start or the
+	// length of the input if there is no token.
+	if len(p.tokens) == 0 {
+		return len(p.input) + p.offset
+	}
+	return p.next().Index + p.offset
+}
+
+func (p *parseAST) currentAbsoluteOffset() int {
+	return p.absoluteOffset + p.inputIndex()
+}
+
+// span returns the span from start to the end of the last processed token.
+func (p *parseAST) span(start int) ParseSpan {
+	endIndex := p.currentEndIndex()
+
+	if start > endIndex {
+		start, endIndex = endIndex, start
+	}
+
+	return ParseSpan{start, endIndex}
+}
+
+func (p *parseAST) spanTo(start int, artificialEndIndex int) ParseSpan {
+	endIndex := p.currentEndIndex()
+	if artificialEndIndex > endIndex {
+		endIndex = artificialEndIndex
+	}
+
+	if start > endIndex {
+		start, endIndex = endIndex, start
+	}
+
+	return ParseSpan{start, endIndex}
+}
+
+func (p *parseAST) sourceSpan(start int) AbsoluteSourceSpan {
+	return p.span(start).ToAbsolute(p.absoluteOffset)
+}
+
+func (p *parseAST) base(start int) ASTBase {
+	return ASTBase{p.span(start), p.sourceSpan(start)}
+}
+
+func (p *parseAST) advance() {
+	p.index++
+}
+
+// withContext executes cb in the given context, restoring the previous one
+// afterwards.
+func (p *parseAST) withContext(context parseContextFlags, cb func() AST) AST {
+	p.context |= context
+	ret := cb()
+	p.context ^= context
+	return ret
+}
+
+func (p *parseAST) consumeOptionalCharacter(code int) bool {
+	if p.next().isCharacter(code) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parseAST) peekKeywordLet() bool {
+	return p.next().isKeywordLet()
+}
+
+func (p *parseAST) peekKeywordAs() bool {
+	return p.next().isKeywordAs()
+}
+
+// expectCharacter consumes an expected character, otherwise emits an error
+// about the missing expected character and skips over the token stream until
+// reaching a recoverable point.
+func (p *parseAST) expectCharacter(code int) {
+	if p.consumeOptionalCharacter(code) {
+		return
+	}
+	p.error("Missing expected "+string(rune(code)), -1)
+}
+
+func (p *parseAST) consumeOptionalOperator(op string) bool {
+	if p.next().isOperator(op) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parseAST) expectOperator(operator string) {
+	if p.consumeOptionalOperator(operator) {
+		return
+	}
+	p.error("Missing expected operator "+operator, -1)
+}
+
+func (p *parseAST) prettyPrintToken(tok Token) string {
+	if tok == EOF {
+		return "end of input"
+	}
+	return "token " + tokenText(tok)
+}
+
+func (p *parseAST) expectIdentifierOrKeyword() (string, bool) {
+	n := p.next()
+	if !n.isIdentifier() && !n.isKeyword() {
+		if n.isPrivateIdentifier() {
+			p.reportErrorForPrivateIdentifier(n, "expected identifier or keyword")
+		} else {
+			p.error("Unexpected "+p.prettyPrintToken(n)+", expected identifier or keyword", -1)
+		}
+		return "", false
+	}
+	p.advance()
+	return n.StrValue, true
+}
+
+func (p *parseAST) expectIdentifierOrKeywordOrString() string {
+	n := p.next()
+	if !n.isIdentifier() && !n.isKeyword() && !n.isString() {
+		if n.isPrivateIdentifier() {
+			p.reportErrorForPrivateIdentifier(n, "expected identifier, keyword or string")
+		} else {
+			p.error("Unexpected "+p.prettyPrintToken(n)+", expected identifier, keyword, or string", -1)
+		}
+		return ""
+	}
+	p.advance()
+	return n.StrValue
+}
+
+func (p *parseAST) parseChain() AST {
+	var exprs []AST
+	start := p.inputIndex()
+
+	for p.index < len(p.tokens) {
+		expr := p.parsePipe()
+		exprs = append(exprs, expr)
+
+		if p.consumeOptionalCharacter(chars.VSEMICOLON) {
+			if !p.parseAction {
+				p.error("Binding expression cannot contain chained expression", -1)
+			}
+			for p.consumeOptionalCharacter(chars.VSEMICOLON) {
+			} // read all semicolons
+		} else if p.index < len(p.tokens) {
+			errorIndex := p.index
+			p.error("Unexpected token '"+tokenText(p.next())+"'", -1)
+
+			// The `error` call above will skip ahead to the next recovery point in an attempt to
+			// recover part of the expression, but that might be the token we started from which will
+			// lead to an infinite loop. If that's the case, break the loop assuming that we can't
+			// parse further.
+			if p.index == errorIndex {
+				break
+			}
+		}
+	}
+
+	if len(exprs) == 0 {
+		// We have no expressions so create an empty expression that spans the entire input length
+		artificialStart := p.offset
+		artificialEnd := p.offset + len(p.input)
+		span := p.spanTo(artificialStart, artificialEnd)
+		return &EmptyExpr{ASTBase{span, span.ToAbsolute(p.absoluteOffset)}}
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+
+	return &Chain{ASTBase: p.base(start), Expressions: exprs}
+}
+
+func (p *parseAST) parsePipe() AST {
+	start := p.inputIndex()
+	result := p.parseExpression()
+
+	if p.consumeOptionalOperator("|") {
+		if p.parseAction {
+			// Report without skipping so the pipe itself still parses.
+			p.parser.reportError("Cannot have a pipe in an action expression", p.input, p.locationText(-1), p.location)
+		}
+
+		for {
+			nameStart := p.inputIndex()
+			nameID, ok := p.expectIdentifierOrKeyword()
+			var nameSpan AbsoluteSourceSpan
+			var fullSpanEnd = -1
+
+			if ok {
+				nameSpan = p.sourceSpan(nameStart)
+			} else {
+				// No valid identifier was found, so we'll assume an empty pipe name ('').
+				nameID = ""
+
+				// However, there may have been whitespace present between the pipe character and the next
+				// token in the sequence (or the end of input). We want to track this whitespace so that
+				// the `BindingPipe` we produce covers not just the pipe character, but any trailing
+				// whitespace beyond it. Another way of thinking about this is that the zero-length name
+				// is assumed to be at the end of any whitespace beyond the pipe character.
+				//
+				// Therefore, we push the end of the `ParseSpan` for this pipe all the way up to the
+				// beginning of the next token, or until the end of input if the next token is EOF.
+				if p.next().Index != -1 {
+					fullSpanEnd = p.next().Index
+				} else {
+					fullSpanEnd = len(p.input) + p.offset
+				}
+
+				// The `nameSpan` for an empty pipe name is zero-length at the end of any whitespace
+				// beyond the pipe character.
+				nameSpan = ParseSpan{fullSpanEnd, fullSpanEnd}.ToAbsolute(p.absoluteOffset)
+			}
+
+			var args []AST
+			for p.consumeOptionalCharacter(chars.VCOLON) {
+				args = append(args, p.parseExpression())
+
+				// If there are additional expressions beyond the name, then the artificial end for the
+				// name is no longer relevant.
+			}
+
+			span := p.spanTo(start, fullSpanEnd)
+			result = &BindingPipe{
+				ASTWithName: ASTWithName{ASTBase{span, span.ToAbsolute(p.absoluteOffset)}, nameSpan},
+				Exp:         result,
+				Name:        nameID,
+				Args:        args,
+			}
+
+			if !p.consumeOptionalOperator("|") {
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+func (p *parseAST) parseExpression() AST {
+	return p.parseConditional()
+}
+
+func (p *parseAST) parseConditional() AST {
+	start := p.inputIndex()
+	result := p.parseLogicalOr()
+
+	if p.consumeOptionalOperator("?") {
+		yes := p.parsePipe()
+		var no AST
+
+		if !p.consumeOptionalCharacter(chars.VCOLON) {
+			end := p.inputIndex()
+			expression := p.input[start-p.offset : end-p.offset]
+			p.error("Conditional expression "+expression+" requires all 3 expressions", -1)
+			no = &EmptyExpr{p.base(start)}
+		} else {
+			no = p.parsePipe()
+		}
+
+		return &Conditional{ASTBase: p.base(start), Condition: result, TrueExp: yes, FalseExp: no}
+	}
+
+	return result
+}
+
+func (p *parseAST) parseLogicalOr() AST {
+	// '||'
+	start := p.inputIndex()
+	result := p.parseLogicalAnd()
+
+	for p.consumeOptionalOperator("||") {
+		right := p.parseLogicalAnd()
+		result = &Binary{ASTBase: p.base(start), Operation: "||", Left: result, Right: right}
+	}
+
+	return result
+}
+
+func (p *parseAST) parseLogicalAnd() AST {
+	// '&&'
+	start := p.inputIndex()
+	result := p.parseNullishCoalescing()
+
+	for p.consumeOptionalOperator("&&") {
+		right := p.parseNullishCoalescing()
+		result = &Binary{ASTBase: p.base(start), Operation: "&&", Left: result, Right: right}
+	}
+
+	return result
+}
+
+func (p *parseAST) parseNullishCoalescing() AST {
+	// '??'
+	start := p.inputIndex()
+	result := p.parseEquality()
+
+	for p.consumeOptionalOperator("??") {
+		right := p.parseEquality()
+		result = &Binary{ASTBase: p.base(start), Operation: "??", Left: result, Right: right}
+	}
+
+	return result
+}
+
+func (p *parseAST) parseEquality() AST {
+	// '==','!=','===','!=='
+	start := p.inputIndex()
+	result := p.parseRelational()
+
+	for p.next().TypeToken == Operator {
+		operator := p.next().StrValue
+
+		switch operator {
+		case "==", "===", "!=", "!==":
+			p.advance()
+			right := p.parseRelational()
+			result = &Binary{ASTBase: p.base(start), Operation: operator, Left: result, Right: right}
+			continue
+		}
+		break
+	}
+
+	return result
+}
+
+func (p *parseAST) parseRelational() AST {
+	// '<', '>', '<=', '>='
+	start := p.inputIndex()
+	result := p.parseAdditive()
+
+	for p.next().TypeToken == Operator || p.next().isKeywordIn() {
+		operator := p.next().StrValue
+
+		switch operator {
+		case "<", ">", "<=", ">=", "in":
+			p.advance()
+			right := p.parseAdditive()
+			result = &Binary{ASTBase: p.base(start), Operation: operator, Left: result, Right: right}
+			continue
+		}
+		break
+	}
+
+	return result
+}
+
+func (p *parseAST) parseAdditive() AST {
+	// '+', '-'
+	start := p.inputIndex()
+	result := p.parseMultiplicative()
+
+	for p.next().TypeToken == Operator {
+		operator := p.next().StrValue
+
+		switch operator {
+		case "+", "-":
+			p.advance()
+			right := p.parseMultiplicative()
+			result = &Binary{ASTBase: p.base(start), Operation: operator, Left: result, Right: right}
+			continue
+		}
+		break
+	}
+
+	return result
+}
+
+func (p *parseAST) parseMultiplicative() AST {
+	// '*', '%', '/'
+	start := p.inputIndex()
+	result := p.parseExponentiation()
+
+	for p.next().TypeToken == Operator {
+		operator := p.next().StrValue
+
+		switch operator {
+		case "*", "%", "/":
+			p.advance()
+			right := p.parseExponentiation()
+			result = &Binary{ASTBase: p.base(start), Operation: operator, Left: result, Right: right}
+			continue
+		}
+		break
+	}
+
+	return result
+}
+
+func (p *parseAST) parseExponentiation() AST {
+	// '**'
+	start := p.inputIndex()
+	unary := p.next().isOperator("+") || p.next().isOperator("-") || p.next().isOperator("!") ||
+		p.next().isKeywordTypeof() || p.next().isKeywordVoid()
+	result := p.parsePrefix()
+
+	for p.next().isOperator("**") {
+		// This aligns with Javascript semantics which require any unary operator preceeding the
+		// exponentiation operation to be explicitly grouped as either applying to the base or result
+		// of the exponentiation operation.
+		if unary {
+			p.parser.reportError("Unary operator used immediately before exponentiation expression. Parenthesis must be used to disambiguate operator precedence",
+				p.input, p.locationText(-1), p.location)
+		}
+
+		p.advance()
+		// '**' is right associative.
+		right := p.parseExponentiation()
+		result = &Binary{ASTBase: p.base(start), Operation: "**", Left: result, Right: right}
+	}
+
+	return result
+}
+
+func (p *parseAST) parsePrefix() AST {
+	if p.next().isKeywordTypeof() {
+		start := p.inputIndex()
+		p.advance()
+		result := p.parsePrefix()
+		return &TypeofExpression{ASTBase: p.base(start), Expression: result}
+	}
+
+	if p.next().isKeywordVoid() {
+		start := p.inputIndex()
+		p.advance()
+		result := p.parsePrefix()
+		return &VoidExpression{ASTBase: p.base(start), Expression: result}
+	}
+
+	if p.next().TypeToken == Operator {
+		start := p.inputIndex()
+		operator := p.next().StrValue
+
+		switch operator {
+		case "+", "-":
+			p.advance()
+			result := p.parsePrefix()
+			return &Unary{ASTBase: p.base(start), Operator: operator, Expr: result}
+		case "!":
+			p.advance()
+			result := p.parsePrefix()
+			return &PrefixNot{ASTBase: p.base(start), Expression: result}
+		}
+	}
+
+	return p.parseCallChain()
+}
+
+func (p *parseAST) parseCallChain() AST {
+	start := p.inputIndex()
+	result := p.parsePrimary()
+
+	for {
+		if p.consumeOptionalCharacter(chars.VPERIOD) {
+			result = p.parseAccessMember(result, start, false)
+		} else if p.consumeOptionalOperator("?.") {
+			if p.consumeOptionalCharacter(chars.VLPAREN) {
+				result = p.parseCall(result, start, true)
+			} else if p.consumeOptionalCharacter(chars.VLBRACKET) {
+				result = p.parseKeyedReadOrWrite(result, start, true)
+			} else {
+				result = p.parseAccessMember(result, start, true)
+			}
+		} else if p.consumeOptionalCharacter(chars.VLBRACKET) {
+			result = p.parseKeyedReadOrWrite(result, start, false)
+		} else if p.consumeOptionalCharacter(chars.VLPAREN) {
+			result = p.parseCall(result, start, false)
+		} else if p.consumeOptionalOperator("!") {
+			result = &NonNullAssert{ASTBase: p.base(start), Expression: result}
+		} else if p.next().isTemplateLiteralEnd() || p.next().isTemplateLiteralPart() {
+			result = p.parseTaggedTemplateLiteral(result, start)
+		} else {
+			return result
+		}
+	}
+}
+
+func (p *parseAST) parsePrimary() AST {
+	start := p.inputIndex()
+
+	if p.consumeOptionalCharacter(chars.VLPAREN) {
+		p.rparensExpected++
+		result := p.parsePipe()
+		p.rparensExpected--
+		p.expectCharacter(chars.VRPAREN)
+		return result
+	} else if p.next().isKeywordNull() {
+		p.advance()
+		return &LiteralPrimitive{p.base(start), nil}
+	} else if p.next().isKeywordUndefined() {
+		p.advance()
+		return &LiteralPrimitive{p.base(start), Undefined}
+	} else if p.next().isKeywordTrue() {
+		p.advance()
+		return &LiteralPrimitive{p.base(start), true}
+	} else if p.next().isKeywordFalse() {
+		p.advance()
+		return &LiteralPrimitive{p.base(start), false}
+	} else if p.next().isKeywordThis() {
+		p.advance()
+		return &ThisReceiver{ImplicitReceiver{p.base(start)}}
+	} else if p.consumeOptionalCharacter(chars.VLBRACKET) {
+		p.rbracketsExpected++
+		elements := p.parseExpressionList(chars.VRBRACKET)
+		p.rbracketsExpected--
+		p.expectCharacter(chars.VRBRACKET)
+		return &LiteralArray{p.base(start), elements}
+	} else if p.next().isCharacter(chars.VLBRACE) {
+		return p.parseLiteralMap()
+	} else if p.next().isIdentifier() {
+		return p.parseAccessMember(&ImplicitReceiver{p.base(start)}, start, false)
+	} else if p.next().isNumber() {
+		value := p.next().toNumber()
+		p.advance()
+		return &LiteralPrimitive{p.base(start), value}
+	} else if p.next().isTemplateLiteralEnd() || p.next().isTemplateLiteralPart() {
+		return p.parseTemplateLiteral()
+	} else if p.next().isString() {
+		literalValue := p.next().StrValue
+		p.advance()
+		return &LiteralPrimitive{p.base(start), literalValue}
+	} else if p.next().isPrivateIdentifier() {
+		p.reportErrorForPrivateIdentifier(p.next(), "")
+		return &EmptyExpr{p.base(start)}
+	} else if p.index >= len(p.tokens) {
+		p.error("Unexpected end of expression: "+p.input, -1)
+		return &EmptyExpr{p.base(start)}
+	} else {
+		p.error("Unexpected token "+tokenText(p.next()), -1)
+		return &EmptyExpr{p.base(start)}
+	}
+}
+
+func (p *parseAST) parseExpressionList(terminator int) []AST {
+	var result []AST
+
+	for {
+		if !p.next().isCharacter(terminator) {
+			result = append(result, p.parsePipe())
+		} else {
+			break
+		}
+
+		if !p.consumeOptionalCharacter(chars.VCOMMA) {
+			break
+		}
+	}
+
+	return result
+}
+
+func (p *parseAST) parseLiteralMap() AST {
+	var keys []LiteralMapKey
+	var values []AST
+	start := p.inputIndex()
+	p.expectCharacter(chars.VLBRACE)
+
+	if !p.consumeOptionalCharacter(chars.VRBRACE) {
+		p.rbracesExpected++
+
+		for {
+			keyStart := p.inputIndex()
+			quoted := p.next().isString()
+			key := p.expectIdentifierOrKeywordOrString()
+			keys = append(keys, LiteralMapKey{Key: key, Quoted: quoted})
+
+			// Properties with quoted keys can't use the shorthand syntax.
+			if quoted {
+				p.expectCharacter(chars.VCOLON)
+				values = append(values, p.parsePipe())
+			} else if p.consumeOptionalCharacter(chars.VCOLON) {
+				values = append(values, p.parsePipe())
+			} else {
+				base := p.base(keyStart)
+				values = append(values, &PropertyRead{
+					ASTWithName: ASTWithName{base, base.SourceSpan},
+					Receiver:    &ImplicitReceiver{base},
+					Name:        key,
+				})
+			}
+
+			if !p.consumeOptionalCharacter(chars.VCOMMA) {
+				break
+			}
+		}
+
+		p.rbracesExpected--
+		p.expectCharacter(chars.VRBRACE)
+	}
+
+	return &LiteralMap{ASTBase: p.base(start), Keys: keys, Values: values}
+}
+
+func (p *parseAST) parseAccessMember(readReceiver AST, start int, isSafe bool) AST {
+	nameStart := p.inputIndex()
+	var id string
+
+	p.withContext(parseContextWritable, func() AST {
+		var ok bool
+		id, ok = p.expectIdentifierOrKeyword()
+		if !ok || len(id) == 0 {
+			p.error("Expected identifier for property access", readReceiver.GetSpan().End)
+		}
+		return nil
+	})
+	nameSpan := p.sourceSpan(nameStart)
+	nameEnd := p.currentEndIndex()
+
+	if isSafe {
+		if p.isAssignmentOperation() {
+			p.advance()
+			p.error("The '?.' operator cannot be used in the assignment", -1)
+			return &EmptyExpr{p.base(start)}
+		}
+
+		return &SafePropertyRead{
+			ASTWithName: ASTWithName{p.base(start), nameSpan},
+			Receiver:    readReceiver,
+			Name:        id,
+		}
+	}
+
+	if p.isAssignmentOperation() {
+		operation := p.next().StrValue
+		p.advance()
+
+		if !p.parseAction {
+			p.error("Bindings cannot contain assignments", -1)
+			return &EmptyExpr{p.base(start)}
+		}
+
+		value := p.parseConditional()
+		if operation != "=" {
+			targetSpan := ParseSpan{start, nameEnd}
+			target := &PropertyRead{
+				ASTWithName: ASTWithName{ASTBase{targetSpan, targetSpan.ToAbsolute(p.absoluteOffset)}, nameSpan},
+				Receiver:    readReceiver,
+				Name:        id,
+			}
+			return &CompoundAssignment{ASTBase: p.base(start), Operation: operation, Target: target, Value: value}
+		}
+
+		return &PropertyWrite{
+			ASTWithName: ASTWithName{p.base(start), nameSpan},
+			Receiver:    readReceiver,
+			Name:        id,
+			Value:       value,
+		}
+	}
+
+	return &PropertyRead{
+		ASTWithName: ASTWithName{p.base(start), nameSpan},
+		Receiver:    readReceiver,
+		Name:        id,
+	}
+}
+
+// isAssignmentOperation reports whether the next token is `=` or a compound
+// assignment operator such as `+=`.
+func (p *parseAST) isAssignmentOperation() bool {
+	n := p.next()
+	return n.TypeToken == Operator && contains(assignmentOperators, n.StrValue)
+}
+
+func (p *parseAST) parseCall(receiver AST, start int, isSafe bool) AST {
+	argumentStart := p.inputIndex()
+	p.rparensExpected++
+	args := p.parseCallArguments()
+	argumentSpan := p.spanTo(argumentStart, p.inputIndex()).ToAbsolute(p.absoluteOffset)
+	p.expectCharacter(chars.VRPAREN)
+	p.rparensExpected--
+
+	if isSafe {
+		return &SafeCall{ASTBase: p.base(start), Receiver: receiver, Args: args, ArgumentSpan: argumentSpan}
+	}
+
+	return &Call{ASTBase: p.base(start), Receiver: receiver, Args: args, ArgumentSpan: argumentSpan}
+}
+
+func (p *parseAST) parseCallArguments() []AST {
+	if p.next().isCharacter(chars.VRPAREN) {
+		return nil
+	}
+
+	var positionals []AST
+	for {
+		positionals = append(positionals, p.parsePipe())
+
+		if !p.consumeOptionalCharacter(chars.VCOMMA) {
+			break
+		}
+	}
+
+	return positionals
+}
+
+func (p *parseAST) parseKeyedReadOrWrite(receiver AST, start int, isSafe bool) AST {
+	return p.withContext(parseContextWritable, func() AST {
+		p.rbracketsExpected++
+		key := p.parsePipe()
+		if _, ok := key.(*EmptyExpr); ok {
+			p.error("Key access cannot be empty", -1)
+		}
+		p.rbracketsExpected--
+		p.expectCharacter(chars.VRBRACKET)
+
+		if isSafe {
+			if p.isAssignmentOperation() {
+				p.advance()
+				p.error("The '?.' operator cannot be used in the assignment", -1)
+				return &EmptyExpr{p.base(start)}
+			}
+
+			return &SafeKeyedRead{ASTBase: p.base(start), Receiver: receiver, Key: key}
+		}
+
+		if p.isAssignmentOperation() {
+			keyEnd := p.currentEndIndex()
+			operation := p.next().StrValue
+			p.advance()
+
+			if operation != "=" && !p.parseAction {
+				p.error("Bindings cannot contain assignments", -1)
+				return &EmptyExpr{p.base(start)}
+			}
+
+			value := p.parseConditional()
+			if operation != "=" {
+				targetSpan := ParseSpan{start, keyEnd}
+				target := &KeyedRead{ASTBase: ASTBase{targetSpan, targetSpan.ToAbsolute(p.absoluteOffset)}, Receiver: receiver, Key: key}
+				return &CompoundAssignment{ASTBase: p.base(start), Operation: operation, Target: target, Value: value}
+			}
+
+			return &KeyedWrite{ASTBase: p.base(start), Receiver: receiver, Key: key, Value: value}
+		}
+
+		return &KeyedRead{ASTBase: p.base(start), Receiver: receiver, Key: key}
+	})
+}
+
+// parseTemplateLiteral parses a template literal, starting at its first part
+// token up to and including its end token.
+func (p *parseAST) parseTemplateLiteral() *TemplateLiteral {
+	var elements []*TemplateLiteralElement
+	var expressions []AST
+	start := p.inputIndex()
+
+	for p.index < len(p.tokens) {
+		token := p.next()
+
+		if token.isTemplateLiteralPart() || token.isTemplateLiteralEnd() {
+			partStart := p.inputIndex()
+			p.advance()
+			elements = append(elements, &TemplateLiteralElement{ASTBase: p.base(partStart), Text: token.StrValue})
+
+			if token.isTemplateLiteralEnd() {
+				break
+			}
+		} else if token.isTemplateLiteralInterpolationStart() {
+			p.advance()
+			if p.next().isTemplateLiteralInterpolationEnd() {
+				p.error("Template literal interpolation cannot be empty", -1)
+				continue
+			}
+
+			p.rbracesExpected++
+			expressions = append(expressions, p.parsePipe())
+			p.rbracesExpected--
+		} else if token.isTemplateLiteralInterpolationEnd() {
+			p.advance()
+		} else {
+			p.error("Unexpected token "+tokenText(token)+" in template literal", -1)
+			if p.index < len(p.tokens) && p.next() == token {
+				p.advance()
+			}
+		}
+	}
+
+	return &TemplateLiteral{ASTBase: p.base(start), Elements: elements, Expressions: expressions}
+}
+
+func (p *parseAST) parseTaggedTemplateLiteral(tag AST, start int) AST {
+	template := p.parseTemplateLiteral()
+	return &TaggedTemplateLiteral{ASTBase: p.base(start), Tag: tag, Template: template}
+}
+
+// error records an error and skips over the token stream until reaching a
+// recoverable point. index is the token index to report, -1 for the current
+// one. A lexer error at the current token is only reported by skip, with the
+// message of the lexer.
+func (p *parseAST) error(message string, index int) {
+	if index != -1 || !p.next().isError() {
+		p.parser.reportError(message, p.input, p.locationText(index), p.location)
+	}
+	p.skip()
+}
+
+func (p *parseAST) locationText(index int) string {
+	if index == -1 {
+		index = p.index
+	}
+
+	if index < len(p.tokens) {
+		return "at column " + strconv.Itoa(p.tokens[index].Index+1) + " in"
+	}
+	return "at the end of the expression"
+}
+
+// reportErrorForPrivateIdentifier records an error for a private identifier,
+// which is not supported in templates.
+func (p *parseAST) reportErrorForPrivateIdentifier(token Token, extraMessage string) {
+	errorMessage := "Private identifiers are not supported. Unexpected private identifier: " + token.StrValue
+	if extraMessage != "" {
+		errorMessage += ", " + extraMessage
+	}
+	p.error(errorMessage, -1)
+}
+
+// skip advances to a recoverable point in the token stream, e.g. an unmatched
+// `)`, `]` or `}`, reporting lexer errors on the way.
+//
+// Error recovery should skip tokens until it encounters a recovery point.
+//
+// The following are treated as unconditional recovery points:
+//   - end of input
+//   - ';' (parseChain() is always the root production, and it expects a ';')
+//   - '|' (since pipes may be chained and each pipe expression may be treated independently)
+//
+// The following are conditional recovery points:
+//   - ')', '}', ']' if one of calling productions is expecting one of these symbols
+//   - This allows skip() to recover from errors such as '(a.) + 1' allowing more of the AST to
+//     be retained (it doesn't skip any tokens as the ')' is retained because of the '(' begins
+//     an '(' <expr> ')' production).
+//     The recovery points of grouping symbols must be conditional as they must be skipped if
+//     none of the calling productions are not expecting the closing token else we will never
+//     make progress in the case of an extraneous group closing symbol (such as a stray ')').
+//     That is, we skip a closing symbol if we are not in a grouping production.
+//   - '=' in a `Writable` context
+//   - In this context, we are able to recover after seeing the `=` operator, which
+//     signals the presence of an independent rvalue expression following the `=` operator.
+func (p *parseAST) skip() {
+	n := p.next()
+
+	for p.index < len(p.tokens) && !n.isCharacter(chars.VSEMICOLON) &&
+		!n.isOperator("|") && (p.rparensExpected <= 0 || !n.isCharacter(chars.VRPAREN)) &&
+		(p.rbracesExpected <= 0 || !n.isCharacter(chars.VRBRACE) && !n.isTemplateLiteralInterpolationEnd()) &&
+		(p.rbracketsExpected <= 0 || !n.isCharacter(chars.VRBRACKET)) &&
+		(p.context&parseContextWritable == 0 || !p.isAssignmentOperation()) {
+
+		if p.next().isError() {
+			p.parser.reportError(p.next().StrValue, p.input, p.locationText(-1), p.location)
+		}
+		p.advance()
+		n = p.next()
+	}
+}
+
+// tokenText returns the source form of a token for error messages.
+func tokenText(t Token) string {
+	if t.TypeToken == Number {
+		return formatNumber(t.NumValue)
+	}
+	return t.StrValue
+}
@@ -0,0 +1,105 @@
+package ep
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describe writes an expression with every binary parenthesized.
+func describe(ast AST) string {
+	switch a := ast.(type) {
+	case *ImplicitReceiver:
+		return ""
+	case *PropertyRead:
+		if receiver := describe(a.Receiver); receiver != "" {
+			return receiver + "." + a.Name
+		}
+		return a.Name
+	case *LiteralPrimitive:
+		if s, ok := a.Value.(string); ok {
+			return fmt.Sprintf("%q", s)
+		}
+		return fmt.Sprint(a.Value)
+	case *Binary:
+		return "(" + describe(a.Left) + " " + a.Operation + " " + describe(a.Right) + ")"
+	case *Call:
+		var args []string
+		for _, arg := range a.Args {
+			args = append(args, describe(arg))
+		}
+		return describe(a.Receiver) + "(" + strings.Join(args, ", ") + ")"
+	case *TemplateLiteral:
+		var b strings.Builder
+		b.WriteString("`")
+		for i, element := range a.Elements {
+			b.WriteString(element.Text)
+			if i < len(a.Expressions) {
+				b.WriteString("${" + describe(a.Expressions[i]) + "}")
+			}
+		}
+		b.WriteString("`")
+		return b.String()
+	case *TaggedTemplateLiteral:
+		return describe(a.Tag) + describe(a.Template)
+	default:
+		return fmt.Sprintf("%T", ast)
+	}
+}
+
+func TestParseBinding(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 + 2 * 3", "(1 + (2 * 3))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a.b(c, 'd')", `a.b(c, "d")`},
+		{"`a${b}c`", "`a${b}c`"},
+		{"`${a + `b${c}`}`", "`${(a + `b${c}`)}`"},
+		{"tag`x${y}`", "tag`x${y}`"},
+	}
+
+	parser := NewParser()
+	for _, test := range tests {
+		ast := parser.ParseBinding(test.input, "", 0)
+		if len(ast.Errors) > 0 {
+			t.Errorf("ParseBinding(%q): %s", test.input, ast.Errors[0].Message)
+			continue
+		}
+		if got := describe(ast.Ast); got != test.want {
+			t.Errorf("ParseBinding(%q) = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParseBindingErrors(t *testing.T) {
+	parser := NewParser()
+	for _, input := range []string{"a +", "`a${b`", "a b", "(a"} {
+		if ast := parser.ParseBinding(input, "", 0); len(ast.Errors) == 0 {
+			t.Errorf("ParseBinding(%q) has no errors", input)
+		}
+	}
+}
+
+// TestParseLexerErrors checks that an invalid token is reported once, with the
+// message of the lexer.
+func TestParseLexerErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1e", "Parser Error: Lexer Error: Invalid exponent at column 1 in expression [1e] at column 2 in [1e] in "},
+		{"a + 1e", "Parser Error: Lexer Error: Invalid exponent at column 5 in expression [a + 1e] at column 6 in [a + 1e] in "},
+		{"'abc", "Parser Error: Lexer Error: Unterminated quote at column 4 in expression ['abc] at column 5 in ['abc] in "},
+		{"a # b", "Parser Error: Lexer Error: Invalid character [#] at column 2 in expression [a # b] at column 3 in [a # b] in "},
+	}
+
+	parser := NewParser()
+	for _, test := range tests {
+		ast := parser.ParseBinding(test.input, "", 0)
+		if len(ast.Errors) != 1 || ast.Errors[0].Message != test.want {
+			t.Errorf("ParseBinding(%q) errors = %v, want %q", test.input, ast.Errors, test.want)
+		}
+	}
+}
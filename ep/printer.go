@@ -0,0 +1,596 @@
+package ep
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// https://github.com/angular/angular/blob/master/packages/compiler/src/expression_parser/ast.ts (Unparser)
+
+// Operator precedence of the printed expressions, from loosest to tightest.
+const (
+	precedenceChain = iota
+	precedencePipe
+	precedenceAssignment
+	precedenceConditional
+	precedenceLogicalOr
+	precedenceLogicalAnd
+	precedenceNullish
+	precedenceEquality
+	precedenceRelational
+	precedenceAdditive
+	precedenceMultiplicative
+	precedenceExponentiation
+	precedencePrefix
+	precedencePostfix
+	precedencePrimary
+)
+
+var binaryPrecedence = map[string]int{
+	"||":  precedenceLogicalOr,
+	"&&":  precedenceLogicalAnd,
+	"??":  precedenceNullish,
+	"==":  precedenceEquality,
+	"!=":  precedenceEquality,
+	"===": precedenceEquality,
+	"!==": precedenceEquality,
+	"<":   precedenceRelational,
+	">":   precedenceRelational,
+	"<=":  precedenceRelational,
+	">=":  precedenceRelational,
+	"in":  precedenceRelational,
+	"+":   precedenceAdditive,
+	"-":   precedenceAdditive,
+	"*":   precedenceMultiplicative,
+	"/":   precedenceMultiplicative,
+	"%":   precedenceMultiplicative,
+	"**":  precedenceExponentiation,
+}
+
+// Print returns the canonical source of an expression: single spaces around
+// binary operators, single-quoted strings and only the parentheses needed to
+// parse back into the same tree.
+func Print(ast AST) string {
+	p := &printer{}
+	p.print(ast, precedenceChain)
+	return p.out.String()
+}
+
+// PrintPreserving prints an expression like Print but copies the original
+// text of every subtree which is unchanged since parsing, so only rewritten
+// parts of the expression are normalised. Nodes created after parsing must
+// have an empty span.
+func PrintPreserving(ast *ASTWithSource) string {
+	p := &printer{source: ast.Source, offset: ast.SourceSpan.Start}
+	p.print(ast.Ast, precedenceChain)
+	return p.out.String()
+}
+
+type printer struct {
+	out    strings.Builder
+	source string
+	// offset is the absolute offset of source, to resolve name spans.
+	offset int
+}
+
+// print writes ast, in parentheses if it binds looser than precedence.
+func (p *printer) print(ast AST, precedence int) {
+	if ast == nil {
+		return
+	}
+
+	if outer, ok := ast.(*ASTWithSource); ok {
+		ast = outer.Ast
+	}
+
+	parens := astPrecedence(ast) < precedence
+	if parens {
+		p.out.WriteString("(")
+	}
+
+	if p.source != "" && p.isOriginal(ast) {
+		span := ast.GetSpan()
+		p.out.WriteString(p.source[span.Start:span.End])
+	} else {
+		ast.Visit(p, nil)
+	}
+
+	if parens {
+		p.out.WriteString(")")
+	}
+}
+
+// isOriginal reports whether ast and all of its descendants still have the
+// spans they were parsed with, in source order.
+func (p *printer) isOriginal(ast AST) bool {
+	span := ast.GetSpan()
+	if span.Start < 0 || span.End > len(p.source) || span.Start > span.End {
+		return false
+	}
+
+	if _, ok := ast.(*EmptyExpr); !ok && span.Start == span.End {
+		return false
+	}
+
+	if !p.matchesSource(ast) {
+		return false
+	}
+
+	last := span.Start
+	for _, child := range childNodes(ast) {
+		// Implicit receivers have no text of their own.
+		if _, ok := child.(*ImplicitReceiver); child == nil || ok {
+			continue
+		}
+
+		childSpan := child.GetSpan()
+		if childSpan.Start < last || childSpan.End > span.End || !p.isOriginal(child) {
+			return false
+		}
+		last = childSpan.End
+	}
+
+	return true
+}
+
+// matchesSource reports whether the names, operators and literal values of
+// ast are still those of its source text.
+func (p *printer) matchesSource(ast AST) bool {
+	switch a := ast.(type) {
+	case *PropertyRead:
+		return p.nameMatches(a.NameSpan, a.Name)
+	case *SafePropertyRead:
+		return p.nameMatches(a.NameSpan, a.Name)
+	case *PropertyWrite:
+		return p.nameMatches(a.NameSpan, a.Name)
+	case *BindingPipe:
+		return p.nameMatches(a.NameSpan, a.Name)
+	case *Binary:
+		between := p.source[a.Left.GetSpan().End:a.Right.GetSpan().Start]
+		return strings.Trim(between, "() \t\n\r") == a.Operation
+	case *Unary:
+		return strings.HasPrefix(p.source[a.Span.Start:], a.Operator)
+	case *LiteralPrimitive:
+		tokens := (&Lexer{}).Tokenize(p.source[a.Span.Start:a.Span.End])
+		if len(tokens) != 1 {
+			return false
+		}
+		switch value := a.Value.(type) {
+		case float64:
+			return tokens[0].isNumber() && tokens[0].NumValue == value
+		case string:
+			return tokens[0].isString() && tokens[0].StrValue == value
+		default:
+			return tokens[0].isKeyword() && tokens[0].StrValue == Print(a)
+		}
+	}
+	return true
+}
+
+func (p *printer) nameMatches(nameSpan AbsoluteSourceSpan, name string) bool {
+	start, end := nameSpan.Start-p.offset, nameSpan.End-p.offset
+	return start >= 0 && end <= len(p.source) && start <= end && p.source[start:end] == name
+}
+
+func astPrecedence(ast AST) int {
+	switch a := ast.(type) {
+	case *Chain:
+		return precedenceChain
+	case *BindingPipe:
+		return precedencePipe
+	case *PropertyWrite, *KeyedWrite, *CompoundAssignment:
+		return precedenceAssignment
+	case *Conditional:
+		return precedenceConditional
+	case *Binary:
+		if precedence, ok := binaryPrecedence[a.Operation]; ok {
+			return precedence
+		}
+		return precedenceLogicalOr
+	case *Unary, *PrefixNot, *TypeofExpression, *VoidExpression:
+		return precedencePrefix
+	case *PropertyRead, *SafePropertyRead, *KeyedRead, *SafeKeyedRead, *Call, *SafeCall,
+		*NonNullAssert, *TaggedTemplateLiteral:
+		return precedencePostfix
+	default:
+		return precedencePrimary
+	}
+}
+
+// printReceiver writes the receiver of a member access, call or tagged
+// template followed by the access operator.
+func (p *printer) printReceiver(receiver AST, operator string) {
+	switch r := receiver.(type) {
+	case *ThisReceiver:
+		if operator == "." {
+			p.out.WriteString("this.")
+			return
+		}
+	case *ImplicitReceiver:
+		return
+	case *LiteralPrimitive:
+		// `1.toString` would lex as a number.
+		if _, ok := r.Value.(float64); ok {
+			p.out.WriteString("(")
+			p.print(receiver, precedenceChain)
+			p.out.WriteString(")" + operator)
+			return
+		}
+	}
+
+	p.print(receiver, precedencePostfix)
+	p.out.WriteString(operator)
+}
+
+func (p *printer) printList(asts []AST, precedence int) {
+	for i, ast := range asts {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.print(ast, precedence)
+	}
+}
+
+func (p *printer) VisitUnary(ast *Unary, context interface{}) interface{} {
+	p.out.WriteString(ast.Operator)
+	p.printPrefixOperand(ast.Expr)
+	return nil
+}
+
+// printPrefixOperand writes the operand of a prefix operator, `- -a` is
+// written as `-(-a)` to keep the operators apart.
+func (p *printer) printPrefixOperand(ast AST) {
+	if _, ok := ast.(*Unary); ok {
+		p.out.WriteString("(")
+		p.print(ast, precedenceChain)
+		p.out.WriteString(")")
+		return
+	}
+	p.print(ast, precedencePrefix)
+}
+
+func (p *printer) VisitBinary(ast *Binary, context interface{}) interface{} {
+	precedence := astPrecedence(ast)
+	left, right := precedence, precedence+1
+
+	// '**' is right associative and its base can't be a prefix expression.
+	if ast.Operation == "**" {
+		left, right = precedencePostfix, precedence
+	}
+
+	// JavaScript rejects '??' next to '||' or '&&' without parentheses.
+	if mixesNullish(ast.Operation, ast.Left) {
+		left = precedencePrimary
+	}
+	if mixesNullish(ast.Operation, ast.Right) {
+		right = precedencePrimary
+	}
+
+	p.print(ast.Left, left)
+	p.out.WriteString(" " + ast.Operation + " ")
+	p.print(ast.Right, right)
+	return nil
+}
+
+// mixesNullish reports whether operand is a '??' in an '||' or '&&' or the
+// other way around.
+func mixesNullish(operation string, operand AST) bool {
+	if outer, ok := operand.(*ASTWithSource); ok {
+		operand = outer.Ast
+	}
+	binary, ok := operand.(*Binary)
+	if !ok {
+		return false
+	}
+	if operation == "??" {
+		return binary.Operation == "||" || binary.Operation == "&&"
+	}
+	return binary.Operation == "??" && (operation == "||" || operation == "&&")
+}
+
+func (p *printer) VisitChain(ast *Chain, context interface{}) interface{} {
+	for i, expression := range ast.Expressions {
+		if i > 0 {
+			p.out.WriteString("; ")
+		}
+		p.print(expression, precedencePipe)
+	}
+	return nil
+}
+
+func (p *printer) VisitConditional(ast *Conditional, context interface{}) interface{} {
+	p.print(ast.Condition, precedenceLogicalOr)
+	p.out.WriteString(" ? ")
+	p.print(ast.TrueExp, precedencePipe)
+	p.out.WriteString(" : ")
+	p.print(ast.FalseExp, precedencePipe)
+	return nil
+}
+
+func (p *printer) VisitThisReceiver(ast *ThisReceiver, context interface{}) interface{} {
+	p.out.WriteString("this")
+	return nil
+}
+
+func (p *printer) VisitImplicitReceiver(ast *ImplicitReceiver, context interface{}) interface{} {
+	return nil
+}
+
+func (p *printer) VisitInterpolation(ast *Interpolation, context interface{}) interface{} {
+	for i, str := range ast.Strings {
+		p.out.WriteString(str)
+		if i < len(ast.Expressions) {
+			p.out.WriteString("{{ ")
+			p.print(ast.Expressions[i], precedenceChain)
+			p.out.WriteString(" }}")
+		}
+	}
+	return nil
+}
+
+func (p *printer) VisitKeyedRead(ast *KeyedRead, context interface{}) interface{} {
+	p.print(ast.Receiver, precedencePostfix)
+	p.out.WriteString("[")
+	p.print(ast.Key, precedencePipe)
+	p.out.WriteString("]")
+	return nil
+}
+
+func (p *printer) VisitKeyedWrite(ast *KeyedWrite, context interface{}) interface{} {
+	p.print(ast.Receiver, precedencePostfix)
+	p.out.WriteString("[")
+	p.print(ast.Key, precedencePipe)
+	p.out.WriteString("] = ")
+	// assignments are right associative, `a = b = c` needs no parentheses
+	p.print(ast.Value, precedenceAssignment)
+	return nil
+}
+
+func (p *printer) VisitLiteralArray(ast *LiteralArray, context interface{}) interface{} {
+	p.out.WriteString("[")
+	p.printList(ast.Expressions, precedencePipe)
+	p.out.WriteString("]")
+	return nil
+}
+
+func (p *printer) VisitLiteralMap(ast *LiteralMap, context interface{}) interface{} {
+	p.out.WriteString("{")
+	for i, key := range ast.Keys {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+
+		if key.Quoted || !IsIdentifier(key.Key) {
+			p.out.WriteString(quoteString(key.Key))
+		} else {
+			p.out.WriteString(key.Key)
+		}
+
+		p.out.WriteString(": ")
+		p.print(ast.Values[i], precedencePipe)
+	}
+	p.out.WriteString("}")
+	return nil
+}
+
+func (p *printer) VisitLiteralPrimitive(ast *LiteralPrimitive, context interface{}) interface{} {
+	switch value := ast.Value.(type) {
+	case nil:
+		p.out.WriteString("null")
+	case undefined:
+		p.out.WriteString("undefined")
+	case bool:
+		p.out.WriteString(strconv.FormatBool(value))
+	case float64:
+		p.out.WriteString(formatNumber(value))
+	case string:
+		p.out.WriteString(quoteString(value))
+	}
+	return nil
+}
+
+func (p *printer) VisitPipe(ast *BindingPipe, context interface{}) interface{} {
+	p.print(ast.Exp, precedencePipe)
+	p.out.WriteString(" | " + ast.Name)
+	for _, arg := range ast.Args {
+		p.out.WriteString(":")
+		p.print(arg, precedenceLogicalOr)
+	}
+	return nil
+}
+
+func (p *printer) VisitPrefixNot(ast *PrefixNot, context interface{}) interface{} {
+	p.out.WriteString("!")
+	p.printPrefixOperand(ast.Expression)
+	return nil
+}
+
+func (p *printer) VisitNonNullAssert(ast *NonNullAssert, context interface{}) interface{} {
+	p.print(ast.Expression, precedencePostfix)
+	p.out.WriteString("!")
+	return nil
+}
+
+func (p *printer) VisitTypeofExpression(ast *TypeofExpression, context interface{}) interface{} {
+	p.out.WriteString("typeof ")
+	p.print(ast.Expression, precedencePrefix)
+	return nil
+}
+
+func (p *printer) VisitVoidExpression(ast *VoidExpression, context interface{}) interface{} {
+	p.out.WriteString("void ")
+	p.print(ast.Expression, precedencePrefix)
+	return nil
+}
+
+func (p *printer) VisitCompoundAssignment(ast *CompoundAssignment, context interface{}) interface{} {
+	p.print(ast.Target, precedencePostfix)
+	p.out.WriteString(" " + ast.Operation + " ")
+	p.print(ast.Value, precedenceAssignment)
+	return nil
+}
+
+func (p *printer) VisitPropertyRead(ast *PropertyRead, context interface{}) interface{} {
+	p.printReceiver(ast.Receiver, ".")
+	p.out.WriteString(ast.Name)
+	return nil
+}
+
+func (p *printer) VisitPropertyWrite(ast *PropertyWrite, context interface{}) interface{} {
+	p.printReceiver(ast.Receiver, ".")
+	p.out.WriteString(ast.Name + " = ")
+	p.print(ast.Value, precedenceAssignment)
+	return nil
+}
+
+func (p *printer) VisitSafePropertyRead(ast *SafePropertyRead, context interface{}) interface{} {
+	p.printReceiver(ast.Receiver, "?.")
+	p.out.WriteString(ast.Name)
+	return nil
+}
+
+func (p *printer) VisitSafeKeyedRead(ast *SafeKeyedRead, context interface{}) interface{} {
+	p.print(ast.Receiver, precedencePostfix)
+	p.out.WriteString("?.[")
+	p.print(ast.Key, precedencePipe)
+	p.out.WriteString("]")
+	return nil
+}
+
+func (p *printer) VisitCall(ast *Call, context interface{}) interface{} {
+	p.print(ast.Receiver, precedencePostfix)
+	p.out.WriteString("(")
+	p.printList(ast.Args, precedencePipe)
+	p.out.WriteString(")")
+	return nil
+}
+
+func (p *printer) VisitSafeCall(ast *SafeCall, context interface{}) interface{} {
+	p.print(ast.Receiver, precedencePostfix)
+	p.out.WriteString("?.(")
+	p.printList(ast.Args, precedencePipe)
+	p.out.WriteString(")")
+	return nil
+}
+
+func (p *printer) VisitTemplateLiteral(ast *TemplateLiteral, context interface{}) interface{} {
+	p.out.WriteString("`")
+	for i, element := range ast.Elements {
+		element.Visit(p, context)
+		if i < len(ast.Expressions) {
+			p.out.WriteString("${")
+			p.print(ast.Expressions[i], precedencePipe)
+			p.out.WriteString("}")
+		}
+	}
+	p.out.WriteString("`")
+	return nil
+}
+
+func (p *printer) VisitTemplateLiteralElement(ast *TemplateLiteralElement, context interface{}) interface{} {
+	text := strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${").Replace(ast.Text)
+	p.out.WriteString(text)
+	return nil
+}
+
+func (p *printer) VisitTaggedTemplateLiteral(ast *TaggedTemplateLiteral, context interface{}) interface{} {
+	p.print(ast.Tag, precedencePostfix)
+	p.print(ast.Template, precedencePrimary)
+	return nil
+}
+
+// quoteString returns str as a single-quoted expression string literal,
+// expressions usually live in double-quoted attributes.
+func quoteString(str string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+
+	for _, r := range str {
+		switch r {
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == utf8.RuneError {
+				hex := strconv.FormatInt(int64(r), 16)
+				b.WriteString(`\u` + strings.Repeat("0", 4-len(hex)) + hex)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// childNodes returns the direct child expressions of ast in source order.
+func childNodes(ast AST) []AST {
+	switch a := ast.(type) {
+	case *ASTWithSource:
+		return []AST{a.Ast}
+	case *Chain:
+		return a.Expressions
+	case *Conditional:
+		return []AST{a.Condition, a.TrueExp, a.FalseExp}
+	case *PropertyRead:
+		return []AST{a.Receiver}
+	case *SafePropertyRead:
+		return []AST{a.Receiver}
+	case *PropertyWrite:
+		return []AST{a.Receiver, a.Value}
+	case *KeyedRead:
+		return []AST{a.Receiver, a.Key}
+	case *SafeKeyedRead:
+		return []AST{a.Receiver, a.Key}
+	case *KeyedWrite:
+		return []AST{a.Receiver, a.Key, a.Value}
+	case *CompoundAssignment:
+		return []AST{a.Target, a.Value}
+	case *BindingPipe:
+		return append([]AST{a.Exp}, a.Args...)
+	case *LiteralArray:
+		return a.Expressions
+	case *LiteralMap:
+		return a.Values
+	case *Interpolation:
+		return a.Expressions
+	case *Binary:
+		return []AST{a.Left, a.Right}
+	case *Unary:
+		return []AST{a.Expr}
+	case *PrefixNot:
+		return []AST{a.Expression}
+	case *TypeofExpression:
+		return []AST{a.Expression}
+	case *VoidExpression:
+		return []AST{a.Expression}
+	case *NonNullAssert:
+		return []AST{a.Expression}
+	case *Call:
+		return append([]AST{a.Receiver}, a.Args...)
+	case *SafeCall:
+		return append([]AST{a.Receiver}, a.Args...)
+	case *TemplateLiteral:
+		var children []AST
+		for i, element := range a.Elements {
+			children = append(children, element)
+			if i < len(a.Expressions) {
+				children = append(children, a.Expressions[i])
+			}
+		}
+		return children
+	case *TaggedTemplateLiteral:
+		return []AST{a.Tag, a.Template}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package ep
+
+import "testing"
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		input  string
+		action bool
+		want   string
+	}{
+		{input: "(a ?? b) || c", want: "(a ?? b) || c"},
+		{input: "a || (b ?? c)", want: "a || (b ?? c)"},
+		{input: "(a && b) ?? c", want: "(a && b) ?? c"},
+		{input: "a ?? b ?? c", want: "a ?? b ?? c"},
+		{input: "(a || b) && c", want: "(a || b) && c"},
+		{input: "a || b && c", want: "a || b && c"},
+		{input: "a = b = c", action: true, want: "a = b = c"},
+		{input: "a[0] = b.c = 1", action: true, want: "a[0] = b.c = 1"},
+		{input: "a += b ? c : d", action: true, want: "a += b ? c : d"},
+		{input: "(a ? b : c) + 1", want: "(a ? b : c) + 1"},
+	}
+
+	for _, test := range tests {
+		var ast *ASTWithSource
+		if test.action {
+			ast = NewParser().ParseAction(test.input, "", 0)
+		} else {
+			ast = NewParser().ParseBinding(test.input, "", 0)
+		}
+		if len(ast.Errors) > 0 {
+			t.Errorf("parse %q: %v", test.input, ast.Errors)
+			continue
+		}
+		got := Print(ast)
+		if got != test.want {
+			t.Errorf("Print(%q) = %q, want %q", test.input, got, test.want)
+		}
+
+		// the canonical output parses back into the same output
+		var again *ASTWithSource
+		if test.action {
+			again = NewParser().ParseAction(got, "", 0)
+		} else {
+			again = NewParser().ParseBinding(got, "", 0)
+		}
+		if len(again.Errors) > 0 || Print(again) != got {
+			t.Errorf("Print(%q) = %q doesn't parse back: %v", test.input, got, again.Errors)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package ep
+
+// ShiftAST moves the absolute spans of an expression and its sub-expressions
+// by delta in place, e.g. after text was inserted before it. The relative
+// spans stay.
+func ShiftAST(ast AST, delta int) {
+	Walk(ast, func(ast AST) bool {
+		if named, ok := ast.(interface{ withName() *ASTWithName }); ok {
+			named.withName().NameSpan.shift(delta)
+		}
+		if based, ok := ast.(interface{ base() *ASTBase }); ok {
+			based.base().SourceSpan.shift(delta)
+		}
+		switch a := ast.(type) {
+		case *Call:
+			a.ArgumentSpan.shift(delta)
+		case *SafeCall:
+			a.ArgumentSpan.shift(delta)
+		}
+		return true
+	})
+}
+
+func (a *ASTBase) base() *ASTBase {
+	return a
+}
+
+func (a *ASTWithName) withName() *ASTWithName {
+	return a
+}
+
+func (s *AbsoluteSourceSpan) shift(delta int) {
+	s.Start += delta
+	s.End += delta
+}
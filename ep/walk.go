@@ -0,0 +1,80 @@
+package ep
+
+// Children returns the sub-expressions of an expression in source order.
+func Children(ast AST) []AST {
+	switch a := ast.(type) {
+	case *Chain:
+		return a.Expressions
+	case *Conditional:
+		return []AST{a.Condition, a.TrueExp, a.FalseExp}
+	case *PropertyRead:
+		return []AST{a.Receiver}
+	case *PropertyWrite:
+		return []AST{a.Receiver, a.Value}
+	case *SafePropertyRead:
+		return []AST{a.Receiver}
+	case *KeyedRead:
+		return []AST{a.Receiver, a.Key}
+	case *SafeKeyedRead:
+		return []AST{a.Receiver, a.Key}
+	case *KeyedWrite:
+		return []AST{a.Receiver, a.Key, a.Value}
+	case *BindingPipe:
+		return append([]AST{a.Exp}, a.Args...)
+	case *LiteralArray:
+		return a.Expressions
+	case *LiteralMap:
+		return a.Values
+	case *Interpolation:
+		return a.Expressions
+	case *Binary:
+		return []AST{a.Left, a.Right}
+	case *Unary:
+		return []AST{a.Expr}
+	case *PrefixNot:
+		return []AST{a.Expression}
+	case *TypeofExpression:
+		return []AST{a.Expression}
+	case *VoidExpression:
+		return []AST{a.Expression}
+	case *CompoundAssignment:
+		return []AST{a.Target, a.Value}
+	case *NonNullAssert:
+		return []AST{a.Expression}
+	case *Call:
+		return append([]AST{a.Receiver}, a.Args...)
+	case *SafeCall:
+		return append([]AST{a.Receiver}, a.Args...)
+	case *TemplateLiteral:
+		// the literal text and the `${}` expressions alternate
+		var children []AST
+		for i, element := range a.Elements {
+			if element != nil {
+				children = append(children, element)
+			}
+			if i < len(a.Expressions) {
+				children = append(children, a.Expressions[i])
+			}
+		}
+		return children
+	case *TaggedTemplateLiteral:
+		if a.Template == nil {
+			return []AST{a.Tag}
+		}
+		return []AST{a.Tag, a.Template}
+	case *ASTWithSource:
+		return []AST{a.Ast}
+	}
+	return nil
+}
+
+// Walk calls fn for an expression and its sub-expressions in source order,
+// the sub-expressions of an expression are skipped if fn returns false.
+func Walk(ast AST, fn func(AST) bool) {
+	if ast == nil || !fn(ast) {
+		return
+	}
+	for _, child := range Children(ast) {
+		Walk(child, fn)
+	}
+}
@@ -1,8 +1,5 @@
 module github.com/irustm/ng-template-parser
 
-require (
-	github.com/SimplePEG/Go v0.1.1
-	golang.org/x/net v0.0.0-20211201190559-0a0e4e1bb54c
-)
+require golang.org/x/net v0.0.0-20211201190559-0a0e4e1bb54c
 
 go 1.17
@@ -0,0 +1,14 @@
+import { parseTemplate } from "@angular/compiler";
+import fs from "fs";
+import path from "path";
+
+// Records the parseTemplate output of every template in
+// template/testdata/angular, `go test -run TestConformance ./template`
+// compares the Go parser against it.
+const dir = "../template/testdata/angular";
+
+for (const file of fs.readdirSync(dir).filter((f) => f.endsWith(".html"))) {
+  const code = fs.readFileSync(path.join(dir, file), "utf8");
+  const data = parseTemplate(code, "./" + file);
+  fs.writeFileSync(path.join(dir, file.replace(/\.html$/, ".json")), JSON.stringify(data));
+}
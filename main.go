@@ -1,10 +1,340 @@
 package main
 
-import "github.com/irustm/ng-template-parser/ep"
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/irustm/ng-template-parser/schema"
+	"github.com/irustm/ng-template-parser/template"
+)
 
 func main() {
-	lex := ep.Lexer{}
-	tokens := lex.Tokenize("rr+jama")
+	var command string
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	switch command {
+	case "validate":
+		os.Exit(validateCommand(os.Args[2:]))
+	case "security":
+		os.Exit(securityCommand(os.Args[2:]))
+	case "fmt":
+		os.Exit(fmtCommand(os.Args[2:]))
+	case "lint":
+		os.Exit(lintCommand(os.Args[2:]))
+	case "render":
+		os.Exit(renderCommand(os.Args[2:]))
+	case "json":
+		os.Exit(jsonCommand(os.Args[2:]))
+	case "lsp":
+		os.Exit(lspCommand(os.Args[2:]))
+	default:
+		fmt.Fprintln(os.Stderr, "usage: ngbuild [validate|security|lint|fmt|render|json|lsp] [flags] files...")
+		os.Exit(2)
+	}
+}
+
+func validateCommand(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	useCache := cacheFlags(flags)
+	customElements := flags.Bool("custom-elements", false, "allow unknown elements and properties on custom elements (CUSTOM_ELEMENTS_SCHEMA)")
+	noErrors := flags.Bool("no-errors", false, "allow any element and property (NO_ERRORS_SCHEMA)")
+	_ = flags.Parse(args)
+	if err := useCache(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var schemas []schema.SchemaMetadata
+	if *customElements {
+		schemas = append(schemas, schema.CustomElementsSchema)
+	}
+	if *noErrors {
+		schemas = append(schemas, schema.NoErrorsSchema)
+	}
+
+	registry := schema.NewDomElementSchemaRegistry()
+	status := 0
+
+	for _, path := range flags.Args() {
+		root, err := parseFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		for _, parseError := range root.Errors {
+			fmt.Printf("%s: %s\n", path, parseError.Message)
+			status = 1
+		}
+
+		for _, schemaError := range template.ValidateSchema(root, registry, schemas) {
+			fmt.Printf("%s: <%s>: %s\n", path, schemaError.Element, schemaError.Message)
+			status = 1
+		}
+	}
+
+	return status
+}
+
+func securityCommand(args []string) int {
+	flags := flag.NewFlagSet("security", flag.ExitOnError)
+	useCache := cacheFlags(flags)
+	_ = flags.Parse(args)
+	if err := useCache(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	registry := schema.NewDomElementSchemaRegistry()
+	status := 0
+
+	for _, path := range flags.Args() {
+		root, err := parseFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		template.AnnotateSecurityContexts(&root, registry)
+
+		for _, entry := range template.SecurityReport(root) {
+			fmt.Printf("%s: <%s> %s=%q %s\n", path, entry.Element, template.BindingKey(entry.Binding),
+				entry.Binding.Value.Source, entry.Binding.SecurityContext)
+		}
+	}
+
+	return status
+}
+
+func lintCommand(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	useCache := cacheFlags(flags)
+	_ = flags.Parse(args)
+	if err := useCache(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	status := 0
+
+	for _, path := range flags.Args() {
+		root, err := parseFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		for _, warning := range template.CheckConstantConditions(root) {
+			element := warning.Element
+			if !strings.HasPrefix(element, "@") {
+				element = "<" + element + ">"
+			}
+			fmt.Printf("%s: %s: %s\n", path, element, warning.Message)
+			status = 1
+		}
+	}
+
+	return status
+}
+
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	useCache := cacheFlags(flags)
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	check := flags.Bool("check", false, "list the files whose formatting differs and fail if there are any")
+	width := flags.Int("width", template.DefaultFormatOptions.Width, "line width after which attributes are wrapped")
+	_ = flags.Parse(args)
+	if err := useCache(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	options := template.DefaultFormatOptions
+	options.Width = *width
+	status := 0
+
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		root := parseSource(src, path)
+		if len(root.Errors) > 0 {
+			for _, parseError := range root.Errors {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", path, parseError.Message)
+			}
+			status = 1
+			continue
+		}
+		formatted := []byte(template.Format(root, options))
+
+		switch {
+		case *check:
+			if !bytes.Equal(src, formatted) {
+				fmt.Println(path)
+				status = 1
+			}
+		case *write:
+			if !bytes.Equal(src, formatted) {
+				if err := ioutil.WriteFile(path, formatted, 0644); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					status = 1
+				}
+			}
+		default:
+			os.Stdout.Write(formatted)
+		}
+	}
+
+	return status
+}
+
+func renderCommand(args []string) int {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	useCache := cacheFlags(flags)
+	contextPath := flags.String("context", "", "JSON file with the values of the names in the template")
+	_ = flags.Parse(args)
+	if err := useCache(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var context interface{} = map[string]interface{}{}
+	if *contextPath != "" {
+		data, err := ioutil.ReadFile(*contextPath)
+		if err == nil {
+			err = json.Unmarshal(data, &context)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	registry := schema.NewDomElementSchemaRegistry()
+	status := 0
+
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		html, err := template.Render(parseSource(src, path), context, registry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 1
+			continue
+		}
+		fmt.Println(html)
+	}
+
+	return status
+}
+
+func jsonCommand(args []string) int {
+	flags := flag.NewFlagSet("json", flag.ExitOnError)
+	useCache := cacheFlags(flags)
+	angular := flags.Bool("angular", false, "print the AST of parseTemplate of @angular/compiler instead of the template tree")
+	url := flags.String("url", "", "templateUrl of the spans in -angular mode, defaults to the file path")
+	_ = flags.Parse(args)
+	if err := useCache(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	registry := schema.NewDomElementSchemaRegistry()
+	status := 0
+
+	for _, path := range flags.Args() {
+		root, err := parseFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		var data []byte
+		if *angular {
+			templateURL := *url
+			if templateURL == "" {
+				templateURL = path
+			}
+			data, err = template.MarshalAngularJSON(root, templateURL, registry)
+		} else {
+			template.AnnotateSecurityContexts(&root, registry)
+			data, err = json.Marshal(root)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 1
+			continue
+		}
+		fmt.Println(string(data))
+	}
+
+	return status
+}
+
+// lspCommand runs the language server on stdin and stdout for editors.
+func lspCommand(args []string) int {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	if err := template.ServeLSP(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// cacheFlags adds the flags of the parse cache to a command, the returned
+// function enables the cache once the flags are parsed.
+func cacheFlags(flags *flag.FlagSet) func() error {
+	dir := flags.String("cache-dir", "", "keep parsed templates in this directory and only parse changed files")
+	size := flags.Int64("cache-size", template.DefaultCacheSize, "size limit of the cache directory in bytes, the least recently used templates are removed")
+
+	return func() error {
+		if *dir == "" {
+			return nil
+		}
+		var err error
+		cache, err = template.NewParseCache(*dir, *size)
+		return err
+	}
+}
+
+// cache is used by parseFile and parseSource when set, see cacheFlags.
+var cache *template.ParseCache
+
+func parseFile(path string) (template.Root, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return template.Root{}, err
+	}
+
+	return parseSource(src, path), nil
+}
 
-	println(len(tokens))
+// parseSource parses a template or returns it from the cache.
+func parseSource(src []byte, location string) template.Root {
+	if cache != nil {
+		return cache.Parse(src, location)
+	}
+	return template.Parse(bytes.NewReader(src), location)
 }
@@ -0,0 +1,473 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+// https://github.com/angular/angular/blob/master/packages/compiler/src/schema/dom_element_schema_registry.ts
+
+type SchemaMetadata struct {
+	Name string
+}
+
+// CustomElementsSchema allows unknown elements and properties on elements whose
+// name contains a dash.
+var CustomElementsSchema = SchemaMetadata{Name: "custom-elements"}
+
+// NoErrorsSchema allows any element and any property.
+var NoErrorsSchema = SchemaMetadata{Name: "no-errors-schema"}
+
+type PropertyType string
+
+const (
+	PropertyTypeBoolean PropertyType = "boolean"
+	PropertyTypeNumber  PropertyType = "number"
+	PropertyTypeString  PropertyType = "string"
+	PropertyTypeObject  PropertyType = "object"
+)
+
+/**
+ * This array represents the DOM schema. It encodes inheritance, properties, and events.
+ *
+ * ## Overview
+ *
+ * Each line represents one kind of element. The `element_inheritance` and properties are joined
+ * using `element_inheritance|properties` syntax.
+ *
+ * ## Element Inheritance
+ *
+ * The `element_inheritance` can be further subdivided as `element1,element2,...^parentElement`.
+ * Here the individual elements are separated by `,` (commas). Every element in the list
+ * has identical properties.
+ *
+ * An `element` may inherit additional properties from `parentElement` If no `^parentElement` is
+ * specified then `""` (blank) element is assumed.
+ *
+ * NOTE: The blank element inherits from root `[Element]` element, the super element of all
+ * elements.
+ *
+ * NOTE an element prefix such as `:svg:` has no special meaning to the schema.
+ *
+ * ## Properties
+ *
+ * Each element has a set of properties separated by `,` (commas). Each property can be prefixed
+ * by a special character designating its type:
+ *
+ * - (no prefix): property is a string.
+ * - `*`: property represents an event.
+ * - `!`: property is a boolean.
+ * - `#`: property is a number.
+ * - `%`: property is an object.
+ */
+var domSchema = []string{
+	"[Element]|textContent,%ariaAtomic,%ariaAutoComplete,%ariaBusy,%ariaChecked,%ariaColCount,%ariaColIndex,%ariaColSpan,%ariaCurrent,%ariaDescription,%ariaDisabled,%ariaExpanded,%ariaHasPopup,%ariaHidden,%ariaKeyShortcuts,%ariaLabel,%ariaLevel,%ariaLive,%ariaModal,%ariaMultiLine,%ariaMultiSelectable,%ariaOrientation,%ariaPlaceholder,%ariaPosInSet,%ariaPressed,%ariaReadOnly,%ariaRelevant,%ariaRequired,%ariaRoleDescription,%ariaRowCount,%ariaRowIndex,%ariaRowSpan,%ariaSelected,%ariaSetSize,%ariaSort,%ariaValueMax,%ariaValueMin,%ariaValueNow,%ariaValueText,%classList,className,elementTiming,id,innerHTML,*beforecopy,*beforecut,*beforepaste,*fullscreenchange,*fullscreenerror,*search,*webkitfullscreenchange,*webkitfullscreenerror,outerHTML,%part,#scrollLeft,#scrollTop,slot" +
+		/* added manually to avoid breaking changes */
+		",*message,*mozfullscreenchange,*mozfullscreenerror,*mozpointerlockchange,*mozpointerlockerror,*webglcontextcreationerror,*webglcontextlost,*webglcontextrestored",
+	"[HTMLElement]^[Element]|accessKey,autocapitalize,!autofocus,contentEditable,dir,!draggable,enterKeyHint,!hidden,innerText,inputMode,lang,nonce,*abort,*animationend,*animationiteration,*animationstart,*auxclick,*beforexrselect,*blur,*cancel,*canplay,*canplaythrough,*change,*click,*close,*contextmenu,*copy,*cuechange,*cut,*dblclick,*drag,*dragend,*dragenter,*dragleave,*dragover,*dragstart,*drop,*durationchange,*emptied,*ended,*error,*focus,*formdata,*gotpointercapture,*input,*invalid,*keydown,*keypress,*keyup,*load,*loadeddata,*loadedmetadata,*loadstart,*lostpointercapture,*mousedown,*mouseenter,*mouseleave,*mousemove,*mouseout,*mouseover,*mouseup,*mousewheel,*paste,*pause,*play,*playing,*pointercancel,*pointerdown,*pointerenter,*pointerleave,*pointermove,*pointerout,*pointerover,*pointerrawupdate,*pointerup,*progress,*ratechange,*reset,*resize,*scroll,*securitypolicyviolation,*seeked,*seeking,*select,*selectionchange,*selectstart,*slotchange,*stalled,*submit,*suspend,*timeupdate,*toggle,*transitioncancel,*transitionend,*transitionrun,*transitionstart,*volumechange,*waiting,*webkitanimationend,*webkitanimationiteration,*webkitanimationstart,*webkittransitionend,*wheel,outerText,!spellcheck,%style,#tabIndex,title,!translate,virtualKeyboardPolicy",
+	"abbr,address,article,aside,b,bdi,bdo,cite,content,code,dd,dfn,dt,em,figcaption,figure,footer,header,hgroup,i,kbd,main,mark,nav,noscript,rb,rp,rt,rtc,ruby,s,samp,section,small,strong,sub,sup,u,var,wbr^[HTMLElement]|",
+	"media^[HTMLElement]|!autoplay,!controls,%controlsList,%crossOrigin,#currentTime,!defaultMuted,#defaultPlaybackRate,!disableRemotePlayback,!loop,!muted,*encrypted,*waitingforkey,#playbackRate,preload,!preservesPitch,src,%srcObject,#volume",
+	":svg:^[HTMLElement]|!autofocus,nonce,*abort,*animationend,*animationiteration,*animationstart,*auxclick,*beforexrselect,*blur,*cancel,*canplay,*canplaythrough,*change,*click,*close,*contextmenu,*copy,*cuechange,*cut,*dblclick,*drag,*dragend,*dragenter,*dragleave,*dragover,*dragstart,*drop,*durationchange,*emptied,*ended,*error,*focus,*formdata,*gotpointercapture,*input,*invalid,*keydown,*keypress,*keyup,*load,*loadeddata,*loadedmetadata,*loadstart,*lostpointercapture,*mousedown,*mouseenter,*mouseleave,*mousemove,*mouseout,*mouseover,*mouseup,*mousewheel,*paste,*pause,*play,*playing,*pointercancel,*pointerdown,*pointerenter,*pointerleave,*pointermove,*pointerout,*pointerover,*pointerrawupdate,*pointerup,*progress,*ratechange,*reset,*resize,*scroll,*securitypolicyviolation,*seeked,*seeking,*select,*selectionchange,*selectstart,*slotchange,*stalled,*submit,*suspend,*timeupdate,*toggle,*transitioncancel,*transitionend,*transitionrun,*transitionstart,*volumechange,*waiting,*webkitanimationend,*webkitanimationiteration,*webkitanimationstart,*webkittransitionend,*wheel,%style,#tabIndex",
+	":svg:graphics^:svg:|",
+	":svg:animation^:svg:|*begin,*end,*repeat",
+	":svg:geometry^:svg:|",
+	":svg:componentTransferFunction^:svg:|",
+	":svg:gradient^:svg:|",
+	":svg:textContent^:svg:graphics|",
+	":svg:textPositioning^:svg:textContent|",
+	"a^[HTMLElement]|charset,coords,download,hash,host,hostname,href,hreflang,name,password,pathname,ping,port,protocol,referrerPolicy,rel,%relList,rev,search,shape,target,text,type,username",
+	"area^[HTMLElement]|alt,coords,download,hash,host,hostname,href,!noHref,password,pathname,ping,port,protocol,referrerPolicy,rel,%relList,search,shape,target,username",
+	"audio^media|",
+	"br^[HTMLElement]|clear",
+	"base^[HTMLElement]|href,target",
+	"body^[HTMLElement]|aLink,background,bgColor,link,*afterprint,*beforeprint,*beforeunload,*blur,*error,*focus,*hashchange,*languagechange,*load,*message,*messageerror,*offline,*online,*pagehide,*pageshow,*popstate,*rejectionhandled,*resize,*scroll,*storage,*unhandledrejection,*unload,text,vLink",
+	"button^[HTMLElement]|!disabled,formAction,formEnctype,formMethod,!formNoValidate,formTarget,name,type,value",
+	"canvas^[HTMLElement]|#height,#width",
+	"content^[HTMLElement]|select",
+	"dl^[HTMLElement]|!compact",
+	"data^[HTMLElement]|value",
+	"datalist^[HTMLElement]|",
+	"details^[HTMLElement]|!open",
+	"dialog^[HTMLElement]|!open,returnValue",
+	"dir^[HTMLElement]|!compact",
+	"div^[HTMLElement]|align",
+	"embed^[HTMLElement]|align,height,name,src,type,width",
+	"fieldset^[HTMLElement]|!disabled,name",
+	"font^[HTMLElement]|color,face,size",
+	"form^[HTMLElement]|acceptCharset,action,autocomplete,encoding,enctype,method,name,!noValidate,target",
+	"frame^[HTMLElement]|frameBorder,longDesc,marginHeight,marginWidth,name,!noResize,scrolling,src",
+	"frameset^[HTMLElement]|cols,*afterprint,*beforeprint,*beforeunload,*blur,*error,*focus,*hashchange,*languagechange,*load,*message,*messageerror,*offline,*online,*pagehide,*pageshow,*popstate,*rejectionhandled,*resize,*scroll,*storage,*unhandledrejection,*unload,rows",
+	"hr^[HTMLElement]|align,color,!noShade,size,width",
+	"head^[HTMLElement]|",
+	"h1,h2,h3,h4,h5,h6^[HTMLElement]|align",
+	"html^[HTMLElement]|version",
+	"iframe^[HTMLElement]|align,allow,!allowFullscreen,!allowPaymentRequest,csp,frameBorder,height,loading,longDesc,marginHeight,marginWidth,name,referrerPolicy,%sandbox,scrolling,src,srcdoc,width",
+	"img^[HTMLElement]|align,alt,border,%crossOrigin,decoding,#height,#hspace,!isMap,loading,longDesc,lowsrc,name,referrerPolicy,sizes,src,srcset,useMap,#vspace,#width",
+	"input^[HTMLElement]|accept,align,alt,autocomplete,!checked,!defaultChecked,defaultValue,dirName,!disabled,%files,formAction,formEnctype,formMethod,!formNoValidate,formTarget,#height,!incremental,!indeterminate,max,#maxLength,min,#minLength,!multiple,name,pattern,placeholder,!readOnly,!required,selectionDirection,#selectionEnd,#selectionStart,#size,src,step,type,useMap,value,%valueAsDate,#valueAsNumber,#width",
+	"li^[HTMLElement]|type,#value",
+	"label^[HTMLElement]|htmlFor",
+	"legend^[HTMLElement]|align",
+	"link^[HTMLElement]|as,charset,%crossOrigin,!disabled,href,hreflang,imageSizes,imageSrcset,integrity,media,referrerPolicy,rel,%relList,rev,%sizes,target,type",
+	"map^[HTMLElement]|name",
+	"marquee^[HTMLElement]|behavior,bgColor,direction,height,#hspace,#loop,#scrollAmount,#scrollDelay,!trueSpeed,#vspace,width",
+	"menu^[HTMLElement]|!compact",
+	"meta^[HTMLElement]|content,httpEquiv,media,name,scheme",
+	"meter^[HTMLElement]|#high,#low,#max,#min,#optimum,#value",
+	"ins,del^[HTMLElement]|cite,dateTime",
+	"ol^[HTMLElement]|!compact,!reversed,#start,type",
+	"object^[HTMLElement]|align,archive,border,code,codeBase,codeType,data,!declare,height,#hspace,name,standby,type,useMap,#vspace,width",
+	"optgroup^[HTMLElement]|!disabled,label",
+	"option^[HTMLElement]|!defaultSelected,!disabled,label,!selected,text,value",
+	"output^[HTMLElement]|defaultValue,%htmlFor,name,value",
+	"p^[HTMLElement]|align",
+	"param^[HTMLElement]|name,type,value,valueType",
+	"picture^[HTMLElement]|",
+	"pre^[HTMLElement]|#width",
+	"progress^[HTMLElement]|#max,#value",
+	"q,blockquote,cite^[HTMLElement]|",
+	"script^[HTMLElement]|!async,charset,%crossOrigin,!defer,event,htmlFor,integrity,!noModule,%referrerPolicy,src,text,type",
+	"select^[HTMLElement]|autocomplete,!disabled,#length,!multiple,name,!required,#selectedIndex,#size,value",
+	"slot^[HTMLElement]|name",
+	"source^[HTMLElement]|#height,media,sizes,src,srcset,type,#width",
+	"span^[HTMLElement]|",
+	"style^[HTMLElement]|!disabled,media,type",
+	"caption^[HTMLElement]|align",
+	"th,td^[HTMLElement]|abbr,align,axis,bgColor,ch,chOff,#colSpan,headers,height,!noWrap,#rowSpan,scope,vAlign,width",
+	"col,colgroup^[HTMLElement]|align,ch,chOff,#span,vAlign,width",
+	"table^[HTMLElement]|align,bgColor,border,%caption,cellPadding,cellSpacing,frame,rules,summary,%tFoot,%tHead,width",
+	"tr^[HTMLElement]|align,bgColor,ch,chOff,vAlign",
+	"tfoot,thead,tbody^[HTMLElement]|align,ch,chOff,vAlign",
+	"template^[HTMLElement]|",
+	"textarea^[HTMLElement]|autocomplete,#cols,defaultValue,dirName,!disabled,#maxLength,#minLength,name,placeholder,!readOnly,!required,#rows,selectionDirection,#selectionEnd,#selectionStart,value,wrap",
+	"time^[HTMLElement]|dateTime",
+	"title^[HTMLElement]|text",
+	"track^[HTMLElement]|!default,kind,label,src,srclang",
+	"ul^[HTMLElement]|!compact,type",
+	"unknown^[HTMLElement]|",
+	"video^media|!disablePictureInPicture,#height,*enterpictureinpicture,*leavepictureinpicture,!playsInline,poster,#width",
+	":svg:a^:svg:graphics|",
+	":svg:animate^:svg:animation|",
+	":svg:animateMotion^:svg:animation|",
+	":svg:animateTransform^:svg:animation|",
+	":svg:circle^:svg:geometry|",
+	":svg:clipPath^:svg:graphics|",
+	":svg:defs^:svg:graphics|",
+	":svg:desc^:svg:|",
+	":svg:discard^:svg:|",
+	":svg:ellipse^:svg:geometry|",
+	":svg:feBlend^:svg:|",
+	":svg:feColorMatrix^:svg:|",
+	":svg:feComponentTransfer^:svg:|",
+	":svg:feComposite^:svg:|",
+	":svg:feConvolveMatrix^:svg:|",
+	":svg:feDiffuseLighting^:svg:|",
+	":svg:feDisplacementMap^:svg:|",
+	":svg:feDistantLight^:svg:|",
+	":svg:feDropShadow^:svg:|",
+	":svg:feFlood^:svg:|",
+	":svg:feFuncA^:svg:componentTransferFunction|",
+	":svg:feFuncB^:svg:componentTransferFunction|",
+	":svg:feFuncG^:svg:componentTransferFunction|",
+	":svg:feFuncR^:svg:componentTransferFunction|",
+	":svg:feGaussianBlur^:svg:|",
+	":svg:feImage^:svg:|",
+	":svg:feMerge^:svg:|",
+	":svg:feMergeNode^:svg:|",
+	":svg:feMorphology^:svg:|",
+	":svg:feOffset^:svg:|",
+	":svg:fePointLight^:svg:|",
+	":svg:feSpecularLighting^:svg:|",
+	":svg:feSpotLight^:svg:|",
+	":svg:feTile^:svg:|",
+	":svg:feTurbulence^:svg:|",
+	":svg:filter^:svg:|",
+	":svg:foreignObject^:svg:graphics|",
+	":svg:g^:svg:graphics|",
+	":svg:image^:svg:graphics|decoding",
+	":svg:line^:svg:geometry|",
+	":svg:linearGradient^:svg:gradient|",
+	":svg:mpath^:svg:|",
+	":svg:marker^:svg:|",
+	":svg:mask^:svg:|",
+	":svg:metadata^:svg:|",
+	":svg:path^:svg:geometry|",
+	":svg:pattern^:svg:|",
+	":svg:polygon^:svg:geometry|",
+	":svg:polyline^:svg:geometry|",
+	":svg:radialGradient^:svg:gradient|",
+	":svg:rect^:svg:geometry|",
+	":svg:svg^:svg:graphics|#currentScale,#zoomAndPan",
+	":svg:script^:svg:|type",
+	":svg:set^:svg:animation|",
+	":svg:stop^:svg:|",
+	":svg:style^:svg:|!disabled,media,title,type",
+	":svg:switch^:svg:graphics|",
+	":svg:symbol^:svg:|",
+	":svg:tspan^:svg:textPositioning|",
+	":svg:text^:svg:textPositioning|",
+	":svg:textPath^:svg:textContent|",
+	":svg:title^:svg:|",
+	":svg:use^:svg:graphics|",
+	":svg:view^:svg:|#zoomAndPan",
+	"data^[HTMLElement]|value",
+	"keygen^[HTMLElement]|!autofocus,challenge,!disabled,form,keytype,name",
+	"menuitem^[HTMLElement]|type,label,icon,!disabled,!checked,radiogroup,!default",
+	"summary^[HTMLElement]|",
+	"time^[HTMLElement]|dateTime",
+	":svg:cursor^:svg:|",
+	":math:^[HTMLElement]|!autofocus,nonce,*abort,*animationend,*animationiteration,*animationstart,*auxclick,*beforeinput,*beforematch,*beforetoggle,*beforexrselect,*blur,*cancel,*canplay,*canplaythrough,*change,*click,*close,*contentvisibilityautostatechange,*contextlost,*contextmenu,*contextrestored,*copy,*cuechange,*cut,*dblclick,*drag,*dragend,*dragenter,*dragleave,*dragover,*dragstart,*drop,*durationchange,*emptied,*ended,*error,*focus,*formdata,*gotpointercapture,*input,*invalid,*keydown,*keypress,*keyup,*load,*loadeddata,*loadedmetadata,*loadstart,*lostpointercapture,*mousedown,*mouseenter,*mouseleave,*mousemove,*mouseout,*mouseover,*mouseup,*mousewheel,*paste,*pause,*play,*playing,*pointercancel,*pointerdown,*pointerenter,*pointerleave,*pointermove,*pointerout,*pointerover,*pointerrawupdate,*pointerup,*progress,*ratechange,*reset,*resize,*scroll,*scrollend,*securitypolicyviolation,*seeked,*seeking,*select,*selectionchange,*selectstart,*slotchange,*stalled,*submit,*suspend,*timeupdate,*toggle,*transitioncancel,*transitionend,*transitionrun,*transitionstart,*volumechange,*waiting,*webkitanimationend,*webkitanimationiteration,*webkitanimationstart,*webkittransitionend,*wheel,%style,#tabIndex",
+	":math:math^:math:|",
+	":math:maction^:math:|",
+	":math:menclose^:math:|",
+	":math:merror^:math:|",
+	":math:mfenced^:math:|",
+	":math:mfrac^:math:|",
+	":math:mi^:math:|",
+	":math:mmultiscripts^:math:|",
+	":math:mn^:math:|",
+	":math:mo^:math:|",
+	":math:mover^:math:|",
+	":math:mpadded^:math:|",
+	":math:mphantom^:math:|",
+	":math:mroot^:math:|",
+	":math:mrow^:math:|",
+	":math:ms^:math:|",
+	":math:mspace^:math:|",
+	":math:msqrt^:math:|",
+	":math:mstyle^:math:|",
+	":math:msub^:math:|",
+	":math:msubsup^:math:|",
+	":math:msup^:math:|",
+	":math:mtable^:math:|",
+	":math:mtd^:math:|",
+	":math:mtext^:math:|",
+	":math:mtr^:math:|",
+	":math:munder^:math:|",
+	":math:munderover^:math:|",
+	":math:semantics^:math:|",
+}
+
+var attrToProp = map[string]string{
+	"class":      "className",
+	"for":        "htmlFor",
+	"formaction": "formAction",
+	"innerHtml":  "innerHTML",
+	"readonly":   "readOnly",
+	"tabindex":   "tabIndex",
+}
+
+type DomElementSchemaRegistry struct {
+	schema      map[string]map[string]PropertyType
+	eventSchema map[string]map[string]bool
+}
+
+func NewDomElementSchemaRegistry() *DomElementSchemaRegistry {
+	r := &DomElementSchemaRegistry{
+		schema:      map[string]map[string]PropertyType{},
+		eventSchema: map[string]map[string]bool{},
+	}
+
+	for _, encodedType := range domSchema {
+		typeProperties := map[string]PropertyType{}
+		events := map[string]bool{}
+
+		parts := strings.SplitN(encodedType, "|", 2)
+		strType, strProperties := parts[0], parts[1]
+
+		typeParts := strings.SplitN(strType, "^", 2)
+		typeNames := typeParts[0]
+
+		for _, tag := range strings.Split(typeNames, ",") {
+			r.schema[strings.ToLower(tag)] = typeProperties
+			r.eventSchema[strings.ToLower(tag)] = events
+		}
+
+		if len(typeParts) > 1 {
+			superName := strings.ToLower(typeParts[1])
+
+			if superType, ok := r.schema[superName]; ok {
+				for prop, value := range superType {
+					typeProperties[prop] = value
+				}
+				for superEvent := range r.eventSchema[superName] {
+					events[superEvent] = true
+				}
+			}
+		}
+
+		for _, property := range strings.Split(strProperties, ",") {
+			if len(property) == 0 {
+				continue
+			}
+
+			switch property[0] {
+			case '*':
+				events[property[1:]] = true
+			case '!':
+				typeProperties[property[1:]] = PropertyTypeBoolean
+			case '#':
+				typeProperties[property[1:]] = PropertyTypeNumber
+			case '%':
+				typeProperties[property[1:]] = PropertyTypeObject
+			default:
+				typeProperties[property] = PropertyTypeString
+			}
+		}
+	}
+
+	return r
+}
+
+func hasSchema(schemaMetas []SchemaMetadata, schema SchemaMetadata) bool {
+	for _, s := range schemaMetas {
+		if s.Name == schema.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasElement reports whether tagName is a known DOM element. Element names
+// are expected in the `:svg:rect` form for namespaced elements.
+func (r *DomElementSchemaRegistry) HasElement(tagName string, schemaMetas []SchemaMetadata) bool {
+	if hasSchema(schemaMetas, NoErrorsSchema) {
+		return true
+	}
+
+	if strings.Contains(tagName, "-") {
+		if IsNgContainer(tagName) || IsNgContent(tagName) {
+			return true
+		}
+
+		if hasSchema(schemaMetas, CustomElementsSchema) {
+			// Can't tell now as we don't know which properties a custom element will get
+			// once it is instantiated
+			return true
+		}
+	}
+
+	_, ok := r.schema[strings.ToLower(tagName)]
+	return ok
+}
+
+// HasProperty reports whether propName is a known DOM property of tagName.
+// Unknown elements are checked against the `unknown` element.
+func (r *DomElementSchemaRegistry) HasProperty(tagName string, propName string, schemaMetas []SchemaMetadata) bool {
+	if hasSchema(schemaMetas, NoErrorsSchema) {
+		return true
+	}
+
+	if strings.Contains(tagName, "-") {
+		if IsNgContainer(tagName) || IsNgContent(tagName) {
+			return true
+		}
+
+		if hasSchema(schemaMetas, CustomElementsSchema) {
+			// Can't tell now as we don't know which properties a custom element will get
+			// once it is instantiated
+			return true
+		}
+	}
+
+	elementProperties, ok := r.schema[strings.ToLower(tagName)]
+	if !ok {
+		elementProperties = r.schema["unknown"]
+	}
+
+	_, ok = elementProperties[propName]
+	return ok
+}
+
+// GetMappedPropName returns the DOM property name for an attribute name,
+// e.g. `class` -> `className`.
+func (r *DomElementSchemaRegistry) GetMappedPropName(propName string) string {
+	if mapped, ok := attrToProp[propName]; ok {
+		return mapped
+	}
+	return propName
+}
+
+func (r *DomElementSchemaRegistry) GetPropertyType(tagName string, propName string) (PropertyType, bool) {
+	elementProperties, ok := r.schema[strings.ToLower(tagName)]
+	if !ok {
+		elementProperties = r.schema["unknown"]
+	}
+
+	propertyType, ok := elementProperties[propName]
+	return propertyType, ok
+}
+
+func (r *DomElementSchemaRegistry) AllKnownElementNames() []string {
+	var names []string
+	for name := range r.schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *DomElementSchemaRegistry) AllKnownEventsOfElement(tagName string) []string {
+	elementEvents, ok := r.eventSchema[strings.ToLower(tagName)]
+	if !ok {
+		elementEvents = r.eventSchema["unknown"]
+	}
+
+	var events []string
+	for event := range elementEvents {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	return events
+}
+
+// ValidateProperty returns an error message for property bindings that
+// target event handler properties such as `[onclick]`.
+func (r *DomElementSchemaRegistry) ValidateProperty(name string) (string, bool) {
+	if strings.HasPrefix(strings.ToLower(name), "on") {
+		msg := `Binding to event property '` + name + `' is disallowed for security reasons, ` +
+			`please use (` + name[2:] + `)=...` +
+			"\nIf '" + name + "' is a directive input, make sure the directive is imported by the" +
+			` current module.`
+		return msg, true
+	}
+
+	return "", false
+}
+
+// ValidateAttribute returns an error message for attribute bindings that
+// target event handler attributes such as `[attr.onclick]`.
+func (r *DomElementSchemaRegistry) ValidateAttribute(name string) (string, bool) {
+	if strings.HasPrefix(strings.ToLower(name), "on") {
+		msg := `Binding to event attribute '` + name + `' is disallowed for security reasons, ` +
+			`please use (` + name[2:] + `)=...`
+		return msg, true
+	}
+
+	return "", false
+}
+
+func IsNgContainer(tagName string) bool {
+	return splitNsName(tagName) == "ng-container"
+}
+
+func IsNgContent(tagName string) bool {
+	return splitNsName(tagName) == "ng-content"
+}
+
+func IsNgTemplate(tagName string) bool {
+	return splitNsName(tagName) == "ng-template"
+}
+
+// splitNsName returns the local part of a `:ns:name` element name.
+func splitNsName(elementName string) string {
+	if elementName == "" || elementName[0] != ':' {
+		return elementName
+	}
+
+	colonIndex := strings.Index(elementName[1:], ":")
+	if colonIndex == -1 {
+		return elementName
+	}
+
+	return elementName[colonIndex+2:]
+}
@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"strings"
+	"sync"
+)
+
+// https://github.com/angular/angular/blob/master/packages/compiler/src/schema/dom_security_schema.ts
+
+type SecurityContext int
+
+const (
+	SecurityContextNone SecurityContext = iota
+	SecurityContextHTML
+	SecurityContextStyle
+	SecurityContextScript
+	SecurityContextURL
+	SecurityContextResourceURL
+)
+
+func (c SecurityContext) String() string {
+	switch c {
+	case SecurityContextHTML:
+		return "HTML"
+	case SecurityContextStyle:
+		return "STYLE"
+	case SecurityContextScript:
+		return "SCRIPT"
+	case SecurityContextURL:
+		return "URL"
+	case SecurityContextResourceURL:
+		return "RESOURCE_URL"
+	default:
+		return "NONE"
+	}
+}
+
+// =================================================================================================
+// =================================================================================================
+// =========== S T O P   -  S T O P   -  S T O P   -  S T O P   -  S T O P   -  S T O P  ===========
+// =================================================================================================
+// =================================================================================================
+//
+//        DO NOT EDIT THIS LIST OF SECURITY SENSITIVE PROPERTIES WITHOUT A SECURITY REVIEW!
+//
+// =================================================================================================
+
+var (
+	securitySchema     map[string]SecurityContext
+	securitySchemaOnce sync.Once
+)
+
+func SecuritySchema() map[string]SecurityContext {
+	securitySchemaOnce.Do(func() {
+		securitySchema = map[string]SecurityContext{}
+
+		// Case is insignificant below, all element and attribute names are lower-cased for lookup.
+
+		registerContext(SecurityContextHTML, []string{
+			"iframe|srcdoc",
+			"*|innerHTML",
+			"*|outerHTML",
+		})
+		registerContext(SecurityContextStyle, []string{"*|style"})
+		// NB: no SCRIPT contexts here, they are never allowed due to the parser stripping them.
+		registerContext(SecurityContextURL, []string{
+			"*|formAction",
+			"area|href", "area|ping",
+			"audio|src",
+			"a|href", "a|ping",
+			"blockquote|cite",
+			"body|background",
+			"del|cite",
+			"feImage|href",
+			"form|action",
+			"image|href",
+			"img|src",
+			"input|src",
+			"ins|cite",
+			"q|cite",
+			"source|src",
+			"track|src",
+			"use|href",
+			"video|poster", "video|src",
+		})
+		registerContext(SecurityContextResourceURL, []string{
+			"applet|code",
+			"applet|codebase",
+			"base|href",
+			"embed|src",
+			"frame|src",
+			"head|profile",
+			"html|manifest",
+			"iframe|src",
+			"link|href",
+			"media|src",
+			"object|codebase",
+			"object|data",
+			"script|src",
+		})
+	})
+
+	return securitySchema
+}
+
+func registerContext(ctx SecurityContext, specs []string) {
+	for _, spec := range specs {
+		securitySchema[strings.ToLower(spec)] = ctx
+	}
+}
+
+// SecurityContext returns the security context of a property or attribute
+// binding, e.g. URL for `[href]` on `a` or RESOURCE_URL for `[src]` on `iframe`.
+func (r *DomElementSchemaRegistry) SecurityContext(tagName string, propName string, isAttribute bool) SecurityContext {
+	if isAttribute {
+		// NB: For security purposes, use the mapped property name, not the attribute name.
+		propName = r.GetMappedPropName(propName)
+	}
+
+	// Make sure comparisons are case insensitive, so that case differences between attribute and
+	// property names do not have a security impact.
+	tagName = strings.ToLower(tagName)
+	propName = strings.ToLower(propName)
+
+	// SVG and MathML elements are looked up without their namespace, e.g.
+	// `:svg:a` like `a`, and `xlink:href` like `href`.
+	if strings.HasPrefix(tagName, ":") {
+		tagName = tagName[strings.LastIndex(tagName, ":")+1:]
+	}
+	propName = strings.TrimPrefix(strings.TrimPrefix(propName, ":"), "xlink:")
+
+	if ctx, ok := SecuritySchema()[tagName+"|"+propName]; ok {
+		return ctx
+	}
+
+	if ctx, ok := SecuritySchema()["*|"+propName]; ok {
+		return ctx
+	}
+
+	return SecurityContextNone
+}
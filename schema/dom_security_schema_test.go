@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSecurityContext(t *testing.T) {
+	tests := []struct {
+		tagName     string
+		propName    string
+		isAttribute bool
+		want        SecurityContext
+	}{
+		{"a", "href", false, SecurityContextURL},
+		{"A", "HREF", false, SecurityContextURL},
+		{"a", "href", true, SecurityContextURL},
+		{"img", "src", false, SecurityContextURL},
+		{"div", "innerHTML", false, SecurityContextHTML},
+		{"div", "innerHtml", true, SecurityContextHTML},
+		{"iframe", "srcdoc", false, SecurityContextHTML},
+		{"div", "style", false, SecurityContextStyle},
+		{"iframe", "src", false, SecurityContextResourceURL},
+		{"script", "src", true, SecurityContextResourceURL},
+		{"button", "formAction", false, SecurityContextURL},
+		{":svg:a", "href", true, SecurityContextURL},
+		{":svg:a", "xlink:href", true, SecurityContextURL},
+		{"a", "xlink:href", true, SecurityContextURL},
+		{":svg:image", "href", true, SecurityContextURL},
+		{":svg:image", ":xlink:href", true, SecurityContextURL},
+		{":svg:use", "href", true, SecurityContextURL},
+		{"div", "title", false, SecurityContextNone},
+		{"img", "alt", true, SecurityContextNone},
+		{":svg:circle", "href", true, SecurityContextNone},
+	}
+
+	registry := NewDomElementSchemaRegistry()
+	for _, test := range tests {
+		if got := registry.SecurityContext(test.tagName, test.propName, test.isAttribute); got != test.want {
+			t.Errorf("SecurityContext(%q, %q, %v) = %s, want %s", test.tagName, test.propName, test.isAttribute, got, test.want)
+		}
+	}
+}
+
+// TestSecuritySchemaConcurrently is meant for `go test -race`.
+func TestSecuritySchemaConcurrently(t *testing.T) {
+	registry := NewDomElementSchemaRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := registry.SecurityContext("a", "href", false); got != SecurityContextURL {
+				t.Errorf("SecurityContext(a, href) = %s, want URL", got)
+			}
+		}()
+	}
+	wg.Wait()
+}
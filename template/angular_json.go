@@ -0,0 +1,1029 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/irustm/ng-template-parser/ep"
+	"github.com/irustm/ng-template-parser/schema"
+)
+
+// https://github.com/angular/angular/blob/master/packages/compiler/src/render3/r3_template_transform.ts
+
+// MarshalAngularJSON encodes a template like JSON.stringify encodes the
+// result of parseTemplate of @angular/compiler with the default options: the
+// same node and field names, spans as ParseSourceSpans with UTF-16 offsets and
+// 0-based lines and columns, whitespace only text removed and whitespace
+// collapsed, structural directives and <ng-template> as Template nodes,
+// <ng-content> as Content nodes, and the <style> and stylesheet <link>
+// elements moved to styles and styleUrls. Control flow blocks use the nodes
+// of Angular 17. url is the templateUrl, e.g. "./app.component.html".
+func MarshalAngularJSON(root Root, url string, registry *schema.DomElementSchemaRegistry) ([]byte, error) {
+	c := &angularConverter{
+		source:   newAngularSource(root.Source, url),
+		registry: registry,
+		parser:   ep.NewParser(),
+	}
+
+	nodes := c.nodes(root.Nodes, "", false)
+
+	var errors interface{}
+	if len(c.errors) > 0 {
+		errors = c.errors
+	}
+
+	return json.Marshal(jsonObject{
+		{"errors", errors},
+		{"nodes", nodes},
+		{"styleUrls", append([]string{}, c.styleUrls...)},
+		{"styles", append([]string{}, c.styles...)},
+		{"ngContentSelectors", append([]string{}, c.ngContentSelectors...)},
+	})
+}
+
+// jsonObject is a JSON object with its keys in order, JavaScript objects are
+// stringified in the order their properties were assigned.
+type jsonObject []jsonField
+
+type jsonField struct {
+	Key   string
+	Value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.Key)
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// angularSource converts byte offsets into the ParseLocations of Angular,
+// which count UTF-16 code units.
+type angularSource struct {
+	file jsonObject
+	// utf16 is the UTF-16 offset of every byte offset.
+	utf16 []int
+	// lines are the byte offsets of the line starts.
+	lines []int
+	url   string
+}
+
+func newAngularSource(source string, url string) *angularSource {
+	s := &angularSource{
+		file:  jsonObject{{"content", source}, {"url", url}},
+		utf16: utf16Offsets(source),
+		lines: []int{0},
+		url:   url,
+	}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			s.lines = append(s.lines, i+1)
+		}
+	}
+	return s
+}
+
+// utf16Offsets returns the UTF-16 offset of every byte offset of text, the
+// bytes within a character have the offset of the character.
+func utf16Offsets(text string) []int {
+	offsets := make([]int, len(text)+1)
+	offset := 0
+	for i, c := range text {
+		size := utf8.RuneLen(c)
+		if size < 0 {
+			size = 1
+		}
+		for j := 0; j < size && i+j < len(text); j++ {
+			offsets[i+j] = offset
+		}
+		offset += len(utf16.Encode([]rune{c}))
+	}
+	offsets[len(text)] = offset
+	return offsets
+}
+
+func (s *angularSource) offset(offset int) int {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(s.utf16) {
+		offset = len(s.utf16) - 1
+	}
+	return s.utf16[offset]
+}
+
+// position returns the 0-based line and column of an offset.
+func (s *angularSource) position(offset int) (int, int) {
+	line := sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset }) - 1
+	if line < 0 {
+		line = 0
+	}
+	return line, s.offset(offset) - s.offset(s.lines[line])
+}
+
+func (s *angularSource) location(offset int) jsonObject {
+	line, col := s.position(offset)
+	return jsonObject{{"file", s.file}, {"offset", s.offset(offset)}, {"line", line}, {"col", col}}
+}
+
+// locationString is the `url@line:col` form of ParseLocation.toString, the
+// location of expressions.
+func (s *angularSource) locationString(offset int) string {
+	line, col := s.position(offset)
+	return s.url + "@" + strconv.Itoa(line) + ":" + strconv.Itoa(col)
+}
+
+// span returns a ParseSourceSpan, details is the key of a binding or nil.
+func (s *angularSource) span(span ep.AbsoluteSourceSpan, details interface{}) jsonObject {
+	return s.fullSpan(span, span.Start, details)
+}
+
+func (s *angularSource) fullSpan(span ep.AbsoluteSourceSpan, fullStart int, details interface{}) jsonObject {
+	return jsonObject{
+		{"start", s.location(span.Start)},
+		{"end", s.location(span.End)},
+		{"fullStart", s.location(fullStart)},
+		{"details", details},
+	}
+}
+
+func (s *angularSource) optionalSpan(span *ep.AbsoluteSourceSpan) interface{} {
+	if span == nil {
+		return nil
+	}
+	return s.span(*span, nil)
+}
+
+type angularConverter struct {
+	source   *angularSource
+	registry *schema.DomElementSchemaRegistry
+	parser   *ep.Parser
+
+	errors             []jsonObject
+	styles             []string
+	styleUrls          []string
+	ngContentSelectors []string
+}
+
+// angularWhitespace are the characters the WhitespaceVisitor of Angular
+// collapses, &ngsp; is never collapsed.
+const angularWhitespace = ` \f\n\r\t\v\x{1680}\x{180e}\x{2000}-\x{200a}\x{2028}\x{2029}\x{202f}\x{205f}\x{3000}\x{feff}`
+
+var (
+	angularWhitespaceRunRegexp = regexp.MustCompile(`[` + angularWhitespace + `]{2,}`)
+	angularNotBlankRegexp      = regexp.MustCompile(`[^` + angularWhitespace + `]`)
+)
+
+// preservesWhitespace reports whether whitespace is kept in an element.
+func preservesWhitespace(element *Element) bool {
+	switch strings.ToLower(element.Name) {
+	case "pre", "template", "textarea", "script", "style":
+		return true
+	}
+	for _, attr := range element.Attributes {
+		if attr.Name == "ngPreserveWhitespaces" {
+			return true
+		}
+	}
+	return false
+}
+
+// textStart skips the leading trivia of a text span, Angular starts text
+// spans after the leading whitespace and keeps the full start separately.
+func (c *angularConverter) textStart(span ep.AbsoluteSourceSpan) int {
+	start := span.Start
+	for start < span.End && start < len(c.source.utf16)-1 && strings.IndexByte(" \n\r\t", c.sourceByte(start)) != -1 {
+		start++
+	}
+	return start
+}
+
+func (c *angularConverter) sourceByte(offset int) byte {
+	return c.source.file[0].Value.(string)[offset]
+}
+
+func (c *angularConverter) nodes(nodes []Node, namespace string, preserve bool) []jsonObject {
+	converted := []jsonObject{}
+
+	for i := 0; i < len(nodes); i++ {
+		switch node := nodes[i].(type) {
+		case *Text:
+			if text := c.text(node.Value, node.SourceSpan, preserve); text != nil {
+				converted = append(converted, text)
+			}
+		case *BoundText:
+			if text := c.text(node.Value.Source, node.SourceSpan, preserve); text != nil {
+				converted = append(converted, text)
+			}
+		case *Element:
+			if element := c.element(node, namespace); element != nil {
+				converted = append(converted, element)
+			}
+		case *Template:
+			// Angular builds the templates from the element as written
+			if element := c.element(node.Element(), namespace); element != nil {
+				converted = append(converted, element)
+			}
+		case *Block:
+			var block jsonObject
+			block, i = c.block(nodes, i, namespace)
+			converted = append(converted, block)
+		}
+	}
+
+	return converted
+}
+
+// text returns a Text or a BoundText for the text with interpolations, or
+// nil for whitespace only text.
+func (c *angularConverter) text(value string, span ep.AbsoluteSourceSpan, preserve bool) jsonObject {
+	if !preserve {
+		if !angularNotBlankRegexp.MatchString(value) {
+			return nil
+		}
+		value = angularWhitespaceRunRegexp.ReplaceAllString(value, " ")
+	}
+
+	start := c.textStart(span)
+	sourceSpan := c.source.fullSpan(ep.AbsoluteSourceSpan{Start: start, End: span.End}, span.Start, nil)
+
+	// like Angular the processed text is parsed at the full start of the span
+	ast := c.parser.ParseInterpolation(value, c.source.locationString(start), span.Start)
+	if ast == nil {
+		return jsonObject{{"value", value}, {"sourceSpan", sourceSpan}}
+	}
+
+	return jsonObject{{"value", c.expression(ast, sourceSpan)}, {"sourceSpan", sourceSpan}}
+}
+
+func (c *angularConverter) element(element *Element, namespace string) jsonObject {
+	name := strings.ToLower(element.Name)
+	switch {
+	case name == "script":
+		return nil
+	case name == "style":
+		var style strings.Builder
+		for _, child := range element.Children {
+			if text, ok := child.(*Text); ok {
+				style.WriteString(text.Value)
+			}
+		}
+		c.styles = append(c.styles, style.String())
+		return nil
+	case name == "link" && isStylesheetLink(element):
+		for _, attr := range element.Attributes {
+			if strings.EqualFold(attr.Name, "href") {
+				c.styleUrls = append(c.styleUrls, attr.Value)
+			}
+		}
+		return nil
+	}
+
+	elementName, childNamespace := schemaElementName(element.Name, namespace)
+	if strings.EqualFold(element.Name, "foreignObject") {
+		childNamespace = ""
+	}
+
+	var templateAttr *TextAttribute
+	attributes := []jsonObject{}
+	var variables []jsonObject
+	isTemplate := schema.IsNgTemplate(name)
+
+	for i, attr := range element.Attributes {
+		switch {
+		case strings.HasPrefix(attr.Name, "*"):
+			templateAttr = &element.Attributes[i]
+		case attr.Name == "i18n" || strings.HasPrefix(attr.Name, "i18n-") || attr.Name == "ngPreserveWhitespaces":
+		case isTemplate && strings.HasPrefix(attr.Name, "let-"):
+			variables = append(variables, c.letVariable(attr))
+		default:
+			attributes = append(attributes, c.textAttribute(attr))
+		}
+	}
+
+	inputs := []jsonObject{}
+	for _, input := range element.Inputs {
+		inputs = append(inputs, c.boundAttribute(input, elementName))
+	}
+
+	outputs := []jsonObject{}
+	for _, output := range element.Outputs {
+		outputs = append(outputs, c.boundEvent(output))
+	}
+
+	references := []jsonObject{}
+	for _, ref := range element.References {
+		references = append(references, c.reference(ref))
+	}
+
+	startSpan := c.source.span(element.StartSourceSpan, nil)
+	endSpan := c.elementEndSpan(element)
+	sourceSpan := c.source.span(element.SourceSpan, nil)
+
+	var parsed jsonObject
+	switch {
+	case schema.IsNgContent(name):
+		selector := "*"
+		for _, attr := range element.Attributes {
+			if attr.Name == "select" && attr.Value != "" {
+				selector = attr.Value
+			}
+		}
+		c.ngContentSelectors = append(c.ngContentSelectors, selector)
+		parsed = jsonObject{{"selector", selector}, {"attributes", attributes}, {"sourceSpan", sourceSpan}, {"name", "ng-content"}}
+	case isTemplate:
+		if variables == nil {
+			variables = []jsonObject{}
+		}
+		parsed = jsonObject{
+			{"tagName", elementName},
+			{"attributes", attributes},
+			{"inputs", inputs},
+			{"outputs", outputs},
+			{"templateAttrs", []jsonObject{}},
+			{"children", c.nodes(element.Children, childNamespace, preservesWhitespace(element))},
+			{"references", references},
+			{"variables", variables},
+			{"sourceSpan", sourceSpan},
+			{"startSourceSpan", startSpan},
+			{"endSourceSpan", endSpan},
+		}
+	default:
+		parsed = jsonObject{
+			{"name", elementName},
+			{"attributes", attributes},
+			{"inputs", inputs},
+			{"outputs", outputs},
+			{"children", c.nodes(element.Children, childNamespace, preservesWhitespace(element))},
+			{"references", references},
+			{"sourceSpan", sourceSpan},
+			{"startSourceSpan", startSpan},
+			{"endSourceSpan", endSpan},
+		}
+	}
+
+	if templateAttr == nil {
+		return parsed
+	}
+
+	// the element of a structural directive is the child of a Template with
+	// the attributes, inputs and outputs of the element
+	templateAttrs, templateVariables := c.templateBindings(*templateAttr)
+	hoisted := []interface{}{attributes, inputs, outputs}
+	if schema.IsNgContent(name) {
+		hoisted = []interface{}{[]jsonObject{}, []jsonObject{}, []jsonObject{}}
+	}
+
+	return jsonObject{
+		{"tagName", elementName},
+		{"attributes", hoisted[0]},
+		{"inputs", hoisted[1]},
+		{"outputs", hoisted[2]},
+		{"templateAttrs", templateAttrs},
+		{"children", []jsonObject{parsed}},
+		{"references", []jsonObject{}},
+		{"variables", templateVariables},
+		{"sourceSpan", sourceSpan},
+		{"startSourceSpan", startSpan},
+		{"endSourceSpan", endSpan},
+	}
+}
+
+func isStylesheetLink(element *Element) bool {
+	rel, href := "", ""
+	for _, attr := range element.Attributes {
+		switch strings.ToLower(attr.Name) {
+		case "rel":
+			rel = attr.Value
+		case "href":
+			href = attr.Value
+		}
+	}
+	return rel == "stylesheet" && href != ""
+}
+
+// elementEndSpan returns the end tag span, which is the start tag for void
+// and self-closing elements.
+func (c *angularConverter) elementEndSpan(element *Element) interface{} {
+	if element.EndSourceSpan != nil {
+		return c.source.span(*element.EndSourceSpan, nil)
+	}
+	if element.SelfClosing || isVoidElement(strings.ToLower(element.Name)) {
+		return c.source.span(element.StartSourceSpan, nil)
+	}
+	return nil
+}
+
+// keySpan returns the span of the name of a binding without its prefix and
+// suffix, e.g. `click` of `(click)`, with the name as details.
+func (c *angularConverter) keySpan(span ep.AbsoluteSourceSpan, prefix int, suffix int) jsonObject {
+	key := ep.AbsoluteSourceSpan{Start: span.Start + prefix, End: span.End - suffix}
+	if key.End < key.Start {
+		key.End = key.Start
+	}
+	return c.source.span(key, c.source.file[0].Value.(string)[key.Start:key.End])
+}
+
+func (c *angularConverter) textAttribute(attr TextAttribute) jsonObject {
+	object := jsonObject{
+		{"name", attr.Name},
+		{"value", attr.Value},
+		{"sourceSpan", c.source.span(attr.SourceSpan, nil)},
+		{"keySpan", c.source.span(attr.KeySpan, nil)},
+	}
+	if attr.ValueSpan != nil {
+		object = append(object, jsonField{"valueSpan", c.source.span(*attr.ValueSpan, nil)})
+	}
+	return object
+}
+
+func (c *angularConverter) reference(ref Reference) jsonObject {
+	object := jsonObject{
+		{"name", ref.Name},
+		{"value", ref.Value},
+		{"sourceSpan", c.source.span(ref.SourceSpan, nil)},
+		{"keySpan", c.keySpan(ref.KeySpan, 1, 0)},
+	}
+	if ref.ValueSpan != nil {
+		object = append(object, jsonField{"valueSpan", c.source.span(*ref.ValueSpan, nil)})
+	}
+	return object
+}
+
+// letVariable returns the Variable of a `let-item="value"` attribute of an
+// <ng-template>.
+func (c *angularConverter) letVariable(attr TextAttribute) jsonObject {
+	value := attr.Value
+	if value == "" {
+		value = "$implicit"
+	}
+	object := jsonObject{
+		{"name", attr.Name[4:]},
+		{"value", value},
+		{"sourceSpan", c.source.span(attr.SourceSpan, nil)},
+		{"keySpan", c.keySpan(attr.KeySpan, 4, 0)},
+	}
+	if attr.ValueSpan != nil {
+		object = append(object, jsonField{"valueSpan", c.source.span(*attr.ValueSpan, nil)})
+	}
+	return object
+}
+
+func (c *angularConverter) variable(name string, value string, span ep.AbsoluteSourceSpan, keySpan ep.AbsoluteSourceSpan, valueSpan *ep.AbsoluteSourceSpan) jsonObject {
+	object := jsonObject{
+		{"name", name},
+		{"value", value},
+		{"sourceSpan", c.source.span(span, nil)},
+		{"keySpan", c.source.span(keySpan, nil)},
+	}
+	if valueSpan != nil {
+		object = append(object, jsonField{"valueSpan", c.source.span(*valueSpan, nil)})
+	}
+	return object
+}
+
+// angularBindingTypes are the BindingType numbers of Angular 13, which had
+// no separate type for two-way bindings.
+var angularBindingTypes = map[BindingType]int{
+	BindingTypeProperty:  0,
+	BindingTypeAttribute: 1,
+	BindingTypeClass:     2,
+	BindingTypeStyle:     3,
+	BindingTypeAnimation: 4,
+	BindingTypeTwoWay:    0,
+}
+
+func (c *angularConverter) boundAttribute(input BoundAttribute, elementName string) jsonObject {
+	name := input.Name
+	bindingType := angularBindingTypes[input.BindingType]
+	var unit interface{}
+
+	switch input.BindingType {
+	case BindingTypeStyle:
+		if dot := strings.IndexByte(name, '.'); dot != -1 {
+			name, unit = name[:dot], name[dot+1:]
+		}
+	case BindingTypeProperty:
+		if strings.HasPrefix(name, "@") {
+			name = name[1:]
+			bindingType = angularBindingTypes[BindingTypeAnimation]
+		}
+	}
+
+	prefix, suffix := 1, 1
+	if input.BindingType == BindingTypeTwoWay {
+		prefix, suffix = 2, 2
+	}
+
+	return jsonObject{
+		{"name", name},
+		{"type", bindingType},
+		{"securityContext", int(bindingSecurityContext(elementName, input, c.registry))},
+		{"value", c.bindingExpression(input.Value, input.ValueSpan, input.SourceSpan)},
+		{"unit", unit},
+		{"sourceSpan", c.source.span(input.SourceSpan, nil)},
+		{"keySpan", c.keySpan(input.KeySpan, prefix, suffix)},
+		{"valueSpan", c.source.optionalSpan(input.ValueSpan)},
+	}
+}
+
+func (c *angularConverter) boundEvent(output BoundEvent) jsonObject {
+	name := output.Name
+	eventType := 0
+	var target, phase interface{}
+
+	if output.BindingType == BindingTypeTwoWay {
+		// the key of [(value)] is value, the event is valueChange
+		return jsonObject{
+			{"name", name},
+			{"type", eventType},
+			{"handler", c.bindingExpression(output.Handler, output.ValueSpan, output.SourceSpan)},
+			{"target", target},
+			{"phase", phase},
+			{"sourceSpan", c.source.span(output.SourceSpan, nil)},
+			{"handlerSpan", c.source.optionalSpan(output.ValueSpan)},
+			{"keySpan", c.keySpan(output.KeySpan, 2, 2)},
+		}
+	}
+
+	if strings.HasPrefix(name, "@") {
+		// (@trigger.done)
+		eventType = 1
+		name = name[1:]
+		if dot := strings.IndexByte(name, '.'); dot != -1 {
+			name, phase = name[:dot], strings.ToLower(name[dot+1:])
+		}
+	} else if colon := strings.IndexByte(name, ':'); colon != -1 {
+		// (window:resize)
+		name, target = name[colon+1:], name[:colon]
+	}
+
+	return jsonObject{
+		{"name", name},
+		{"type", eventType},
+		{"handler", c.bindingExpression(output.Handler, output.ValueSpan, output.SourceSpan)},
+		{"target", target},
+		{"phase", phase},
+		{"sourceSpan", c.source.span(output.SourceSpan, nil)},
+		{"handlerSpan", c.source.optionalSpan(output.ValueSpan)},
+		{"keySpan", c.keySpan(output.KeySpan, 1, 1)},
+	}
+}
+
+// bindingExpression converts the expression of a binding, its location is
+// the start of the value like in Angular.
+func (c *angularConverter) bindingExpression(ast *ep.ASTWithSource, valueSpan *ep.AbsoluteSourceSpan, span ep.AbsoluteSourceSpan) jsonObject {
+	start := span.Start
+	if valueSpan != nil {
+		start = valueSpan.Start
+	}
+	withLocation := *ast
+	withLocation.Location = c.source.locationString(start)
+	return c.expression(&withLocation, c.source.span(span, nil))
+}
+
+// templateBindings returns the templateAttrs and variables of the Template of
+// a structural directive attribute like `*ngFor="let item of items"`.
+func (c *angularConverter) templateBindings(attr TextAttribute) ([]jsonObject, []jsonObject) {
+	directive := attr.Name[1:]
+	offset := attr.SourceSpan.Start
+	if attr.ValueSpan != nil {
+		offset = attr.ValueSpan.Start
+	}
+	bindings, _ := ParseTemplateBindings(directive, attr.Value, c.source.locationString(offset), offset, c.parser)
+	if len(bindings) == 0 || bindings[0].IsVariable() {
+		// Angular binds the directive key first, without a value before `let`
+		bindings = append([]TemplateBinding{{Key: directive}}, bindings...)
+	}
+
+	attrs := []jsonObject{}
+	variables := []jsonObject{}
+	for i, binding := range bindings {
+		keySpan := binding.KeySpan
+		if i == 0 {
+			// the directive key is the attribute name without the `*`
+			keySpan = ep.AbsoluteSourceSpan{Start: attr.KeySpan.Start + 1, End: attr.KeySpan.End}
+		}
+		span := binding.SourceSpan
+		if i == 0 || span.End <= span.Start {
+			span.Start = keySpan.Start
+			if span.End < keySpan.End {
+				span.End = keySpan.End
+			}
+		}
+
+		switch {
+		case binding.IsVariable():
+			variables = append(variables, c.variable(binding.Key, binding.Variable, binding.SourceSpan, binding.KeySpan, binding.ValueSpan))
+		case binding.Value == nil:
+			attrs = append(attrs, jsonObject{
+				{"name", binding.Key},
+				{"value", ""},
+				{"sourceSpan", c.source.span(span, nil)},
+				{"keySpan", c.source.span(keySpan, nil)},
+			})
+		default:
+			valueSpan := binding.Value.SourceSpan
+			attrs = append(attrs, jsonObject{
+				{"name", binding.Key},
+				{"type", 0},
+				{"securityContext", int(c.registry.SecurityContext("ng-template", binding.Key, false))},
+				{"value", c.expression(binding.Value, c.source.span(span, nil))},
+				{"unit", nil},
+				{"sourceSpan", c.source.span(span, nil)},
+				{"keySpan", c.source.span(keySpan, nil)},
+				{"valueSpan", c.source.span(valueSpan, nil)},
+			})
+		}
+	}
+
+	return attrs, variables
+}
+
+// block converts the block at index together with its connected blocks and
+// returns the index of the last one.
+func (c *angularConverter) block(nodes []Node, index int, namespace string) (jsonObject, int) {
+	chain, last := connectedBlocks(nodes, index)
+	block := chain[0]
+
+	base := func(block *Block, span ep.AbsoluteSourceSpan) jsonObject {
+		return jsonObject{
+			{"nameSpan", c.source.span(c.blockNameSpan(block), nil)},
+			{"sourceSpan", c.source.span(span, nil)},
+			{"startSourceSpan", c.source.span(block.StartSourceSpan, nil)},
+			{"endSourceSpan", c.source.optionalSpan(chain[len(chain)-1].EndSourceSpan)},
+		}
+	}
+	own := func(block *Block) jsonObject {
+		object := base(block, block.SourceSpan)
+		object[3].Value = c.source.optionalSpan(block.EndSourceSpan)
+		return object
+	}
+	chainSpan := ep.AbsoluteSourceSpan{Start: block.SourceSpan.Start, End: chain[len(chain)-1].SourceSpan.End}
+	children := func(block *Block) []jsonObject {
+		return c.nodes(block.Children, namespace, false)
+	}
+
+	switch block.Name {
+	case "if":
+		branches := []jsonObject{}
+		for _, branch := range chain {
+			var expression, alias interface{}
+			if len(branch.Parameters) > 0 && branch.Name != "else" {
+				expression = c.blockExpression(branch.Parameters[0], branch)
+				for _, parameter := range branch.Parameters[1:] {
+					trimmed := strings.TrimSpace(parameter.Expression)
+					if strings.HasPrefix(trimmed, "as") {
+						name := strings.TrimSpace(trimmed[2:])
+						nameStart := parameter.SourceSpan.Start + strings.LastIndex(parameter.Expression, name)
+						keySpan := ep.AbsoluteSourceSpan{Start: nameStart, End: nameStart + len(name)}
+						alias = c.variable(name, name, parameter.SourceSpan, keySpan, nil)
+					}
+				}
+			}
+			branches = append(branches, append(own(branch),
+				jsonField{"expression", expression},
+				jsonField{"children", children(branch)},
+				jsonField{"expressionAlias", alias},
+			))
+		}
+		return append(base(block, chainSpan), jsonField{"branches", branches}), last
+	case "for":
+		return c.forBlock(chain, base(block, chainSpan), own, children), last
+	case "switch":
+		var expression interface{}
+		if len(block.Parameters) > 0 {
+			expression = c.blockExpression(block.Parameters[0], block)
+		}
+		cases := []jsonObject{}
+		unknown := []jsonObject{}
+		for _, child := range block.Children {
+			switchCase, ok := child.(*Block)
+			if !ok {
+				continue
+			}
+			if switchCase.Name != "case" && switchCase.Name != "default" {
+				unknown = append(unknown, c.unknownBlock(switchCase))
+				continue
+			}
+			var caseExpression interface{}
+			if switchCase.Name == "case" && len(switchCase.Parameters) > 0 {
+				caseExpression = c.blockExpression(switchCase.Parameters[0], switchCase)
+			}
+			cases = append(cases, append(own(switchCase),
+				jsonField{"expression", caseExpression},
+				jsonField{"children", children(switchCase)},
+			))
+		}
+		return append(own(block),
+			jsonField{"expression", expression},
+			jsonField{"cases", cases},
+			jsonField{"unknownBlocks", unknown},
+		), last
+	case "defer":
+		var placeholder, loading, deferError interface{}
+		for _, connected := range chain[1:] {
+			switch connected.Name {
+			case "placeholder":
+				placeholder = append(own(connected), jsonField{"children", children(connected)}, jsonField{"minimumTime", nil})
+			case "loading":
+				loading = append(own(connected), jsonField{"children", children(connected)}, jsonField{"afterTime", nil}, jsonField{"minimumTime", nil})
+			case "error":
+				deferError = append(own(connected), jsonField{"children", children(connected)})
+			}
+		}
+		return append(base(block, chainSpan),
+			jsonField{"children", children(block)},
+			jsonField{"triggers", jsonObject{}},
+			jsonField{"prefetchTriggers", jsonObject{}},
+			jsonField{"hydrateTriggers", jsonObject{}},
+			jsonField{"placeholder", placeholder},
+			jsonField{"loading", loading},
+			jsonField{"error", deferError},
+			jsonField{"mainBlockSpan", c.source.span(block.SourceSpan, nil)},
+		), last
+	default:
+		return c.unknownBlock(block), index
+	}
+}
+
+func (c *angularConverter) unknownBlock(block *Block) jsonObject {
+	return jsonObject{
+		{"name", block.Name},
+		{"sourceSpan", c.source.span(block.SourceSpan, nil)},
+		{"nameSpan", c.source.span(c.blockNameSpan(block), nil)},
+	}
+}
+
+var forContextVariables = []string{"$index", "$first", "$last", "$even", "$odd", "$count"}
+
+func (c *angularConverter) forBlock(chain []*Block, object jsonObject, own func(*Block) jsonObject, children func(*Block) []jsonObject) jsonObject {
+	block := chain[0]
+	var item, expression, trackBy, trackKeywordSpan interface{}
+	contextVariables := []jsonObject{}
+	for _, name := range forContextVariables {
+		contextVariables = append(contextVariables, c.variable(name, name, block.StartSourceSpan, block.StartSourceSpan, nil))
+	}
+
+	for i, parameter := range block.Parameters {
+		text := parameter.Expression
+		start := parameter.SourceSpan.Start
+
+		switch {
+		case i == 0:
+			match := forOfRegexp.FindStringSubmatchIndex(text)
+			if match == nil {
+				continue
+			}
+			keySpan := ep.AbsoluteSourceSpan{Start: start + match[2], End: start + match[3]}
+			item = c.variable(text[match[2]:match[3]], "$implicit", keySpan, keySpan, nil)
+			expression = c.blockExpression(BlockParameter{
+				Expression: text[match[4]:],
+				SourceSpan: ep.AbsoluteSourceSpan{Start: start + match[4], End: parameter.SourceSpan.End},
+			}, block)
+		case strings.HasPrefix(text, "track") && len(text) > 5 && isTagSpace(text[5]):
+			trackKeywordSpan = c.source.span(ep.AbsoluteSourceSpan{Start: start, End: start + 5}, nil)
+			trackBy = c.blockExpression(BlockParameter{
+				Expression: text[6:],
+				SourceSpan: ep.AbsoluteSourceSpan{Start: start + 6, End: parameter.SourceSpan.End},
+			}, block)
+		case hasWordAt(text, 0, "let"):
+			// let i = $index, odd = $odd
+			cursor := 3
+			for _, alias := range strings.Split(text[3:], ",") {
+				aliasStart := start + cursor
+				cursor += len(alias) + 1
+				parts := strings.SplitN(alias, "=", 2)
+				if len(parts) != 2 {
+					continue
+				}
+				name, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+				nameStart := aliasStart + strings.Index(alias, name)
+				valueStart := aliasStart + len(parts[0]) + 1 + strings.Index(parts[1], value)
+				keySpan := ep.AbsoluteSourceSpan{Start: nameStart, End: nameStart + len(name)}
+				valueSpan := ep.AbsoluteSourceSpan{Start: valueStart, End: valueStart + len(value)}
+				contextVariables = append(contextVariables, c.variable(name, value,
+					ep.AbsoluteSourceSpan{Start: nameStart, End: valueSpan.End}, keySpan, &valueSpan))
+			}
+		}
+	}
+
+	var empty interface{}
+	for _, connected := range chain[1:] {
+		if connected.Name == "empty" {
+			empty = append(own(connected), jsonField{"children", children(connected)})
+		}
+	}
+
+	return append(object,
+		jsonField{"item", item},
+		jsonField{"expression", expression},
+		jsonField{"trackBy", trackBy},
+		jsonField{"trackKeywordSpan", trackKeywordSpan},
+		jsonField{"contextVariables", contextVariables},
+		jsonField{"children", children(block)},
+		jsonField{"empty", empty},
+		jsonField{"mainBlockSpan", c.source.span(block.SourceSpan, nil)},
+	)
+}
+
+// blockNameSpan returns the span of `@else if` in the start of a block.
+func (c *angularConverter) blockNameSpan(block *Block) ep.AbsoluteSourceSpan {
+	source := c.source.file[0].Value.(string)
+	end := block.StartSourceSpan.Start + 1
+	for i := end; i < block.StartSourceSpan.End && i < len(source); i++ {
+		if isBlockNameChar(source[i]) {
+			end = i + 1
+		} else if !isTagSpace(source[i]) {
+			break
+		}
+	}
+	return ep.AbsoluteSourceSpan{Start: block.StartSourceSpan.Start, End: end}
+}
+
+func (c *angularConverter) blockExpression(parameter BlockParameter, block *Block) jsonObject {
+	ast := c.parser.ParseBinding(parameter.Expression, c.source.locationString(parameter.SourceSpan.Start), parameter.SourceSpan.Start)
+	return c.expression(ast, c.source.span(block.StartSourceSpan, nil))
+}
+
+// expression converts an ASTWithSource, its parse errors are reported at
+// errorSpan like Angular reports them at the binding.
+func (c *angularConverter) expression(ast *ep.ASTWithSource, errorSpan jsonObject) jsonObject {
+	errors := []jsonObject{}
+	for _, parseError := range ast.Errors {
+		errors = append(errors, jsonObject{
+			{"input", parseError.Input},
+			{"errLocation", parseError.ErrLocation},
+			{"ctxLocation", parseError.CtxLocation},
+			{"message", parseError.Message},
+		})
+		c.errors = append(c.errors, jsonObject{{"span", errorSpan}, {"msg", parseError.Message}, {"level", 1}})
+	}
+
+	e := &angularExpression{source: ast.Source, base: c.source.offset(ast.SourceSpan.Start), offsets: utf16Offsets(ast.Source), start: ast.SourceSpan.Start}
+	length := e.offsets[len(e.offsets)-1]
+
+	return jsonObject{
+		{"span", jsonObject{{"start", 0}, {"end", length}}},
+		{"sourceSpan", jsonObject{{"start", e.base}, {"end", e.base + length}}},
+		{"ast", e.convert(ast.Ast)},
+		{"source", ast.Source},
+		{"location", ast.Location},
+		{"errors", errors},
+	}
+}
+
+// angularExpression converts the nodes of one expression, their spans are
+// relative to its source and converted to UTF-16.
+type angularExpression struct {
+	source  string
+	offsets []int
+	// base is the UTF-16 offset of the expression, start its byte offset.
+	base  int
+	start int
+}
+
+func (e *angularExpression) relative(offset int) int {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(e.offsets) {
+		offset = len(e.offsets) - 1
+	}
+	return e.offsets[offset]
+}
+
+func (e *angularExpression) spans(ast ep.AST) jsonObject {
+	span := ast.GetSpan()
+	start, end := e.relative(span.Start), e.relative(span.End)
+	return jsonObject{
+		{"span", jsonObject{{"start", start}, {"end", end}}},
+		{"sourceSpan", jsonObject{{"start", e.base + start}, {"end", e.base + end}}},
+	}
+}
+
+// absolute converts an absolute span like the name span of a property read.
+func (e *angularExpression) absolute(span ep.AbsoluteSourceSpan) jsonObject {
+	return jsonObject{{"start", e.base + e.relative(span.Start-e.start)}, {"end", e.base + e.relative(span.End-e.start)}}
+}
+
+func (e *angularExpression) converts(asts []ep.AST) []jsonObject {
+	converted := make([]jsonObject, len(asts))
+	for i, ast := range asts {
+		converted[i] = e.convert(ast)
+	}
+	return converted
+}
+
+func (e *angularExpression) convert(ast ep.AST) jsonObject {
+	if ast == nil {
+		return nil
+	}
+	object := e.spans(ast)
+
+	switch ast := ast.(type) {
+	case *ep.ASTWithSource:
+		return e.convert(ast.Ast)
+	case *ep.Chain:
+		return append(object, jsonField{"expressions", e.converts(ast.Expressions)})
+	case *ep.Conditional:
+		return append(object,
+			jsonField{"condition", e.convert(ast.Condition)},
+			jsonField{"trueExp", e.convert(ast.TrueExp)},
+			jsonField{"falseExp", e.convert(ast.FalseExp)})
+	case *ep.PropertyRead:
+		return append(object, jsonField{"nameSpan", e.absolute(ast.NameSpan)}, jsonField{"receiver", e.convert(ast.Receiver)}, jsonField{"name", ast.Name})
+	case *ep.SafePropertyRead:
+		return append(object, jsonField{"nameSpan", e.absolute(ast.NameSpan)}, jsonField{"receiver", e.convert(ast.Receiver)}, jsonField{"name", ast.Name})
+	case *ep.PropertyWrite:
+		return append(object, jsonField{"nameSpan", e.absolute(ast.NameSpan)}, jsonField{"receiver", e.convert(ast.Receiver)},
+			jsonField{"name", ast.Name}, jsonField{"value", e.convert(ast.Value)})
+	case *ep.KeyedRead:
+		return append(object, jsonField{"receiver", e.convert(ast.Receiver)}, jsonField{"key", e.convert(ast.Key)})
+	case *ep.SafeKeyedRead:
+		return append(object, jsonField{"receiver", e.convert(ast.Receiver)}, jsonField{"key", e.convert(ast.Key)})
+	case *ep.KeyedWrite:
+		return append(object, jsonField{"receiver", e.convert(ast.Receiver)}, jsonField{"key", e.convert(ast.Key)}, jsonField{"value", e.convert(ast.Value)})
+	case *ep.BindingPipe:
+		return append(object, jsonField{"nameSpan", e.absolute(ast.NameSpan)}, jsonField{"exp", e.convert(ast.Exp)},
+			jsonField{"name", ast.Name}, jsonField{"args", e.converts(ast.Args)})
+	case *ep.LiteralPrimitive:
+		// JSON.stringify drops undefined properties
+		if ast.Value == ep.Undefined {
+			return object
+		}
+		// and writes Infinity and NaN as null
+		if number, ok := ast.Value.(float64); ok && (math.IsInf(number, 0) || math.IsNaN(number)) {
+			return append(object, jsonField{"value", nil})
+		}
+		return append(object, jsonField{"value", ast.Value})
+	case *ep.LiteralArray:
+		return append(object, jsonField{"expressions", e.converts(ast.Expressions)})
+	case *ep.LiteralMap:
+		keys := make([]jsonObject, len(ast.Keys))
+		for i, key := range ast.Keys {
+			keys[i] = jsonObject{{"key", key.Key}, {"quoted", key.Quoted}}
+		}
+		return append(object, jsonField{"keys", keys}, jsonField{"values", e.converts(ast.Values)})
+	case *ep.Interpolation:
+		return append(object, jsonField{"strings", ast.Strings}, jsonField{"expressions", e.converts(ast.Expressions)})
+	case *ep.Binary:
+		return append(object, jsonField{"operation", ast.Operation}, jsonField{"left", e.convert(ast.Left)}, jsonField{"right", e.convert(ast.Right)})
+	case *ep.CompoundAssignment:
+		return append(object, jsonField{"operation", ast.Operation}, jsonField{"left", e.convert(ast.Target)}, jsonField{"right", e.convert(ast.Value)})
+	case *ep.Unary:
+		// Unary extends Binary: -x is 0 - x and +x is x - 0
+		zero := append(e.spans(ast), jsonField{"value", 0})
+		left, right := zero, e.convert(ast.Expr)
+		if ast.Operator == "+" {
+			left, right = right, zero
+		}
+		return append(object, jsonField{"operation", "-"}, jsonField{"left", left}, jsonField{"right", right},
+			jsonField{"operator", ast.Operator}, jsonField{"expr", e.convert(ast.Expr)})
+	case *ep.PrefixNot:
+		return append(object, jsonField{"expression", e.convert(ast.Expression)})
+	case *ep.NonNullAssert:
+		return append(object, jsonField{"expression", e.convert(ast.Expression)})
+	case *ep.TypeofExpression:
+		return append(object, jsonField{"expression", e.convert(ast.Expression)})
+	case *ep.VoidExpression:
+		return append(object, jsonField{"expression", e.convert(ast.Expression)})
+	case *ep.Call:
+		return append(object, jsonField{"receiver", e.convert(ast.Receiver)}, jsonField{"args", e.converts(ast.Args)},
+			jsonField{"argumentSpan", e.absolute(ast.ArgumentSpan)})
+	case *ep.SafeCall:
+		return append(object, jsonField{"receiver", e.convert(ast.Receiver)}, jsonField{"args", e.converts(ast.Args)},
+			jsonField{"argumentSpan", e.absolute(ast.ArgumentSpan)})
+	case *ep.TemplateLiteral:
+		elements := make([]jsonObject, len(ast.Elements))
+		for i, element := range ast.Elements {
+			elements[i] = append(e.spans(element), jsonField{"text", element.Text})
+		}
+		return append(object, jsonField{"elements", elements}, jsonField{"expressions", e.converts(ast.Expressions)})
+	case *ep.TaggedTemplateLiteral:
+		return append(object, jsonField{"tag", e.convert(ast.Tag)}, jsonField{"template", e.convert(ast.Template)})
+	default:
+		// empty expressions and receivers only have spans
+		return object
+	}
+}
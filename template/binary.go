@@ -0,0 +1,280 @@
+package template
+
+import (
+	"fmt"
+
+	"github.com/irustm/ng-template-parser/ep"
+	"github.com/irustm/ng-template-parser/schema"
+)
+
+// EncodeBinary encodes a parsed template in the compact binary encoding of
+// the ep package, which is several times smaller and faster than its JSON.
+// The template is decoded by DecodeBinary equal to the encoded one.
+func EncodeBinary(root Root) []byte {
+	w := ep.NewBinaryWriter()
+	writeNodes(w, root.Nodes)
+	w.ParserErrors(root.Errors)
+	w.String(root.Source)
+	return w.Bytes()
+}
+
+// DecodeBinary decodes a template encoded by EncodeBinary, data of another
+// ep.BinaryVersion fails.
+func DecodeBinary(data []byte) (Root, error) {
+	r, err := ep.NewBinaryReader(data)
+	if err != nil {
+		return Root{}, err
+	}
+
+	root := Root{Nodes: readNodes(r), Errors: r.ParserErrors(), Source: r.String()}
+	if r.Err() != nil {
+		return Root{}, r.Err()
+	}
+	return root, nil
+}
+
+// the kinds of nodes in the binary encoding, 0 is nil
+const (
+	binaryNil byte = iota
+	binaryElement
+	binaryText
+	binaryBoundText
+	binaryComment
+	binaryBlock
+	binaryTemplate
+)
+
+func writeNodes(w *ep.BinaryWriter, nodes []Node) {
+	w.Length(len(nodes), nodes == nil)
+	for _, node := range nodes {
+		writeNode(w, node)
+	}
+}
+
+func writeNode(w *ep.BinaryWriter, node Node) {
+	switch n := node.(type) {
+	case nil:
+		w.Int(int(binaryNil))
+	case *Element:
+		w.Int(int(binaryElement))
+		w.String(n.Name)
+		writeTagAttributes(w, n.Attributes, n.Inputs, n.Outputs, n.References)
+		writeNodes(w, n.Children)
+		w.Bool(n.SelfClosing)
+		w.Span(n.SourceSpan)
+		w.Span(n.StartSourceSpan)
+		w.OptionalSpan(n.EndSourceSpan)
+	case *Template:
+		w.Int(int(binaryTemplate))
+		w.String(n.TagName)
+		w.Bool(n.TemplateAttr != nil)
+		if n.TemplateAttr != nil {
+			writeTextAttribute(w, *n.TemplateAttr)
+		}
+		writeTagAttributes(w, n.Attributes, n.Inputs, n.Outputs, n.References)
+		writeNodes(w, n.Children)
+		w.Bool(n.SelfClosing)
+		w.Span(n.SourceSpan)
+		w.Span(n.StartSourceSpan)
+		w.OptionalSpan(n.EndSourceSpan)
+	case *Text:
+		w.Int(int(binaryText))
+		w.String(n.Value)
+		w.Span(n.SourceSpan)
+	case *BoundText:
+		w.Int(int(binaryBoundText))
+		w.AST(astOrNil(n.Value))
+		w.Span(n.SourceSpan)
+	case *Comment:
+		w.Int(int(binaryComment))
+		w.String(n.Value)
+		w.Span(n.SourceSpan)
+	case *Block:
+		w.Int(int(binaryBlock))
+		w.String(n.Name)
+		w.Length(len(n.Parameters), n.Parameters == nil)
+		for _, parameter := range n.Parameters {
+			w.String(parameter.Expression)
+			w.Span(parameter.SourceSpan)
+		}
+		writeNodes(w, n.Children)
+		w.Span(n.SourceSpan)
+		w.Span(n.StartSourceSpan)
+		w.OptionalSpan(n.EndSourceSpan)
+	default:
+		panic(fmt.Sprintf("can't encode %T", node))
+	}
+}
+
+// writeTagAttributes writes the attributes, bindings and references of an
+// element or template.
+func writeTagAttributes(w *ep.BinaryWriter, attributes []TextAttribute, inputs []BoundAttribute, outputs []BoundEvent, references []Reference) {
+	w.Length(len(attributes), attributes == nil)
+	for _, attr := range attributes {
+		writeTextAttribute(w, attr)
+	}
+
+	w.Length(len(inputs), inputs == nil)
+	for _, input := range inputs {
+		w.String(input.Name)
+		w.Int(int(input.BindingType))
+		w.Int(int(input.SecurityContext))
+		w.AST(astOrNil(input.Value))
+		w.Span(input.SourceSpan)
+		w.Span(input.KeySpan)
+		w.OptionalSpan(input.ValueSpan)
+	}
+
+	w.Length(len(outputs), outputs == nil)
+	for _, output := range outputs {
+		w.String(output.Name)
+		w.Int(int(output.BindingType))
+		w.AST(astOrNil(output.Handler))
+		w.Span(output.SourceSpan)
+		w.Span(output.KeySpan)
+		w.OptionalSpan(output.ValueSpan)
+	}
+
+	w.Length(len(references), references == nil)
+	for _, ref := range references {
+		w.String(ref.Name)
+		w.String(ref.Value)
+		w.Span(ref.SourceSpan)
+		w.Span(ref.KeySpan)
+		w.OptionalSpan(ref.ValueSpan)
+	}
+}
+
+func writeTextAttribute(w *ep.BinaryWriter, attr TextAttribute) {
+	w.String(attr.Name)
+	w.String(attr.Value)
+	w.Span(attr.SourceSpan)
+	w.Span(attr.KeySpan)
+	w.OptionalSpan(attr.ValueSpan)
+}
+
+// astOrNil keeps a nil *ep.ASTWithSource from becoming a non-nil ep.AST.
+func astOrNil(ast *ep.ASTWithSource) ep.AST {
+	if ast == nil {
+		return nil
+	}
+	return ast
+}
+
+func readNodes(r *ep.BinaryReader) []Node {
+	n := r.Length()
+	if n < 0 {
+		return nil
+	}
+	nodes := make([]Node, n)
+	for i := range nodes {
+		nodes[i] = readNode(r)
+	}
+	return nodes
+}
+
+func readNode(r *ep.BinaryReader) Node {
+	switch kind := byte(r.Int()); kind {
+	case binaryElement:
+		element := &Element{Name: r.String()}
+		element.Attributes, element.Inputs, element.Outputs, element.References = readTagAttributes(r)
+		element.Children = readNodes(r)
+		element.SelfClosing = r.Bool()
+		element.SourceSpan = r.Span()
+		element.StartSourceSpan = r.Span()
+		element.EndSourceSpan = r.OptionalSpan()
+		return element
+	case binaryTemplate:
+		template := &Template{TagName: r.String()}
+		if r.Bool() {
+			attr := readTextAttribute(r)
+			template.TemplateAttr = &attr
+		}
+		template.Attributes, template.Inputs, template.Outputs, template.References = readTagAttributes(r)
+		template.Children = readNodes(r)
+		template.SelfClosing = r.Bool()
+		template.SourceSpan = r.Span()
+		template.StartSourceSpan = r.Span()
+		template.EndSourceSpan = r.OptionalSpan()
+		return template
+	case binaryText:
+		return &Text{Value: r.String(), SourceSpan: r.Span()}
+	case binaryBoundText:
+		return &BoundText{Value: r.ASTWithSource(), SourceSpan: r.Span()}
+	case binaryComment:
+		return &Comment{Value: r.String(), SourceSpan: r.Span()}
+	case binaryBlock:
+		block := &Block{Name: r.String()}
+		if n := r.Length(); n >= 0 {
+			block.Parameters = make([]BlockParameter, n)
+			for i := range block.Parameters {
+				block.Parameters[i] = BlockParameter{Expression: r.String(), SourceSpan: r.Span()}
+			}
+		}
+		block.Children = readNodes(r)
+		block.SourceSpan = r.Span()
+		block.StartSourceSpan = r.Span()
+		block.EndSourceSpan = r.OptionalSpan()
+		return block
+	case binaryNil:
+		return nil
+	default:
+		r.Fail()
+		return nil
+	}
+}
+
+func readTagAttributes(r *ep.BinaryReader) ([]TextAttribute, []BoundAttribute, []BoundEvent, []Reference) {
+	var attributes []TextAttribute
+	if n := r.Length(); n >= 0 {
+		attributes = make([]TextAttribute, n)
+		for i := range attributes {
+			attributes[i] = readTextAttribute(r)
+		}
+	}
+
+	var inputs []BoundAttribute
+	if n := r.Length(); n >= 0 {
+		inputs = make([]BoundAttribute, n)
+		for i := range inputs {
+			inputs[i] = BoundAttribute{
+				Name:            r.String(),
+				BindingType:     BindingType(r.Int()),
+				SecurityContext: schema.SecurityContext(r.Int()),
+				Value:           r.ASTWithSource(),
+				SourceSpan:      r.Span(),
+				KeySpan:         r.Span(),
+				ValueSpan:       r.OptionalSpan(),
+			}
+		}
+	}
+
+	var outputs []BoundEvent
+	if n := r.Length(); n >= 0 {
+		outputs = make([]BoundEvent, n)
+		for i := range outputs {
+			outputs[i] = BoundEvent{
+				Name:        r.String(),
+				BindingType: BindingType(r.Int()),
+				Handler:     r.ASTWithSource(),
+				SourceSpan:  r.Span(),
+				KeySpan:     r.Span(),
+				ValueSpan:   r.OptionalSpan(),
+			}
+		}
+	}
+
+	var references []Reference
+	if n := r.Length(); n >= 0 {
+		references = make([]Reference, n)
+		for i := range references {
+			references[i] = Reference{Name: r.String(), Value: r.String(), SourceSpan: r.Span(), KeySpan: r.Span(), ValueSpan: r.OptionalSpan()}
+		}
+	}
+
+	return attributes, inputs, outputs, references
+}
+
+func readTextAttribute(r *ep.BinaryReader) TextAttribute {
+	return TextAttribute{Name: r.String(), Value: r.String(), SourceSpan: r.Span(), KeySpan: r.Span(), ValueSpan: r.OptionalSpan()}
+}
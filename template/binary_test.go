@@ -0,0 +1,112 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// benchmarkTemplates are the templates of the benchmarks, compare runs with
+// e.g. `go test -run - -bench . -count 10 | benchstat`.
+var benchmarkTemplates = []string{"../template.html", "../bin/template.html", "testdata/angular/template.html"}
+
+func parseFile(path string) (Root, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Root{}, err
+	}
+	return Parse(bytes.NewReader(src), path), nil
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for _, path := range benchmarkTemplates {
+		root, err := parseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeBinary(EncodeBinary(root))
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, root) {
+			t.Errorf("%s doesn't decode into the same tree", path)
+		}
+	}
+}
+
+// benchmarkEach runs fn as a sub-benchmark for every benchmark template.
+func benchmarkEach(b *testing.B, fn func(b *testing.B, root Root)) {
+	for _, path := range benchmarkTemplates {
+		root, err := parseFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(strings.Replace(strings.TrimPrefix(path, "../"), "/", "_", -1), func(b *testing.B) {
+			fn(b, root)
+		})
+	}
+}
+
+func BenchmarkParseTemplate(b *testing.B) {
+	benchmarkEach(b, func(b *testing.B, root Root) {
+		b.SetBytes(int64(len(root.Source)))
+		for i := 0; i < b.N; i++ {
+			Parse(strings.NewReader(root.Source), "")
+		}
+	})
+}
+
+func BenchmarkEncodeJSON(b *testing.B) {
+	benchmarkEach(b, func(b *testing.B, root Root) {
+		var data []byte
+		for i := 0; i < b.N; i++ {
+			var err error
+			if data, err = json.Marshal(root); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(len(data)), "bytes")
+	})
+}
+
+func BenchmarkDecodeJSON(b *testing.B) {
+	benchmarkEach(b, func(b *testing.B, root Root) {
+		data, err := json.Marshal(root)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			var decoded Root
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkEncodeBinary(b *testing.B) {
+	benchmarkEach(b, func(b *testing.B, root Root) {
+		var data []byte
+		for i := 0; i < b.N; i++ {
+			data = EncodeBinary(root)
+		}
+		b.ReportMetric(float64(len(data)), "bytes")
+	})
+}
+
+func BenchmarkDecodeBinary(b *testing.B) {
+	benchmarkEach(b, func(b *testing.B, root Root) {
+		data := EncodeBinary(root)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := DecodeBinary(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
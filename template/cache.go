@@ -0,0 +1,174 @@
+package template
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+// ParserVersion is part of the keys of the parse cache, change it with every
+// change of the parse result so cached templates are parsed again.
+const ParserVersion = "2"
+
+// DefaultCacheSize is the default size limit of a parse cache, 256 MB.
+const DefaultCacheSize = 256 << 20
+
+const cacheExtension = ".ngtb"
+
+// ParseCache keeps parsed templates in a directory in the binary encoding,
+// keyed by the SHA-256 of the source, ParserVersion, the encoding version and
+// the location of the template. When the directory grows over MaxSize the
+// least recently used templates are removed.
+type ParseCache struct {
+	Dir     string
+	MaxSize int64
+
+	// Hits and Misses count the templates read from and added to the cache.
+	Hits   int
+	Misses int
+
+	// size is the size of the entries found by the last Evict and of those
+	// written since, so the directory is only read again when it may be too
+	// large. Entries written by other builds are counted by the next Evict.
+	size    int64
+	scanned bool
+}
+
+func NewParseCache(dir string, maxSize int64) (*ParseCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &ParseCache{Dir: dir, MaxSize: maxSize}, nil
+}
+
+// Key returns the cache key of a template, location is part of the errors of
+// its expressions.
+func (c *ParseCache) Key(src []byte, location string) string {
+	hash := sha256.New()
+	for _, part := range []string{ParserVersion, strconv.Itoa(ep.BinaryVersion), location} {
+		hash.Write([]byte(strconv.Itoa(len(part)) + ":" + part))
+	}
+	hash.Write(src)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Parse returns the cached template for src or parses and caches it. Entries
+// which can't be read or decoded are parsed again, failing to write the cache
+// doesn't fail the parse. The first template added runs Evict, later ones
+// only when the cache may have grown over MaxSize.
+func (c *ParseCache) Parse(src []byte, location string) Root {
+	path := filepath.Join(c.Dir, c.Key(src, location)+cacheExtension)
+
+	if data, err := ioutil.ReadFile(path); err == nil {
+		if root, err := DecodeBinary(data); err == nil {
+			c.Hits++
+			// the modification time is the last use for the eviction
+			now := time.Now()
+			_ = os.Chtimes(path, now, now)
+			return root
+		}
+	}
+
+	c.Misses++
+	root := Parse(bytes.NewReader(src), location)
+	data := EncodeBinary(root)
+	if err := c.write(path, data); err == nil {
+		c.size += int64(len(data))
+		if !c.scanned || (c.MaxSize > 0 && c.size > c.MaxSize) {
+			_ = c.Evict()
+		}
+	}
+	return root
+}
+
+// tempPrefix starts the names of the files written before they are renamed
+// to entries.
+const tempPrefix = ".tmp-"
+
+// staleTempAge is the age of temporary files which Evict removes, they were
+// left by a build which crashed while writing, other builds rename theirs
+// long before.
+const staleTempAge = time.Hour
+
+// write replaces a file by renaming a temporary file, so concurrent builds
+// never read a partial entry.
+func (c *ParseCache) write(path string, data []byte) error {
+	f, err := ioutil.TempFile(c.Dir, tempPrefix)
+	if err != nil {
+		return err
+	}
+	_ = f.Chmod(0644)
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Evict removes the least recently used entries until the cache is not
+// larger than MaxSize, a MaxSize of 0 or less means no limit, and the
+// temporary files older than staleTempAge.
+func (c *ParseCache) Evict() error {
+	files, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		return err
+	}
+
+	var entries []os.FileInfo
+	var size int64
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(file.Name(), tempPrefix) {
+			if time.Since(file.ModTime()) > staleTempAge {
+				if err := os.Remove(filepath.Join(c.Dir, file.Name())); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			continue
+		}
+		if strings.HasSuffix(file.Name(), cacheExtension) {
+			entries = append(entries, file)
+			size += file.Size()
+		}
+	}
+	c.size, c.scanned = size, true
+	if c.MaxSize <= 0 || size <= c.MaxSize {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+	for _, entry := range entries {
+		if size <= c.MaxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.Dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		size -= entry.Size()
+		c.size = size
+	}
+
+	return nil
+}
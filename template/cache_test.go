@@ -0,0 +1,80 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ngbuild-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stale := filepath.Join(dir, tempPrefix+"stale")
+	fresh := filepath.Join(dir, tempPrefix+"fresh")
+	for _, path := range []string{stale, fresh} {
+		if err := ioutil.WriteFile(path, []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * staleTempAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewParseCache(dir, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sources []string
+	for i := 0; i < 50; i++ {
+		sources = append(sources, `<div [title]="a`+strconv.Itoa(i)+`">{{ b | c }}</div>`)
+	}
+	for _, src := range sources {
+		root := cache.Parse([]byte(src), "t.html")
+		if want := Parse(strings.NewReader(src), "t.html"); !reflect.DeepEqual(root, want) {
+			t.Fatalf("the cached %q differs from Parse", src)
+		}
+	}
+	if cache.Misses != len(sources) || cache.Hits != 0 {
+		t.Errorf("got %d misses and %d hits, want %d misses", cache.Misses, cache.Hits, len(sources))
+	}
+
+	last := sources[len(sources)-1]
+	if root := cache.Parse([]byte(last), "t.html"); !reflect.DeepEqual(root, Parse(strings.NewReader(last), "t.html")) || cache.Hits != 1 {
+		t.Errorf("the last template is not read from the cache")
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temporary file is not removed")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("temporary file of a running build is removed: %v", err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size int64
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), cacheExtension) {
+			size += file.Size()
+		}
+	}
+	if size > cache.MaxSize {
+		t.Errorf("cache has %d bytes, over its limit of %d", size, cache.MaxSize)
+	}
+	if len(files) >= len(sources) {
+		t.Errorf("no entry is evicted, the cache has %d files", len(files))
+	}
+}
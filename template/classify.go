@@ -0,0 +1,364 @@
+package template
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+// SemanticTokenKind is what a range of a template is, for syntax highlighting.
+type SemanticTokenKind int
+
+const (
+	// TokenTagName is the name in a start or end tag.
+	TokenTagName SemanticTokenKind = iota
+	// TokenAttributeName is the name of a plain attribute, of an input or of
+	// a structural directive and its microsyntax keys like `of`.
+	TokenAttributeName
+	// TokenBindingKind is the binding syntax around a name: `[`, `]`, `(`,
+	// `)`, `[(`, `)]`, `*`, `#` and prefixes like `class.`.
+	TokenBindingKind
+	// TokenEventName is the name of an event binding.
+	TokenEventName
+	// TokenReference is a template variable: `#ref`, `let item`, `as alias`.
+	TokenReference
+	// TokenPipeName is the name of a pipe.
+	TokenPipeName
+	// TokenPropertyRead is a property read or written in an expression.
+	TokenPropertyRead
+	// TokenKeyword is an expression keyword like `true`, `null` or `this`,
+	// or a key of the template context like `index` in `index as i`.
+	TokenKeyword
+	TokenString
+	TokenNumber
+	TokenOperator
+	// TokenControlFlowKeyword is the name of a block like `@if` and the
+	// keywords in its parameters like `track` or `on`.
+	TokenControlFlowKeyword
+)
+
+var semanticTokenKindNames = []string{
+	"tagName", "attributeName", "bindingKind", "eventName", "reference", "pipeName",
+	"propertyRead", "keyword", "string", "number", "operator", "controlFlowKeyword",
+}
+
+func (k SemanticTokenKind) String() string {
+	if k >= 0 && int(k) < len(semanticTokenKindNames) {
+		return semanticTokenKindNames[k]
+	}
+	return "unknown"
+}
+
+// SemanticToken is a classified range of a template, Span is in bytes.
+type SemanticToken struct {
+	Kind SemanticTokenKind
+	Span ep.AbsoluteSourceSpan
+}
+
+// Classify parses a template and labels its names, binding syntax and the
+// tokens of its expressions and block parameters in source order. Text,
+// comments and punctuation like `{{` or `,` are not labelled, tokens don't
+// overlap.
+func Classify(src string) []SemanticToken {
+	return classifyTemplate(Parse(strings.NewReader(src), ""))
+}
+
+// classifyTemplate classifies a parsed template, e.g. one kept by the
+// language server.
+func classifyTemplate(root Root) []SemanticToken {
+	c := &classifier{source: root.Source, parser: ep.NewParser()}
+	for _, node := range root.Nodes {
+		Walk(node, func(n Node) bool {
+			switch n := n.(type) {
+			case *Element:
+				c.element(n)
+			case *Template:
+				// the element of a structural directive is visited next
+				if n.TemplateAttr != nil {
+					c.structuralDirective(*n.TemplateAttr)
+				} else {
+					c.element(n.Element())
+				}
+			case *BoundText:
+				c.expression(n.Value)
+			case *Block:
+				c.block(n)
+			}
+			return true
+		})
+	}
+
+	// the first token added at an offset wins, e.g. a binding over a keyword
+	sort.SliceStable(c.tokens, func(i, j int) bool { return c.tokens[i].Span.Start < c.tokens[j].Span.Start })
+	var tokens []SemanticToken
+	end := 0
+	for _, token := range c.tokens {
+		if token.Span.Start >= end {
+			tokens = append(tokens, token)
+			end = token.Span.End
+		}
+	}
+	return tokens
+}
+
+type classifier struct {
+	source string
+	parser *ep.Parser
+	tokens []SemanticToken
+}
+
+func (c *classifier) add(start, end int, kind SemanticTokenKind) {
+	if start < end && end <= len(c.source) {
+		c.tokens = append(c.tokens, SemanticToken{Kind: kind, Span: ep.AbsoluteSourceSpan{Start: start, End: end}})
+	}
+}
+
+func (c *classifier) element(n *Element) {
+	start := n.StartSourceSpan.Start + 1
+	c.add(start, start+len(n.Name), TokenTagName)
+	if n.EndSourceSpan != nil {
+		// any end tag closes the element, its name may differ
+		start, end := n.EndSourceSpan.Start+2, n.EndSourceSpan.Start+2
+		for end < n.EndSourceSpan.End && !isTagSpace(c.source[end]) && c.source[end] != '>' && c.source[end] != '/' {
+			end++
+		}
+		c.add(start, end, TokenTagName)
+	}
+
+	for _, attr := range n.Attributes {
+		if !c.hasKey(attr.KeySpan) {
+			continue
+		}
+		if strings.HasPrefix(attr.Name, "*") {
+			c.structuralDirective(attr)
+		} else {
+			c.add(attr.KeySpan.Start, attr.KeySpan.End, TokenAttributeName)
+		}
+	}
+
+	for _, input := range n.Inputs {
+		if c.hasKey(input.KeySpan) {
+			key := input.KeySpan
+			open, close := 1, 1
+			if input.BindingType == BindingTypeTwoWay {
+				open, close = 2, 2
+			}
+			c.add(key.Start, key.Start+open, TokenBindingKind)
+			if i := strings.IndexByte(c.source[key.Start:key.End], '.'); i != -1 && input.BindingType != BindingTypeProperty && input.BindingType != BindingTypeTwoWay {
+				// [class.active] binds a class
+				c.add(key.Start+open, key.Start+i+1, TokenBindingKind)
+				open = i + 1
+			}
+			c.add(key.Start+open, key.End-close, TokenAttributeName)
+			c.add(key.End-close, key.End, TokenBindingKind)
+		}
+		c.expression(input.Value)
+	}
+
+	for _, output := range n.Outputs {
+		// the handler of [(x)] is made up, the input has the same key
+		if output.BindingType == BindingTypeTwoWay {
+			continue
+		}
+		if c.hasKey(output.KeySpan) {
+			key := output.KeySpan
+			c.add(key.Start, key.Start+1, TokenBindingKind)
+			c.add(key.Start+1, key.End-1, TokenEventName)
+			c.add(key.End-1, key.End, TokenBindingKind)
+		}
+		c.expression(output.Handler)
+	}
+
+	for _, ref := range n.References {
+		if c.hasKey(ref.KeySpan) {
+			c.add(ref.KeySpan.Start, ref.KeySpan.Start+1, TokenBindingKind)
+			c.add(ref.KeySpan.Start+1, ref.KeySpan.End, TokenReference)
+		}
+	}
+}
+
+// structuralDirective classifies an attribute like `*ngIf="user"`.
+func (c *classifier) structuralDirective(attr TextAttribute) {
+	if !c.hasKey(attr.KeySpan) {
+		return
+	}
+	c.add(attr.KeySpan.Start, attr.KeySpan.Start+1, TokenBindingKind)
+	c.add(attr.KeySpan.Start+1, attr.KeySpan.End, TokenAttributeName)
+	c.templateBindings(attr)
+}
+
+// hasKey reports whether the parser found the key of an attribute in the
+// source, it leaves the KeySpan unset otherwise.
+func (c *classifier) hasKey(key ep.AbsoluteSourceSpan) bool {
+	return key.End > key.Start && key.End <= len(c.source)
+}
+
+// templateBindings classifies the microsyntax of a structural directive like
+// `*ngFor="let item of items; index as i"`.
+func (c *classifier) templateBindings(attr TextAttribute) {
+	offset := attributeValueOffset(attr)
+	bindings, _ := ParseTemplateBindings(attr.Name[1:], attr.Value, "", offset, c.parser)
+	for _, binding := range bindings {
+		if binding.IsVariable() {
+			c.add(binding.KeySpan.Start, binding.KeySpan.End, TokenReference)
+			// `index` of `index as i` is a key of the template context, no
+			// property of the component
+			if binding.ValueSpan != nil {
+				c.add(binding.ValueSpan.Start, binding.ValueSpan.End, TokenKeyword)
+			}
+			continue
+		}
+		c.add(binding.KeySpan.Start, binding.KeySpan.End, TokenAttributeName)
+		c.expression(binding.Value)
+	}
+
+	// `let` and `as` are only in the lexer tokens
+	for _, token := range (ep.Lexer{}).Tokenize(attr.Value) {
+		if token.TypeToken == ep.Keyword {
+			c.add(offset+token.Index, offset+token.End, TokenKeyword)
+		}
+	}
+}
+
+// expression classifies the lexer tokens of an expression, the tree tells
+// which identifiers are properties and which pipes.
+func (c *classifier) expression(ast *ep.ASTWithSource) {
+	if ast == nil {
+		return
+	}
+
+	names := map[int]SemanticTokenKind{}
+	ep.Walk(ast, func(e ep.AST) bool {
+		switch e := e.(type) {
+		case *ep.PropertyRead:
+			names[e.NameSpan.Start] = TokenPropertyRead
+		case *ep.SafePropertyRead:
+			names[e.NameSpan.Start] = TokenPropertyRead
+		case *ep.PropertyWrite:
+			names[e.NameSpan.Start] = TokenPropertyRead
+		case *ep.BindingPipe:
+			names[e.NameSpan.Start] = TokenPipeName
+		}
+		return true
+	})
+
+	// an interpolation is lexed piece by piece, the text around is no
+	// expression
+	pieces := []ep.AST{ast}
+	if interpolation, ok := ast.Ast.(*ep.Interpolation); ok {
+		pieces = interpolation.Expressions
+	}
+	for _, piece := range pieces {
+		span := piece.GetSourceSpan()
+		start, end := span.Start-ast.SourceSpan.Start, span.End-ast.SourceSpan.Start
+		if start < 0 || end > len(ast.Source) || start > end {
+			continue
+		}
+		for _, token := range (ep.Lexer{}).Tokenize(ast.Source[start:end]) {
+			tokenStart, tokenEnd := span.Start+token.Index, span.Start+token.End
+			if token.TypeToken == ep.Identifier {
+				if kind, ok := names[tokenStart]; ok {
+					c.add(tokenStart, tokenEnd, kind)
+				}
+			} else if kind, ok := lexerTokenKind(token); ok {
+				c.add(tokenStart, tokenEnd, kind)
+			}
+		}
+	}
+}
+
+func lexerTokenKind(token ep.Token) (SemanticTokenKind, bool) {
+	switch token.TypeToken {
+	case ep.Keyword:
+		return TokenKeyword, true
+	case ep.String:
+		return TokenString, true
+	case ep.Number:
+		return TokenNumber, true
+	case ep.Operator:
+		return TokenOperator, true
+	}
+	return 0, false
+}
+
+// blockParameterKeywords start the parameters of blocks, like `track` of
+// @for or `on` and `prefetch when` of @defer.
+var blockParameterKeywords = map[string]bool{
+	"track": true, "let": true, "on": true, "when": true, "prefetch": true,
+	"hydrate": true, "never": true, "minimum": true, "after": true,
+}
+
+func (c *classifier) block(n *Block) {
+	// `@else if` may have several spaces
+	raw := c.source[n.StartSourceSpan.Start:n.StartSourceSpan.End]
+	i := 1
+	for i < len(raw) && isBlockNameChar(raw[i]) {
+		i++
+	}
+	if n.Name == "else if" {
+		i = skipTagSpace(raw, i) + len("if")
+	}
+	c.add(n.StartSourceSpan.Start, n.StartSourceSpan.Start+i, TokenControlFlowKeyword)
+
+	for index, parameter := range n.Parameters {
+		c.blockParameter(n, index, parameter)
+	}
+}
+
+// blockParameter classifies a parameter by its lexer tokens: the keywords at
+// its start, the variables it declares and the expression.
+func (c *classifier) blockParameter(n *Block, index int, parameter BlockParameter) {
+	tokens := (ep.Lexer{}).Tokenize(parameter.Expression)
+	leading := ""
+	depth := 0
+	for i, token := range tokens {
+		start, end := parameter.SourceSpan.Start+token.Index, parameter.SourceSpan.Start+token.End
+		word := token.TypeToken == ep.Identifier || token.TypeToken == ep.Keyword
+		previous, next := ep.EOF, ep.EOF
+		if i > 0 {
+			previous = tokens[i-1]
+		}
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+
+		switch {
+		case word && i == len(strings.Fields(leading)) && blockParameterKeywords[token.StrValue]:
+			// `prefetch on` is two keywords
+			leading += token.StrValue + " "
+			c.add(start, end, TokenControlFlowKeyword)
+		case token.TypeToken == ep.Character:
+			if token.StrValue == "(" {
+				depth++
+			} else if token.StrValue == ")" {
+				depth--
+			}
+		case n.Name == "for" && index == 0 && i == 0 && word:
+			// the loop variable of `item of items`
+			c.add(start, end, TokenReference)
+		case n.Name == "for" && index == 0 && i == 1 && token.StrValue == "of":
+			c.add(start, end, TokenControlFlowKeyword)
+		case token.TypeToken == ep.Keyword && token.StrValue == "as":
+			c.add(start, end, TokenControlFlowKeyword)
+		case word && previous.TypeToken == ep.Keyword && previous.StrValue == "as":
+			c.add(start, end, TokenReference)
+		case word && leading == "let " && next.TypeToken == ep.Operator && next.StrValue == "=":
+			c.add(start, end, TokenReference)
+		case word && strings.HasSuffix(leading, "on ") && depth == 0:
+			// the triggers of @defer like `on idle, timer(5s)`
+			c.add(start, end, TokenControlFlowKeyword)
+		case token.TypeToken == ep.Identifier && previous.TypeToken == ep.Number && previous.End == token.Index:
+			// the unit of `timer(5s)`
+			c.add(start, end, TokenNumber)
+		case token.TypeToken == ep.Identifier && previous.TypeToken == ep.Operator && previous.StrValue == "|":
+			c.add(start, end, TokenPipeName)
+		case token.TypeToken == ep.Identifier:
+			c.add(start, end, TokenPropertyRead)
+		default:
+			if kind, ok := lexerTokenKind(token); ok {
+				c.add(start, end, kind)
+			}
+		}
+	}
+}
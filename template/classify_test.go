@@ -0,0 +1,54 @@
+package template
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{
+			src:  "{{ `a${b}cd` }}",
+			want: []string{"string `a", "operator ${", "propertyRead b", "operator }", "string cd`"},
+		},
+		{
+			src: `<li *ngFor="let i of items; index as j"></li>`,
+			want: []string{
+				"tagName li", "bindingKind *", "attributeName ngFor", "keyword let", "reference i",
+				"attributeName of", "propertyRead items", "keyword index", "keyword as", "reference j", "tagName li",
+			},
+		},
+		{
+			src: `<a [class.on]="x | y" (click)="go()" #ref>`,
+			want: []string{
+				"tagName a", "bindingKind [", "bindingKind class.", "attributeName on", "bindingKind ]",
+				"propertyRead x", "operator |", "pipeName y", "bindingKind (", "eventName click",
+				"bindingKind )", "propertyRead go", "bindingKind #", "reference ref",
+			},
+		},
+		{
+			src: "@for (item of items; track item.id) {} @empty {}",
+			want: []string{
+				"controlFlowKeyword @for", "reference item", "controlFlowKeyword of", "propertyRead items",
+				"controlFlowKeyword track", "propertyRead item", "propertyRead id", "controlFlowKeyword @empty",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tokens := Classify(test.src)
+		var got []string
+		for _, token := range tokens {
+			got = append(got, token.Kind.String()+" "+test.src[token.Span.Start:token.Span.End])
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("Classify(%q) = %q, want %q", test.src, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("Classify(%q) token %d = %q, want %q", test.src, i, got[i], test.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/irustm/ng-template-parser/schema"
+)
+
+// TestConformance compares the MarshalAngularJSON output for the templates in
+// testdata/angular with the JSON recorded from parseTemplate of
+// @angular/compiler next to them, e.g. `card.html` with `card.json`, and
+// reports the differences node by node. `npm run fixtures` in js_ngc_demo
+// records the JSON, the templateUrl of every fixture is `./<name>.html`.
+func TestConformance(t *testing.T) {
+	templates, err := filepath.Glob(filepath.Join("testdata", "angular", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := schema.NewDomElementSchemaRegistry()
+
+	for _, template := range templates {
+		template := template
+		t.Run(filepath.Base(template), func(t *testing.T) {
+			root, err := parseFile(template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := MarshalAngularJSON(root, "./"+filepath.Base(template), registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := ioutil.ReadFile(strings.TrimSuffix(template, ".html") + ".json")
+			if os.IsNotExist(err) {
+				t.Fatal("no JSON recorded, run `npm run fixtures` in js_ngc_demo")
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			differences, err := compareAngularJSON(expected, actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, difference := range differences {
+				t.Error(difference)
+			}
+		})
+	}
+}
+
+// conformanceDifference is a difference between the output of
+// @angular/compiler and MarshalAngularJSON. Path names the nodes on the way,
+// e.g. `nodes[2]<div>.children[0]<span>.inputs[1]`.
+type conformanceDifference struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (d conformanceDifference) String() string {
+	return d.Path + ": expected " + d.Expected + ", got " + d.Actual
+}
+
+// compareAngularJSON compares two parseTemplate results node by node. The
+// order of object keys and the file of spans are ignored, locations are
+// compared as a whole and printed as the 0-based line:col of Angular.
+func compareAngularJSON(expected []byte, actual []byte) ([]conformanceDifference, error) {
+	var want, got interface{}
+	if err := decodeJSON(expected, &want); err != nil {
+		return nil, fmt.Errorf("expected: %v", err)
+	}
+	if err := decodeJSON(actual, &got); err != nil {
+		return nil, fmt.Errorf("actual: %v", err)
+	}
+
+	var differences []conformanceDifference
+	compareJSON("", want, got, &differences)
+	return differences, nil
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func compareJSON(path string, want interface{}, got interface{}, differences *[]conformanceDifference) {
+	differ := func(expected string, actual string) {
+		*differences = append(*differences, conformanceDifference{Path: strings.TrimPrefix(path, "."), Expected: expected, Actual: actual})
+	}
+
+	switch want := want.(type) {
+	case map[string]interface{}:
+		got, ok := got.(map[string]interface{})
+		if !ok {
+			differ(describeJSON(want), describeJSON(got))
+			return
+		}
+		if isJSONLocation(want) && isJSONLocation(got) {
+			if describeJSON(want) != describeJSON(got) {
+				differ(describeJSON(want), describeJSON(got))
+			}
+			return
+		}
+
+		for _, key := range sortedKeys(want) {
+			if key == "file" {
+				continue
+			}
+			value, ok := got[key]
+			if !ok {
+				differ(key+" "+describeJSON(want[key]), "no "+key)
+				continue
+			}
+			compareJSON(path+"."+key, want[key], value, differences)
+		}
+		for _, key := range sortedKeys(got) {
+			if _, ok := want[key]; !ok {
+				differ("no "+key, key+" "+describeJSON(got[key]))
+			}
+		}
+	case []interface{}:
+		got, ok := got.([]interface{})
+		if !ok {
+			differ(describeJSON(want), describeJSON(got))
+			return
+		}
+		if len(want) != len(got) {
+			differ(strings.TrimSpace(fmt.Sprintf("%d items %s", len(want), describeJSONItems(want))),
+				strings.TrimSpace(fmt.Sprintf("%d items %s", len(got), describeJSONItems(got))))
+		}
+		for i := 0; i < len(want) && i < len(got); i++ {
+			compareJSON(fmt.Sprintf("%s[%d]%s", path, i, nodeLabel(want[i])), want[i], got[i], differences)
+		}
+	default:
+		if describeJSON(want) != describeJSON(got) {
+			differ(describeJSON(want), describeJSON(got))
+		}
+	}
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isJSONLocation reports whether an object is a ParseLocation.
+func isJSONLocation(object map[string]interface{}) bool {
+	_, offset := object["offset"]
+	_, line := object["line"]
+	_, col := object["col"]
+	return offset && line && col
+}
+
+// nodeLabel returns a short name of a template node for paths, e.g. `<div>`
+// or `[value]`, or "" for other values.
+func nodeLabel(value interface{}) string {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	name, _ := object["name"].(string)
+	if tagName, ok := object["tagName"].(string); ok {
+		if tagName == "ng-template" {
+			return "<ng-template>"
+		}
+		// the Template of a structural directive
+		return "*<" + tagName + ">"
+	}
+	switch {
+	case name == "":
+		if _, ok := object["branches"]; ok {
+			return "@if"
+		}
+		if _, ok := object["item"]; ok {
+			return "@for"
+		}
+		if _, ok := object["cases"]; ok {
+			return "@switch"
+		}
+		return ""
+	case hasJSONKeys(object, "children", "startSourceSpan"):
+		return "<" + name + ">"
+	case hasJSONKeys(object, "handler"):
+		return "(" + name + ")"
+	case hasJSONKeys(object, "securityContext"):
+		return "[" + name + "]"
+	default:
+		return " " + name
+	}
+}
+
+func hasJSONKeys(object map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := object[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// describeJSON prints a value for a difference, locations as line:col and
+// long values shortened.
+func describeJSON(value interface{}) string {
+	if object, ok := value.(map[string]interface{}); ok && isJSONLocation(object) {
+		line, _ := object["line"].(json.Number)
+		col, _ := object["col"].(json.Number)
+		return fmt.Sprintf("%s:%s (offset %s)", line, col, object["offset"])
+	}
+	if object, ok := value.(map[string]interface{}); ok {
+		if label := nodeLabel(object); label != "" {
+			return strings.TrimSpace(label)
+		}
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	text := string(data)
+	if len(text) > 60 {
+		text = text[:57] + "..."
+	}
+	return text
+}
+
+// describeJSONItems lists the labels of the nodes of a list, if any.
+func describeJSONItems(items []interface{}) string {
+	var labels []string
+	for _, item := range items {
+		if label := strings.TrimSpace(nodeLabel(item)); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(labels, " ") + ")"
+}
@@ -0,0 +1,27 @@
+package template_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/irustm/ng-template-parser/template"
+)
+
+func ExampleParse() {
+	root := template.Parse(strings.NewReader(`<p [title]="a+b">{{x|uppercase}}</p>`), "example.html")
+
+	fmt.Println(template.Serialize(root, template.SerializeNormalized))
+	// Output: <p [title]="a + b">{{ x | uppercase }}</p>
+}
+
+func ExampleFormatTemplate() {
+	formatted, err := template.FormatTemplate([]byte(`<div><p [title]="a+b">{{x|uppercase}}</p></div>`), template.DefaultFormatOptions)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(string(formatted))
+	// Output:
+	// <div>
+	//   <p [title]="a + b">{{ x | uppercase }}</p>
+	// </div>
+}
@@ -0,0 +1,449 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+// FormatOptions configures Format.
+type FormatOptions struct {
+	// Width is the line length after which the attributes of a start tag are
+	// put on separate lines.
+	Width int
+	// Indent is the text of one indentation level.
+	Indent string
+}
+
+var DefaultFormatOptions = FormatOptions{Width: 80, Indent: "  "}
+
+// FormatTemplate parses and formats a template, templates with expression
+// errors are not formatted.
+func FormatTemplate(src []byte, options FormatOptions) ([]byte, error) {
+	root := Parse(bytes.NewReader(src), "")
+	if len(root.Errors) > 0 {
+		messages := make([]string, len(root.Errors))
+		for i, parseError := range root.Errors {
+			messages[i] = parseError.Message
+		}
+		return nil, errors.New(strings.Join(messages, "\n"))
+	}
+
+	return []byte(Format(root, options)), nil
+}
+
+// Format prints a parsed template in a canonical layout: every element, block,
+// comment and text on its own line indented by its depth, attributes grouped
+// as references, structural directives, inputs, outputs and plain attributes,
+// and expressions printed by ep.Print. Elements with only text are kept on
+// one line when they fit, the content of elements like <pre> is kept as is.
+// A node which follows its sibling without whitespace continues its line, as
+// a line break between them would add a space to the rendered page.
+func Format(root Root, options FormatOptions) string {
+	f := &formatter{options: options}
+	if options.Width <= 0 {
+		f.options.Width = DefaultFormatOptions.Width
+	}
+
+	f.formatNodes(root.Nodes, 0, root.Source)
+
+	if len(f.lines) == 0 {
+		return ""
+	}
+	return strings.Join(f.lines, "\n") + "\n"
+}
+
+type formatter struct {
+	options FormatOptions
+	lines   []string
+}
+
+func (f *formatter) indent(depth int) string {
+	return strings.Repeat(f.options.Indent, depth)
+}
+
+func (f *formatter) line(depth int, text string) {
+	f.lines = append(f.lines, f.indent(depth)+text)
+}
+
+// appendToLine continues the last line, e.g. with the end tag of an element.
+func (f *formatter) appendToLine(text string) {
+	f.lines[len(f.lines)-1] += text
+}
+
+func (f *formatter) fits(line string) bool {
+	return utf8.RuneCountInString(line) <= f.options.Width
+}
+
+// formatNodes writes a list of siblings. Whitespace only text separates them,
+// one blank line of it is kept, siblings without whitespace between them
+// continue the same line. source is only set for the root nodes, where the
+// text between the nodes can be a doctype.
+func (f *formatter) formatNodes(nodes []Node, depth int, source string) {
+	blankLine := false
+	first := true
+	var previous Node
+	separated := false
+	cursor := 0
+
+	for _, node := range nodes {
+		if source != "" {
+			if span := node.GetSourceSpan(); span.End > span.Start && span.Start >= cursor && span.End <= len(source) {
+				if gap := strings.TrimSpace(source[cursor:span.Start]); gap != "" {
+					f.line(depth, gap)
+					first = false
+					previous = nil
+				}
+				cursor = span.End
+			}
+		}
+
+		if text, ok := node.(*Text); ok && strings.TrimSpace(text.Value) == "" {
+			blankLine = blankLine || strings.Count(text.Value, "\n") > 1
+			separated = true
+			continue
+		}
+
+		if block, ok := node.(*Block); ok && isConnectedBlock(block.Name) {
+			if _, ok := previous.(*Block); ok {
+				f.appendToLine(" ")
+				f.formatBlock(block, depth, true)
+				previous = node
+				blankLine = false
+				continue
+			}
+		}
+
+		adjacent := previous != nil && !separated && !endsWithSpace(previous) && !startsWithSpace(node)
+		if blankLine && !first {
+			f.lines = append(f.lines, "")
+		}
+		blankLine = false
+		separated = false
+		first = false
+		previous = node
+
+		if !adjacent {
+			f.formatNode(node, depth)
+			continue
+		}
+		// the node continues the last line of its previous sibling
+		start := len(f.lines)
+		f.formatNode(node, depth)
+		f.lines[start-1] += strings.TrimPrefix(f.lines[start], f.indent(depth))
+		f.lines = append(f.lines[:start], f.lines[start+1:]...)
+	}
+
+	if source != "" && cursor < len(source) {
+		if gap := strings.TrimSpace(source[cursor:]); gap != "" {
+			f.line(depth, gap)
+		}
+	}
+}
+
+// startsWithSpace reports whether a node is text starting with whitespace.
+func startsWithSpace(node Node) bool {
+	text := nodeText(node)
+	return text != "" && isTagSpace(text[0])
+}
+
+// endsWithSpace reports whether a node is text ending with whitespace.
+func endsWithSpace(node Node) bool {
+	text := nodeText(node)
+	return text != "" && isTagSpace(text[len(text)-1])
+}
+
+func nodeText(node Node) string {
+	switch n := node.(type) {
+	case *Text:
+		return n.Value
+	case *BoundText:
+		return n.Value.Source
+	}
+	return ""
+}
+
+func (f *formatter) formatNode(node Node, depth int) {
+	switch n := node.(type) {
+	case *Element:
+		f.formatElement(n, depth)
+	case *Template:
+		f.formatElement(n.Element(), depth)
+	case *Block:
+		f.formatBlock(n, depth, false)
+	case *Text:
+		f.line(depth, formatText(n))
+	case *BoundText:
+		f.line(depth, formatText(n))
+	case *Comment:
+		f.line(depth, "<!--"+n.Value+"-->")
+	}
+}
+
+// formatText returns text with collapsed whitespace, or "" for other nodes.
+func formatText(node Node) string {
+	switch n := node.(type) {
+	case *Text:
+		return strings.TrimSpace(escapeText(collapseWhitespace(n.Value)))
+	case *BoundText:
+		return strings.TrimSpace(formatBoundText(n))
+	default:
+		return ""
+	}
+}
+
+func formatBoundText(text *BoundText) string {
+	interpolation, ok := text.Value.Ast.(*ep.Interpolation)
+	if !ok {
+		return ep.Print(text.Value)
+	}
+
+	var out strings.Builder
+	for i, str := range interpolation.Strings {
+		out.WriteString(escapeText(collapseWhitespace(str)))
+		if i < len(interpolation.Expressions) {
+			out.WriteString("{{ " + ep.Print(interpolation.Expressions[i]) + " }}")
+		}
+	}
+	return out.String()
+}
+
+var whitespaceRegexp = regexp.MustCompile(`[ \t\n\r\f]+`)
+
+// collapseWhitespace replaces runs of whitespace by one space, so "a \n b"
+// becomes "a b" but leading and trailing whitespace is kept as a space.
+func collapseWhitespace(text string) string {
+	return whitespaceRegexp.ReplaceAllString(text, " ")
+}
+
+func (f *formatter) formatElement(element *Element, depth int) {
+	name := element.Name
+	lowerName := strings.ToLower(name)
+	void := isVoidElement(lowerName)
+	selfClosing := element.SelfClosing && len(element.Children) == 0
+	wrapped := f.formatStartTag(element, depth, selfClosing)
+
+	if void || selfClosing {
+		return
+	}
+
+	endTag := "</" + name + ">"
+
+	if isRawTextElement(lowerName) || lowerName == "pre" {
+		s := &serializer{rawText: !isEscapableRawTextElement(lowerName)}
+		s.serializeNodes(element.Children, 0, 0)
+		f.appendToLine(s.out.String() + endTag)
+		return
+	}
+
+	content, inline := formatInline(element.Children)
+	content = strings.TrimSpace(content)
+
+	if content == "" && inline {
+		f.appendToLine(endTag)
+		return
+	}
+
+	if inline && !wrapped && f.fits(f.lines[len(f.lines)-1]+content+endTag) {
+		f.appendToLine(content + endTag)
+		return
+	}
+
+	f.formatNodes(element.Children, depth+1, "")
+	f.line(depth, endTag)
+}
+
+// formatInline returns nodes on one line and whether they can be written on
+// one line, i.e. they are text and phrasing elements like <b>. The whitespace
+// between nodes is collapsed but kept, so "Hello <b>world</b>!" stays as is.
+func formatInline(nodes []Node) (string, bool) {
+	var content strings.Builder
+
+	for _, node := range nodes {
+		if template, ok := node.(*Template); ok {
+			node = template.Element()
+		}
+		switch n := node.(type) {
+		case *Text:
+			content.WriteString(escapeText(collapseWhitespace(n.Value)))
+		case *BoundText:
+			content.WriteString(formatBoundText(n))
+		case *Element:
+			name := strings.ToLower(n.Name)
+			if !isPhrasingElement(name) {
+				return "", false
+			}
+
+			attrs := formatAttributes(n)
+			content.WriteString("<" + n.Name)
+			for _, attr := range attrs {
+				content.WriteString(" " + attr)
+			}
+			if n.SelfClosing && len(n.Children) == 0 {
+				content.WriteString(" />")
+				continue
+			}
+			content.WriteString(">")
+			if isVoidElement(name) {
+				continue
+			}
+
+			children, ok := formatInline(n.Children)
+			if !ok {
+				return "", false
+			}
+			content.WriteString(children + "</" + n.Name + ">")
+		default:
+			return "", false
+		}
+	}
+
+	return content.String(), true
+}
+
+func isPhrasingElement(name string) bool {
+	switch name {
+	case "a", "abbr", "b", "bdi", "bdo", "br", "cite", "code", "data", "dfn", "em", "i", "img", "kbd", "label",
+		"mark", "q", "s", "samp", "small", "span", "strong", "sub", "sup", "time", "u", "var", "wbr":
+		return true
+	}
+	return false
+}
+
+// formatStartTag writes the start tag on one line, or with one attribute per
+// line if it's too long, and reports whether it was wrapped.
+func (f *formatter) formatStartTag(element *Element, depth int, selfClosing bool) bool {
+	attrs := formatAttributes(element)
+	end := ">"
+	if selfClosing {
+		end = " />"
+	}
+
+	tag := "<" + element.Name
+	for _, attr := range attrs {
+		tag += " " + attr
+	}
+	if len(attrs) <= 1 || f.fits(f.indent(depth)+tag+end) {
+		f.line(depth, tag+end)
+		return false
+	}
+
+	f.line(depth, "<"+element.Name)
+	for _, attr := range attrs {
+		f.line(depth+1, attr)
+	}
+	f.appendToLine(end)
+	return true
+}
+
+// formatAttributes returns the attributes of an element in the order
+// references, structural directives, inputs, outputs and plain attributes,
+// each group in source order.
+func formatAttributes(element *Element) []string {
+	attrs := elementAttributes(element)
+	sort.SliceStable(attrs, func(i, j int) bool {
+		return attributeGroup(attrs[i].key) < attributeGroup(attrs[j].key)
+	})
+
+	texts := make([]string, len(attrs))
+	for i, attr := range attrs {
+		texts[i] = attr.text
+		// only a plain expression like *ngIf="visible" is printed, the
+		// microsyntax of *ngFor="let item of items" doesn't parse as one
+		if strings.HasPrefix(attr.key, "*") && attr.value != "" {
+			texts[i] = attr.key + `="` + escapeAttribute(formatExpression(attr.value), '"', false) + `"`
+		}
+	}
+	return texts
+}
+
+func attributeGroup(key string) int {
+	switch key[0] {
+	case '#':
+		return 0
+	case '*':
+		return 1
+	case '[':
+		return 2
+	case '(':
+		return 3
+	default:
+		return 4
+	}
+}
+
+var formatParser = ep.NewParser()
+
+// formatExpression prints a binding expression, text which doesn't parse as
+// one is returned with collapsed whitespace.
+func formatExpression(text string) string {
+	ast := formatParser.ParseBinding(text, "", 0)
+	if len(ast.Errors) > 0 {
+		return strings.TrimSpace(collapseWhitespace(text))
+	}
+	return ep.Print(ast)
+}
+
+// isConnectedBlock reports whether a block continues the block before it.
+func isConnectedBlock(name string) bool {
+	switch name {
+	case "else", "else if", "empty", "placeholder", "loading", "error":
+		return true
+	}
+	return false
+}
+
+var forOfRegexp = regexp.MustCompile(`^(\S+)\s+of\s+([\s\S]*)$`)
+
+// formatBlock writes a block, connected blocks continue the line with the
+// end of the previous block.
+func (f *formatter) formatBlock(block *Block, depth int, connected bool) {
+	parameters := make([]string, len(block.Parameters))
+	for i, parameter := range block.Parameters {
+		parameters[i] = formatBlockParameter(block.Name, i, parameter.Expression)
+	}
+
+	start := "@" + block.Name
+	if len(parameters) > 0 {
+		start += " (" + strings.Join(parameters, "; ") + ")"
+	}
+	start += " {"
+
+	if connected {
+		f.appendToLine(start)
+	} else {
+		f.line(depth, start)
+	}
+
+	lines := len(f.lines)
+	f.formatNodes(block.Children, depth+1, "")
+
+	if len(f.lines) == lines {
+		f.appendToLine("}")
+	} else {
+		f.line(depth, "}")
+	}
+}
+
+// formatBlockParameter prints the expressions in a block parameter, i.e. the
+// condition of @if and @switch, the value of @case and the iterable and the
+// track expression of @for.
+func formatBlockParameter(blockName string, index int, parameter string) string {
+	switch {
+	case index == 0 && (blockName == "if" || blockName == "else if" || blockName == "switch" || blockName == "case"):
+		return formatExpression(parameter)
+	case index == 0 && blockName == "for":
+		if match := forOfRegexp.FindStringSubmatch(parameter); match != nil {
+			return match[1] + " of " + formatExpression(match[2])
+		}
+	case blockName == "for" && strings.HasPrefix(parameter, "track") && len(parameter) > 5 && isTagSpace(parameter[5]):
+		return "track " + formatExpression(parameter[6:])
+	}
+
+	return strings.TrimSpace(collapseWhitespace(parameter))
+}
@@ -0,0 +1,78 @@
+package template
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renderedText returns the text of nodes with the tags around it and
+// whitespace collapsed, the whitespace at the start and the end of the
+// children of an element or block is dropped like the indentation Format
+// adds there, and so is the whitespace before a connected block like @else.
+func renderedText(nodes []Node) string {
+	var out strings.Builder
+	for i, node := range nodes {
+		if text, ok := node.(*Text); ok && strings.TrimSpace(text.Value) == "" && i+1 < len(nodes) {
+			if block, ok := nodes[i+1].(*Block); ok && isConnectedBlock(block.Name) {
+				continue
+			}
+		}
+		if template, ok := node.(*Template); ok {
+			node = template.Element()
+		}
+		switch n := node.(type) {
+		case *Text:
+			out.WriteString(collapseWhitespace(n.Value))
+		case *BoundText:
+			out.WriteString(formatBoundText(n))
+		case *Element:
+			out.WriteString("<" + n.Name + ">" + renderedText(n.Children) + "</" + n.Name + ">")
+		case *Block:
+			out.WriteString("@" + n.Name + "{" + renderedText(n.Children) + "}")
+		case *Comment:
+			out.WriteString("<!---->")
+		}
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// TestFormatKeepsText formats templates, parses the result again and compares
+// the text, so Format doesn't add whitespace between nodes which had none.
+func TestFormatKeepsText(t *testing.T) {
+	sources := []string{
+		`<div>Hello<span>x</span><p>y</p></div>`,
+		`<b>a</b><i>b</i>`,
+		`<b>a</b> <i>b</i>`,
+		`<div>a<div><p>b</p><p>c</p></div>d</div>`,
+		`<ul><li>a</li><li>b</li></ul>`,
+		`<p>{{ a }}<b>b</b>{{c}}</p><!--x-->text`,
+		"<div>\n  <p>a</p>\n\n  <p>b</p>\n</div>",
+		`@if (a) {<b>x</b>}@else {y}<i>z</i>`,
+		`<section>a<ng-template [ngIf]="x"><p>b</p></ng-template><p *ngIf="y">c</p></section>`,
+	}
+	paths, err := filepath.Glob("testdata/angular/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range append(paths, benchmarkTemplates...) {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, string(src))
+	}
+
+	for _, src := range sources {
+		formatted, err := FormatTemplate([]byte(src), DefaultFormatOptions)
+		if err != nil {
+			t.Errorf("FormatTemplate(%.40q): %v", src, err)
+			continue
+		}
+		want := renderedText(Parse(strings.NewReader(src), "").Nodes)
+		if got := renderedText(Parse(strings.NewReader(string(formatted)), "").Nodes); got != want {
+			t.Errorf("FormatTemplate(%.40q) changed the text\n%s\nto\n%s\nformatted:\n%s", src, want, got, formatted)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package template
+
+import (
+	"io"
+	"strings"
+
+	"github.com/irustm/ng-template-parser/ep"
+	"golang.org/x/net/html"
+)
+
+// TextEdit replaces Length bytes at Offset of a template with Replacement.
+type TextEdit struct {
+	Offset      int
+	Length      int
+	Replacement string
+}
+
+// ParsedTemplate is a template kept parsed while it is edited, e.g. in an
+// editor, see ApplyEdit.
+type ParsedTemplate struct {
+	Root     Root
+	Location string
+
+	// unclosed is set when an element or block has no end: an element takes
+	// the rest of the template and the `}` after a block may end a block which
+	// is not its parent.
+	unclosed bool
+}
+
+// NewParsedTemplate parses a template, location is used in expression errors.
+func NewParsedTemplate(src string, location string) *ParsedTemplate {
+	root := Parse(strings.NewReader(src), location)
+	return &ParsedTemplate{Root: root, Location: location, unclosed: hasUnclosed(root.Nodes)}
+}
+
+// ApplyEdit returns the template with edit applied and the span of the new
+// source which was parsed again. Only the children of the innermost element
+// or block around the edit which overlap it are parsed again: the nodes
+// before them are reused and the ones after them are moved in place, only
+// the elements and blocks around the edit are copied. When the edit changes
+// how the text around it is parsed, e.g. it opens a tag or a block which is
+// not closed in those children, the whole template is parsed again. The
+// result equals the parse of the edited source and takes over the nodes of
+// t, so t must not be used afterwards.
+func (t *ParsedTemplate) ApplyEdit(edit TextEdit) (*ParsedTemplate, ep.AbsoluteSourceSpan) {
+	old := t.Root.Source
+
+	start := edit.Offset
+	if start < 0 {
+		start = 0
+	} else if start > len(old) {
+		start = len(old)
+	}
+	end := start + edit.Length
+	if end < start {
+		end = start
+	} else if end > len(old) {
+		end = len(old)
+	}
+
+	src := old[:start] + edit.Replacement + old[end:]
+	if parsed, changed, ok := t.reparse(src, start, end, len(edit.Replacement)-(end-start)); ok {
+		return parsed, changed
+	}
+	return NewParsedTemplate(src, t.Location), ep.AbsoluteSourceSpan{Start: 0, End: len(src)}
+}
+
+// reparse parses the children around the edit of old[start:end] again, src
+// is the edited source and delta the change of its length.
+func (t *ParsedTemplate) reparse(src string, start, end, delta int) (*ParsedTemplate, ep.AbsoluteSourceSpan, bool) {
+	if t.unclosed {
+		return nil, ep.AbsoluteSourceSpan{}, false
+	}
+
+	// find the innermost element or block whose content contains the edit,
+	// indexes are the positions of the ancestors in their parents
+	nodes := t.Root.Nodes
+	var ancestors []Node
+	var indexes []int
+	contentStart, contentEnd := 0, len(t.Root.Source)
+	blockDepth := 0
+descend:
+	for {
+		for i, node := range nodes {
+			if content, ok := contentSpan(node); ok && content.Start <= start && end <= content.End {
+				ancestors = append(ancestors, node)
+				indexes = append(indexes, i)
+				nodes = Children(node)
+				contentStart, contentEnd = content.Start, content.End
+				if _, ok := node.(*Block); ok {
+					blockDepth++
+				}
+				continue descend
+			}
+		}
+		break
+	}
+
+	// the children overlapping the edit are parsed again with the text
+	// touching it, nodes[first:last], and a node at the end of the template,
+	// which may be an unfinished comment
+	first := 0
+	for first < len(nodes) && keptBefore(nodes[first], start) && nodes[first].GetSourceSpan().End < len(t.Root.Source) {
+		first++
+	}
+	last := first
+	for last < len(nodes) && !keptAfter(nodes[last], end) {
+		last++
+	}
+
+	regionStart, regionEnd := contentStart, contentEnd
+	if first > 0 {
+		regionStart = nodes[first-1].GetSourceSpan().End
+	}
+	if last < len(nodes) {
+		regionEnd = nodes[last].GetSourceSpan().Start
+	}
+
+	fragment, fragmentErrors, ok := parseFragment(src, regionStart, regionEnd+delta, t.Location, blockDepth, len(ancestors) > 0)
+	if !ok {
+		return nil, ep.AbsoluteSourceSpan{}, false
+	}
+
+	// the nodes after the edit are moved once nothing can fail anymore
+	var children []Node
+	children = append(children, nodes[:first]...)
+	children = append(children, fragment...)
+	children = append(children, nodes[last:]...)
+	shifted := [][]Node{nodes[last:]}
+
+	// text next to text would have been one text node
+	for _, seam := range []int{first, first + len(fragment)} {
+		if seam > 0 && seam < len(children) && isTextNode(children[seam-1]) && isTextNode(children[seam]) {
+			return nil, ep.AbsoluteSourceSpan{}, false
+		}
+	}
+
+	// the errors are in the order of the nodes, the ones of the reparsed
+	// nodes are replaced
+	errorsBefore := 0
+	for _, node := range nodes[:first] {
+		errorsBefore += countErrors(node)
+	}
+	errorsReplaced := 0
+	for _, node := range nodes[first:last] {
+		errorsReplaced += countErrors(node)
+	}
+
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		parent := t.Root.Nodes
+		if i > 0 {
+			parent = Children(ancestors[i-1])
+		}
+		index := indexes[i]
+
+		for _, node := range parent[:index] {
+			errorsBefore += countErrors(node)
+		}
+		errorsBefore += ownErrors(ancestors[i])
+
+		node := replaceChildren(ancestors[i], children, delta)
+		children = nil
+		children = append(children, parent[:index]...)
+		children = append(children, node)
+		children = append(children, parent[index+1:]...)
+		shifted = append(shifted, parent[index+1:])
+	}
+
+	if errorsBefore+errorsReplaced > len(t.Root.Errors) {
+		return nil, ep.AbsoluteSourceSpan{}, false
+	}
+	var errors []ep.ParserError
+	errors = append(errors, t.Root.Errors[:errorsBefore]...)
+	errors = append(errors, fragmentErrors...)
+	errors = append(errors, t.Root.Errors[errorsBefore+errorsReplaced:]...)
+
+	if delta != 0 {
+		for _, nodes := range shifted {
+			shiftNodes(nodes, delta)
+		}
+	}
+
+	parsed := &ParsedTemplate{
+		Root:     Root{Nodes: children, Errors: errors, Source: src},
+		Location: t.Location,
+	}
+	return parsed, ep.AbsoluteSourceSpan{Start: regionStart, End: regionEnd + delta}, true
+}
+
+// parseFragment parses src[start:end] as the children of an element or block
+// in blockDepth blocks, or as top level nodes. It fails when the fragment
+// may parse differently in the whole template: when it closes an element or
+// block around it, leaves one of its own open, or ends in a token which could
+// continue in the text after it.
+func parseFragment(src string, start, end int, location string, blockDepth int, nested bool) ([]Node, []ep.ParserError, bool) {
+	tokenizer := newTemplateTokenizer(strings.NewReader(src[start:end]))
+	tokenizer.location = location
+	tokenizer.offset = start
+	tokenizer.blockDepth = blockDepth
+
+	var nodes []Node
+	for tokenType := tokenizer.Next(); tokenType != html.ErrorToken; tokenType = tokenizer.Token().Type {
+		if nested && (tokenType == html.EndTagToken || tokenType == blockEndToken) {
+			return nil, nil, false
+		}
+		nodes = tokenizer.appendChild(nodes, walk(tokenizer, tokenizer.Token()))
+	}
+	// html.Tokenizer drops an unfinished tag at the end, a `}` dropped in an
+	// element ends a block around the fragment
+	if tokenizer.Err() != io.EOF || tokenizer.start != end || tokenizer.blockDepth != blockDepth {
+		return nil, nil, false
+	}
+
+	if hasUnclosed(nodes) {
+		return nil, nil, false
+	}
+
+	// a doctype or stray end tag at the end isn't a node to check
+	if len(nodes) == 0 && end > start || len(nodes) > 0 && nodes[len(nodes)-1].GetSourceSpan().End != end {
+		return nil, nil, false
+	}
+
+	if len(nodes) > 0 {
+		lastNode := nodes[len(nodes)-1]
+		span := lastNode.GetSourceSpan()
+		raw := src[span.Start:span.End]
+		switch lastNode.(type) {
+		case *Text, *BoundText:
+			// text ends at a tag in the whole template too, an interpolation
+			// or block start can only continue in text after it
+			followedByTag := end == len(src) || src[end] == '<'
+			if strings.Contains(raw, "<") || !followedByTag && (strings.Contains(raw, "@") || strings.Contains(raw, "{{")) {
+				return nil, nil, false
+			}
+		case *Comment:
+			// html.Tokenizer reads bogus and empty comments differently
+			// at the end of the input
+			if len(raw) < len("<!---->") || !strings.HasPrefix(raw, "<!--") || !strings.HasSuffix(raw, "-->") {
+				return nil, nil, false
+			}
+		case *Element, *Template:
+			if !strings.HasSuffix(raw, ">") {
+				return nil, nil, false
+			}
+		}
+	}
+
+	return nodes, tokenizer.errors, true
+}
+
+// contentSpan returns the span between the start and the end of a closed
+// element, template or block whose content is parsed. The content of the
+// template of a structural directive is the one of its element.
+func contentSpan(node Node) (ep.AbsoluteSourceSpan, bool) {
+	switch n := node.(type) {
+	case *Element:
+		if n.EndSourceSpan != nil && !isRawTextElement(strings.ToLower(n.Name)) {
+			return ep.AbsoluteSourceSpan{Start: n.StartSourceSpan.End, End: n.EndSourceSpan.Start}, true
+		}
+	case *Template:
+		if n.TemplateAttr != nil {
+			if len(n.Children) == 1 {
+				return contentSpan(n.Children[0])
+			}
+		} else if n.EndSourceSpan != nil {
+			return ep.AbsoluteSourceSpan{Start: n.StartSourceSpan.End, End: n.EndSourceSpan.Start}, true
+		}
+	case *Block:
+		if n.EndSourceSpan != nil {
+			return ep.AbsoluteSourceSpan{Start: n.StartSourceSpan.End, End: n.EndSourceSpan.Start}, true
+		}
+	}
+	return ep.AbsoluteSourceSpan{}, false
+}
+
+// keptBefore reports whether a node ends before offset, text touching it may
+// continue.
+func keptBefore(node Node, offset int) bool {
+	span := node.GetSourceSpan()
+	return span.End < offset || span.End == offset && !isTextNode(node)
+}
+
+// keptAfter reports whether a node starts after offset, text touching it may
+// continue.
+func keptAfter(node Node, offset int) bool {
+	span := node.GetSourceSpan()
+	return span.Start > offset || span.Start == offset && !isTextNode(node)
+}
+
+func isTextNode(node Node) bool {
+	switch node.(type) {
+	case *Text, *BoundText:
+		return true
+	}
+	return false
+}
+
+// hasUnclosed reports whether an element, template or block in nodes has no
+// end.
+func hasUnclosed(nodes []Node) bool {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Element:
+			if n.EndSourceSpan == nil && !n.SelfClosing && !isVoidElement(strings.ToLower(n.Name)) {
+				return true
+			}
+		case *Template:
+			if n.EndSourceSpan == nil && !n.SelfClosing && !isVoidElement(strings.ToLower(n.TagName)) {
+				return true
+			}
+		case *Block:
+			if n.EndSourceSpan == nil {
+				return true
+			}
+		}
+		if hasUnclosed(Children(node)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownErrors counts the errors of the expressions of a node without its
+// children.
+func ownErrors(node Node) int {
+	switch n := node.(type) {
+	case *Element:
+		return bindingErrors(n.Inputs, n.Outputs)
+	case *Template:
+		return bindingErrors(n.Inputs, n.Outputs)
+	case *BoundText:
+		if n.Value != nil {
+			return len(n.Value.Errors)
+		}
+	}
+	return 0
+}
+
+func bindingErrors(inputs []BoundAttribute, outputs []BoundEvent) int {
+	count := 0
+	for _, input := range inputs {
+		if input.Value != nil {
+			count += len(input.Value.Errors)
+		}
+	}
+	for _, output := range outputs {
+		if output.Handler != nil {
+			count += len(output.Handler.Errors)
+		}
+	}
+	return count
+}
+
+func countErrors(node Node) int {
+	count := ownErrors(node)
+	for _, child := range Children(node) {
+		count += countErrors(child)
+	}
+	return count
+}
+
+// replaceChildren returns a copy of an element, template or block with new
+// children, its end moved by delta.
+func replaceChildren(node Node, children []Node, delta int) Node {
+	switch n := node.(type) {
+	case *Element:
+		element := *n
+		element.Children = children
+		element.SourceSpan.End += delta
+		element.EndSourceSpan = movedSpan(n.EndSourceSpan, delta)
+		return &element
+	case *Template:
+		template := *n
+		template.Children = children
+		template.SourceSpan.End += delta
+		template.EndSourceSpan = movedSpan(n.EndSourceSpan, delta)
+		return &template
+	case *Block:
+		block := *n
+		block.Children = children
+		block.SourceSpan.End += delta
+		block.EndSourceSpan = movedSpan(n.EndSourceSpan, delta)
+		return &block
+	}
+	return node
+}
+
+// shiftNode moves a node and its children by delta in place.
+func shiftNode(node Node, delta int) {
+	switch n := node.(type) {
+	case *Element:
+		shiftSpan(&n.SourceSpan, delta)
+		shiftSpan(&n.StartSourceSpan, delta)
+		shiftOptionalSpan(n.EndSourceSpan, delta)
+		shiftTagAttributes(n.Attributes, n.Inputs, n.Outputs, n.References, delta)
+		shiftNodes(n.Children, delta)
+	case *Template:
+		shiftSpan(&n.SourceSpan, delta)
+		shiftSpan(&n.StartSourceSpan, delta)
+		shiftOptionalSpan(n.EndSourceSpan, delta)
+		if n.TemplateAttr != nil {
+			shiftTextAttribute(n.TemplateAttr, delta)
+		}
+		shiftTagAttributes(n.Attributes, n.Inputs, n.Outputs, n.References, delta)
+		shiftNodes(n.Children, delta)
+	case *Text:
+		shiftSpan(&n.SourceSpan, delta)
+	case *BoundText:
+		shiftExpression(n.Value, delta)
+		shiftSpan(&n.SourceSpan, delta)
+	case *Comment:
+		shiftSpan(&n.SourceSpan, delta)
+	case *Block:
+		shiftSpan(&n.SourceSpan, delta)
+		shiftSpan(&n.StartSourceSpan, delta)
+		shiftOptionalSpan(n.EndSourceSpan, delta)
+		for i := range n.Parameters {
+			shiftSpan(&n.Parameters[i].SourceSpan, delta)
+		}
+		shiftNodes(n.Children, delta)
+	}
+}
+
+// shiftTagAttributes moves the attributes, bindings and references of an
+// element or template by delta in place.
+func shiftTagAttributes(attributes []TextAttribute, inputs []BoundAttribute, outputs []BoundEvent, references []Reference, delta int) {
+	for i := range attributes {
+		shiftTextAttribute(&attributes[i], delta)
+	}
+	for i := range inputs {
+		input := &inputs[i]
+		shiftExpression(input.Value, delta)
+		shiftSpan(&input.SourceSpan, delta)
+		shiftSpan(&input.KeySpan, delta)
+		shiftOptionalSpan(input.ValueSpan, delta)
+	}
+	for i := range outputs {
+		output := &outputs[i]
+		shiftExpression(output.Handler, delta)
+		shiftSpan(&output.SourceSpan, delta)
+		shiftSpan(&output.KeySpan, delta)
+		shiftOptionalSpan(output.ValueSpan, delta)
+	}
+	for i := range references {
+		ref := &references[i]
+		shiftSpan(&ref.SourceSpan, delta)
+		shiftSpan(&ref.KeySpan, delta)
+		shiftOptionalSpan(ref.ValueSpan, delta)
+	}
+}
+
+func shiftTextAttribute(attr *TextAttribute, delta int) {
+	shiftSpan(&attr.SourceSpan, delta)
+	shiftSpan(&attr.KeySpan, delta)
+	shiftOptionalSpan(attr.ValueSpan, delta)
+}
+
+func shiftNodes(nodes []Node, delta int) {
+	for _, node := range nodes {
+		shiftNode(node, delta)
+	}
+}
+
+func shiftExpression(ast *ep.ASTWithSource, delta int) {
+	if ast != nil {
+		ep.ShiftAST(ast, delta)
+	}
+}
+
+// shiftSpan keeps zero spans, the parser leaves the KeySpan of attributes it
+// can't find in the raw tag unset.
+func shiftSpan(span *ep.AbsoluteSourceSpan, delta int) {
+	if *span != (ep.AbsoluteSourceSpan{}) {
+		span.Start += delta
+		span.End += delta
+	}
+}
+
+func shiftOptionalSpan(span *ep.AbsoluteSourceSpan, delta int) {
+	if span != nil {
+		shiftSpan(span, delta)
+	}
+}
+
+// movedSpan returns a copy of a span moved by delta.
+func movedSpan(span *ep.AbsoluteSourceSpan, delta int) *ep.AbsoluteSourceSpan {
+	if span == nil {
+		return nil
+	}
+	moved := *span
+	shiftSpan(&moved, delta)
+	return &moved
+}
@@ -0,0 +1,134 @@
+package template
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var incrementalTemplates = []string{
+	"<ul>\n  <li *ngFor=\"let item of items; index as i\">{{ i }}: {{ item.name | uppercase }}</li>\n</ul>\n<p [title]=\"title\" (click)=\"select(item)\">text</p>\n",
+	"@if (user.loggedIn) {\n  <p>Hello {{ user.name }}</p>\n} @else {\n  <button (click)=\"login()\">Log in</button>\n}\n@for (item of items; track item.id) {\n  <span>{{ item }}</span>\n} @empty {\n  none\n}\n",
+	"<div>\n  <!-- a comment -->\n  <input #name [value]=\"value\" (input)=\"value = name.value\">\n  <br>\n  @switch (mode) {\n    @case ('a') { <b>a</b> }\n    @default { <i>{{ 1 + 2 }}</i> }\n  }\n</div>\n",
+}
+
+// editReplacements are inserted by randomEdit besides deletions and copies of
+// the template itself.
+var editReplacements = []string{
+	"x", " ", "\n", "<", ">", "/", "\"", "@", "{", "}", "{{", "}}", "{{ a }}",
+	"<b>", "</b>", "<br>", "<div [x]=\"y\" (click)=\"f()\">", "</div>", "<!-- c -->", "<!--",
+	"@if (a) {", "@else {", "@for (item of items; track item) {", "<script>", "</script>",
+}
+
+func randomEdit(random *rand.Rand, src string) TextEdit {
+	edit := TextEdit{Offset: random.Intn(len(src) + 1)}
+	switch random.Intn(3) {
+	case 0:
+		edit.Length = random.Intn(20)
+	case 1:
+		start := random.Intn(len(src) + 1)
+		end := start + random.Intn(20)
+		if end > len(src) {
+			end = len(src)
+		}
+		edit.Replacement = src[start:end]
+	default:
+		edit.Length = random.Intn(3)
+		edit.Replacement = editReplacements[random.Intn(len(editReplacements))]
+	}
+	return edit
+}
+
+// TestApplyEdit applies random edits one after another and compares every
+// result with a full parse of the edited source.
+func TestApplyEdit(t *testing.T) {
+	sources := append([]string(nil), incrementalTemplates...)
+	for _, path := range []string{"../template.html", "../bin/template.html", "testdata/angular/template.html"} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, string(data))
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for _, src := range sources {
+		var template *ParsedTemplate
+		for i := 0; i < 1000; i++ {
+			if i%10 == 0 {
+				template = NewParsedTemplate(src, "test")
+			}
+			edit := randomEdit(random, template.Root.Source)
+			source := template.Root.Source
+
+			edited, changed := template.ApplyEdit(edit)
+			expected := NewParsedTemplate(edited.Root.Source, "test")
+			if !reflect.DeepEqual(edited.Root, expected.Root) {
+				t.Fatalf("edit %+v of %q, parsed again in %+v:\n%s\nwant\n%s",
+					edit, source, changed, encodeRoot(t, edited.Root), encodeRoot(t, expected.Root))
+			}
+			template = edited
+		}
+	}
+}
+
+// TestApplyEditReusesNodes checks that an edit in an element only parses
+// its content again and keeps the nodes around it.
+func TestApplyEditReusesNodes(t *testing.T) {
+	src := "<p>{{ a }}</p><div>x</div><p [title]=\"b\">{{ c }}</p>"
+	template := NewParsedTemplate(src, "test")
+	before := template.Root.Nodes[0].(*Element)
+	after := template.Root.Nodes[2].(*Element)
+	value := after.Inputs[0].Value
+	text := after.Children[0].(*BoundText).Value
+
+	offset := strings.Index(src, "x")
+	edited, changed := template.ApplyEdit(TextEdit{Offset: offset, Length: 1, Replacement: "yz"})
+	if changed.Start != offset || changed.End != offset+2 {
+		t.Errorf("parsed again in %+v, want the content of the div", changed)
+	}
+
+	nodes := edited.Root.Nodes
+	if nodes[0] != before || nodes[2] != after {
+		t.Errorf("the elements around the edit are copies")
+	}
+	if after.Inputs[0].Value != value || after.Children[0].(*BoundText).Value != text {
+		t.Errorf("the expressions after the edit are copies")
+	}
+
+	expected := NewParsedTemplate(edited.Root.Source, "test")
+	if !reflect.DeepEqual(edited.Root, expected.Root) {
+		t.Errorf("got\n%s\nwant\n%s", encodeRoot(t, edited.Root), encodeRoot(t, expected.Root))
+	}
+}
+
+func encodeRoot(t *testing.T, root Root) []byte {
+	data, err := json.MarshalIndent(root, "", " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// BenchmarkApplyEdit types and deletes a character in the middle of the
+// templates.
+func BenchmarkApplyEdit(b *testing.B) {
+	benchmarkEach(b, func(b *testing.B, root Root) {
+		offset := len(root.Source) / 2
+		if i := strings.Index(root.Source[offset:], "\n"); i != -1 {
+			offset += i
+		}
+		template := NewParsedTemplate(root.Source, "")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			edit := TextEdit{Offset: offset, Replacement: "x"}
+			if i%2 == 1 {
+				edit = TextEdit{Offset: offset, Length: 1}
+			}
+			template, _ = template.ApplyEdit(edit)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+// Nodes are encoded as JSON objects with their fields and a "kind" naming
+// their type, e.g. {"kind":"Element","Name":"div",...}, like the expressions
+// of the ep package, so a Root can be decoded again with json.Unmarshal.
+
+var nodeKinds = map[string]func() Node{
+	"Element":   func() Node { return &Element{} },
+	"Template":  func() Node { return &Template{} },
+	"Text":      func() Node { return &Text{} },
+	"BoundText": func() Node { return &BoundText{} },
+	"Comment":   func() Node { return &Comment{} },
+	"Block":     func() Node { return &Block{} },
+}
+
+// UnmarshalNode decodes a node encoded by the MarshalJSON method of its type,
+// null is decoded as nil.
+func UnmarshalNode(data []byte) (Node, error) {
+	var tagged struct {
+		Kind *string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return nil, err
+	}
+	if tagged.Kind == nil {
+		if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("node without kind")
+	}
+
+	create, ok := nodeKinds[*tagged.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown node kind %q", *tagged.Kind)
+	}
+	node := create()
+	if err := json.Unmarshal(data, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func unmarshalNodes(items []json.RawMessage) ([]Node, error) {
+	if items == nil {
+		return nil, nil
+	}
+
+	nodes := make([]Node, len(items))
+	for i, item := range items {
+		node, err := UnmarshalNode(item)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = node
+	}
+	return nodes, nil
+}
+
+func (n *Element) MarshalJSON() ([]byte, error) {
+	type element Element
+	return ep.MarshalKind("Element", (*element)(n))
+}
+
+func (n *Element) UnmarshalJSON(data []byte) error {
+	type element Element
+	decoded := struct {
+		*element
+		Children []json.RawMessage
+	}{element: (*element)(n)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	children, err := unmarshalNodes(decoded.Children)
+	n.Children = children
+	return err
+}
+
+func (n *Template) MarshalJSON() ([]byte, error) {
+	type template Template
+	return ep.MarshalKind("Template", (*template)(n))
+}
+
+func (n *Template) UnmarshalJSON(data []byte) error {
+	type template Template
+	decoded := struct {
+		*template
+		Children []json.RawMessage
+	}{template: (*template)(n)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	children, err := unmarshalNodes(decoded.Children)
+	n.Children = children
+	return err
+}
+
+func (n *Text) MarshalJSON() ([]byte, error) {
+	type text Text
+	return ep.MarshalKind("Text", (*text)(n))
+}
+
+func (n *Text) UnmarshalJSON(data []byte) error {
+	type text Text
+	return json.Unmarshal(data, (*text)(n))
+}
+
+func (n *BoundText) MarshalJSON() ([]byte, error) {
+	type boundText BoundText
+	return ep.MarshalKind("BoundText", (*boundText)(n))
+}
+
+func (n *BoundText) UnmarshalJSON(data []byte) error {
+	type boundText BoundText
+	return json.Unmarshal(data, (*boundText)(n))
+}
+
+func (n *Comment) MarshalJSON() ([]byte, error) {
+	type comment Comment
+	return ep.MarshalKind("Comment", (*comment)(n))
+}
+
+func (n *Comment) UnmarshalJSON(data []byte) error {
+	type comment Comment
+	return json.Unmarshal(data, (*comment)(n))
+}
+
+func (n *Block) MarshalJSON() ([]byte, error) {
+	type block Block
+	return ep.MarshalKind("Block", (*block)(n))
+}
+
+func (n *Block) UnmarshalJSON(data []byte) error {
+	type block Block
+	decoded := struct {
+		*block
+		Children []json.RawMessage
+	}{block: (*block)(n)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	children, err := unmarshalNodes(decoded.Children)
+	n.Children = children
+	return err
+}
+
+func (r *Root) UnmarshalJSON(data []byte) error {
+	type root Root
+	decoded := struct {
+		*root
+		Nodes []json.RawMessage
+	}{root: (*root)(r)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	nodes, err := unmarshalNodes(decoded.Nodes)
+	r.Nodes = nodes
+	return err
+}
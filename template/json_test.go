@@ -0,0 +1,60 @@
+package template
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		`<div [title]="a ?? 'b'" (click)="go($event)" #ref>{{ 1e400 }} {{ -1e400 }} text</div>`,
+		`@if (user; as u) { <p *ngFor="let i of items">{{ i | json }}</p> } @else { <!-- none --> }`,
+		`<ng-template #row let-item [ngIf]="a"><li *ngIf="item" *ngFor="let i of b">{{ i }}</li></ng-template>`,
+	} {
+		root := Parse(strings.NewReader(src), "")
+		data, err := json.Marshal(root)
+		if err != nil {
+			t.Errorf("json.Marshal(%q): %v", src, err)
+			continue
+		}
+
+		var decoded Root
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Errorf("json.Unmarshal(%q): %v", src, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, root) {
+			t.Errorf("JSON of %q doesn't decode into the same tree:\n%s", src, data)
+		}
+	}
+}
+
+func TestJSONNonFiniteNumber(t *testing.T) {
+	for _, value := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+		data, err := json.Marshal(&ep.LiteralPrimitive{Value: value})
+		if err != nil {
+			t.Errorf("json.Marshal(%v): %v", value, err)
+			continue
+		}
+
+		var decoded ep.LiteralPrimitive
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Errorf("json.Unmarshal(%s): %v", data, err)
+			continue
+		}
+		number, ok := decoded.Value.(float64)
+		if !ok || (number != value && !(math.IsNaN(number) && math.IsNaN(value))) {
+			t.Errorf("%v decoded as %v from %s", value, decoded.Value, data)
+		}
+	}
+
+	root := Parse(strings.NewReader("{{ 1e400 }}"), "")
+	if _, err := MarshalAngularJSON(root, "", nil); err != nil {
+		t.Errorf("MarshalAngularJSON: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package template
+
+import (
+	"strings"
+
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+// ConditionWarning is a condition in a template whose value is known without
+// running it, like `*ngIf="false"` or the `true` in `{{ true ? a : b }}`.
+type ConditionWarning struct {
+	// Element is the element of the binding, or the block like `@if`.
+	Element    string
+	Message    string
+	SourceSpan ep.AbsoluteSourceSpan
+}
+
+// CheckConstantConditions folds the expressions of a template with ep.Fold and
+// reports the conditions which are always true or false: the values of ngIf
+// and @if, and the conditions within any expression.
+func CheckConstantConditions(root Root) []ConditionWarning {
+	var warnings []ConditionWarning
+	parser := ep.NewParser()
+
+	for _, node := range root.Nodes {
+		warnings = checkConstantConditions(node, "", parser, warnings)
+	}
+
+	return warnings
+}
+
+func checkConstantConditions(node Node, parent string, parser *ep.Parser, warnings []ConditionWarning) []ConditionWarning {
+	report := func(element string, ast ep.AST, condition bool) {
+		fold := ep.Fold
+		if condition {
+			fold = ep.FoldCondition
+		}
+		_, foldWarnings := fold(ast)
+		for _, warning := range foldWarnings {
+			warnings = append(warnings, ConditionWarning{Element: element, Message: warning.Message, SourceSpan: warning.SourceSpan})
+		}
+	}
+
+	// the value of ngIf is a condition, the other bindings of a structural
+	// directive are not
+	structuralDirective := func(element string, attr TextAttribute) {
+		bindings, errors := ParseTemplateBindings(attr.Name[1:], attr.Value, "", attributeValueOffset(attr), parser)
+		if len(errors) > 0 {
+			return
+		}
+		for _, binding := range bindings {
+			if binding.Value != nil {
+				report(element, binding.Value, binding.Key == "ngIf")
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *BoundText:
+		report(parent, n.Value, false)
+	case *Block:
+		// the first parameter of @if, @else if, @switch and @case is an
+		// expression, the others are aliases and @for parameters
+		if len(n.Parameters) > 0 && (n.Name == "if" || n.Name == "else if" || n.Name == "switch" || n.Name == "case") {
+			parameter := n.Parameters[0]
+			ast := parser.ParseBinding(parameter.Expression, "", parameter.SourceSpan.Start)
+			if len(ast.Errors) == 0 {
+				report("@"+n.Name, ast, n.Name == "if" || n.Name == "else if")
+			}
+		}
+		for _, child := range n.Children {
+			warnings = checkConstantConditions(child, parent, parser, warnings)
+		}
+	case *Template:
+		// the element of a structural directive reports its own bindings
+		if n.TemplateAttr != nil {
+			structuralDirective(n.TagName, *n.TemplateAttr)
+			for _, child := range n.Children {
+				warnings = checkConstantConditions(child, parent, parser, warnings)
+			}
+			break
+		}
+		for _, input := range n.Inputs {
+			report(n.TagName, input.Value, input.Name == "ngIf")
+		}
+		for _, output := range n.Outputs {
+			report(n.TagName, output.Handler, false)
+		}
+		for _, child := range n.Children {
+			warnings = checkConstantConditions(child, n.TagName, parser, warnings)
+		}
+	case *Element:
+		for _, attr := range n.Attributes {
+			if strings.HasPrefix(attr.Name, "*") {
+				structuralDirective(n.Name, attr)
+			}
+		}
+		for _, input := range n.Inputs {
+			report(n.Name, input.Value, input.Name == "ngIf")
+		}
+		for _, output := range n.Outputs {
+			report(n.Name, output.Handler, false)
+		}
+		for _, child := range n.Children {
+			warnings = checkConstantConditions(child, n.Name, parser, warnings)
+		}
+	}
+
+	return warnings
+}
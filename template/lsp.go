@@ -0,0 +1,775 @@
+package template
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/irustm/ng-template-parser/chars"
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+// ServeLSP runs a Language Server Protocol server for templates: it reads
+// requests from r and writes responses and notifications to w until the exit
+// notification or the end of r. It publishes the expression errors of open
+// documents as diagnostics and answers document symbol, folding range,
+// selection range and semantic token requests. Edits are parsed with
+// ParsedTemplate.ApplyEdit, so large templates stay fast. Tests can talk to
+// it in-process through an io.Pipe.
+func ServeLSP(r io.Reader, w io.Writer) error {
+	server := &lspServer{
+		reader:    bufio.NewReader(r),
+		writer:    w,
+		documents: map[string]*lspDocument{},
+	}
+	return server.serve()
+}
+
+// JSON-RPC error codes
+const (
+	lspParseError     = -32700
+	lspInvalidParams  = -32602
+	lspMethodNotFound = -32601
+	lspInternalError  = -32603
+)
+
+// the token types and modifiers of the semantic tokens legend, in the order
+// of their numbers and bits
+var (
+	lspTokenTypes     = []string{"class", "property", "decorator", "event", "variable", "function", "keyword", "string", "number", "operator"}
+	lspTokenModifiers = []string{"declaration", "controlFlow"}
+)
+
+const (
+	lspTokenClass = iota
+	lspTokenProperty
+	lspTokenDecorator
+	lspTokenEvent
+	lspTokenVariable
+	lspTokenFunction
+	lspTokenKeyword
+	lspTokenString
+	lspTokenNumber
+	lspTokenOperator
+)
+
+const (
+	lspModifierDeclaration = 1 << iota
+	lspModifierControlFlow
+)
+
+// symbol kinds of the protocol
+const (
+	lspSymbolNamespace = 3
+	lspSymbolField     = 8
+	lspSymbolVariable  = 13
+)
+
+type lspRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type lspResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+type lspErrorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *lspError       `json:"error"`
+}
+
+type lspNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail,omitempty"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children,omitempty"`
+}
+
+type lspFoldingRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Kind      string `json:"kind,omitempty"`
+}
+
+type lspSelectionRange struct {
+	Range  lspRange           `json:"range"`
+	Parent *lspSelectionRange `json:"parent,omitempty"`
+}
+
+type lspServer struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	documents map[string]*lspDocument
+	// notifications are written after the response to the current message
+	notifications []lspNotification
+}
+
+func (s *lspServer) serve() error {
+	for {
+		data, err := readLSPMessage(s.reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var request lspRequest
+		if err := json.Unmarshal(data, &request); err != nil {
+			if err := s.respondError(json.RawMessage("null"), &lspError{Code: lspParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if request.Method == "exit" {
+			return nil
+		}
+
+		result, rpcErr := s.recoverHandle(request.Method, request.Params)
+		switch {
+		case len(request.ID) == 0:
+			// notifications have no response, the client logs their failure
+			if rpcErr != nil && rpcErr.Code == lspInternalError {
+				s.notify("window/logMessage", map[string]interface{}{"type": 1, "message": rpcErr.Message})
+			}
+		case rpcErr != nil:
+			err = s.respondError(request.ID, rpcErr)
+		default:
+			err = s.write(lspResponse{JSONRPC: "2.0", ID: request.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// recoverHandle handles a message like handle but turns a panic into an
+// internal error, so a bug in the parser fails one request and not the
+// server.
+func (s *lspServer) recoverHandle(method string, params json.RawMessage) (result interface{}, rpcErr *lspError) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, rpcErr = nil, &lspError{Code: lspInternalError, Message: fmt.Sprintf("%s failed: %v", method, r)}
+		}
+	}()
+	return s.handle(method, params)
+}
+
+func (s *lspServer) handle(method string, params json.RawMessage) (interface{}, *lspError) {
+	switch method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				// incremental changes
+				"textDocumentSync":       map[string]interface{}{"openClose": true, "change": 2},
+				"documentSymbolProvider": true,
+				"foldingRangeProvider":   true,
+				"selectionRangeProvider": true,
+				"semanticTokensProvider": map[string]interface{}{
+					"legend": map[string]interface{}{"tokenTypes": lspTokenTypes, "tokenModifiers": lspTokenModifiers},
+					"full":   true,
+				},
+			},
+			"serverInfo": map[string]interface{}{"name": "ngbuild"},
+		}, nil
+	case "initialized", "shutdown":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+				Text    string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		document := newLSPDocument(NewParsedTemplate(p.TextDocument.Text, p.TextDocument.URI))
+		document.version = p.TextDocument.Version
+		s.documents[p.TextDocument.URI] = document
+		s.publishDiagnostics(p.TextDocument.URI, document)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Range *lspRange `json:"range"`
+				Text  string    `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		document, rpcErr := s.document(p.TextDocument.URI)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		// a failed change leaves the document closed rather than out of sync
+		// with the client
+		delete(s.documents, p.TextDocument.URI)
+		for _, change := range p.ContentChanges {
+			if change.Range == nil {
+				document = newLSPDocument(NewParsedTemplate(change.Text, p.TextDocument.URI))
+				continue
+			}
+			start, end := document.offset(change.Range.Start), document.offset(change.Range.End)
+			if end < start {
+				start, end = end, start
+			}
+			template, _ := document.template.ApplyEdit(TextEdit{Offset: start, Length: end - start, Replacement: change.Text})
+			document = newLSPDocument(template)
+		}
+		document.version = p.TextDocument.Version
+		s.documents[p.TextDocument.URI] = document
+		s.publishDiagnostics(p.TextDocument.URI, document)
+		return nil, nil
+
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.documents, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+			"uri": p.TextDocument.URI, "diagnostics": []lspDiagnostic{},
+		})
+		return nil, nil
+
+	case "textDocument/documentSymbol", "textDocument/foldingRange", "textDocument/semanticTokens/full":
+		var p struct {
+			TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		document, rpcErr := s.document(p.TextDocument.URI)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		switch method {
+		case "textDocument/documentSymbol":
+			return document.symbols(document.template.Root.Nodes), nil
+		case "textDocument/foldingRange":
+			return document.foldingRanges(), nil
+		default:
+			return map[string]interface{}{"data": document.semanticTokens()}, nil
+		}
+
+	case "textDocument/selectionRange":
+		var p struct {
+			TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+			Positions    []lspPosition             `json:"positions"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		document, rpcErr := s.document(p.TextDocument.URI)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		ranges := make([]lspSelectionRange, len(p.Positions))
+		for i, position := range p.Positions {
+			ranges[i] = document.selectionRange(document.offset(position))
+		}
+		return ranges, nil
+	}
+
+	if strings.HasPrefix(method, "$/") {
+		// optional notifications and requests like $/cancelRequest
+		return nil, nil
+	}
+	return nil, &lspError{Code: lspMethodNotFound, Message: "unknown method " + method}
+}
+
+func invalidParams(err error) *lspError {
+	return &lspError{Code: lspInvalidParams, Message: err.Error()}
+}
+
+func (s *lspServer) document(uri string) (*lspDocument, *lspError) {
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, &lspError{Code: lspInvalidParams, Message: "document not open: " + uri}
+	}
+	return document, nil
+}
+
+func (s *lspServer) publishDiagnostics(uri string, document *lspDocument) {
+	s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"version":     document.version,
+		"diagnostics": document.diagnostics(),
+	})
+}
+
+func (s *lspServer) notify(method string, params interface{}) {
+	s.notifications = append(s.notifications, lspNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *lspServer) flush() error {
+	notifications := s.notifications
+	s.notifications = nil
+	for _, notification := range notifications {
+		if err := s.write(notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *lspServer) respondError(id json.RawMessage, rpcErr *lspError) error {
+	return s.write(lspErrorResponse{JSONRPC: "2.0", ID: id, Error: rpcErr})
+}
+
+func (s *lspServer) write(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = s.writer.Write(data)
+	return err
+}
+
+// readLSPMessage reads the content of a message framed by a Content-Length
+// header.
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, errors.New("lsp: missing or invalid Content-Length header")
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// lspDocument is an open template with the line starts to convert offsets
+// into the line and UTF-16 character positions of the protocol.
+type lspDocument struct {
+	template *ParsedTemplate
+	version  int
+	lines    []int
+}
+
+func newLSPDocument(template *ParsedTemplate) *lspDocument {
+	source := template.Root.Source
+	d := &lspDocument{template: template, lines: []int{0}}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' || source[i] == '\r' && (i+1 == len(source) || source[i+1] != '\n') {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	return d
+}
+
+func (d *lspDocument) position(offset int) lspPosition {
+	source := d.template.Root.Source
+	if offset > len(source) {
+		offset = len(source)
+	}
+	line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > offset }) - 1
+	if line < 0 {
+		line = 0
+	}
+	character := 0
+	for _, r := range source[d.lines[line]:offset] {
+		character += chars.UTF16Len(r)
+	}
+	return lspPosition{Line: line, Character: character}
+}
+
+// offset returns the byte offset of a position, positions past the end of a
+// line are at its end.
+func (d *lspDocument) offset(position lspPosition) int {
+	source := d.template.Root.Source
+	if position.Line < 0 {
+		return 0
+	}
+	if position.Line >= len(d.lines) {
+		return len(source)
+	}
+	end := len(source)
+	if position.Line+1 < len(d.lines) {
+		end = d.lines[position.Line+1]
+	}
+	offset := d.lines[position.Line]
+	for character := 0; offset < end && character < position.Character; {
+		if source[offset] == '\n' || source[offset] == '\r' {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(source[offset:])
+		character += chars.UTF16Len(r)
+		offset += size
+	}
+	return offset
+}
+
+func (d *lspDocument) span(span ep.AbsoluteSourceSpan) lspRange {
+	return lspRange{Start: d.position(span.Start), End: d.position(span.End)}
+}
+
+// diagnostics returns the errors of the expressions, at the expression.
+func (d *lspDocument) diagnostics() []lspDiagnostic {
+	diagnostics := []lspDiagnostic{}
+	add := func(ast *ep.ASTWithSource) {
+		if ast == nil {
+			return
+		}
+		for _, parseError := range ast.Errors {
+			diagnostics = append(diagnostics, lspDiagnostic{Range: d.span(ast.SourceSpan), Severity: 1, Source: "ngbuild", Message: parseError.Message})
+		}
+	}
+	for _, node := range d.template.Root.Nodes {
+		Walk(node, func(n Node) bool {
+			switch n := n.(type) {
+			case *Element:
+				for _, input := range n.Inputs {
+					add(input.Value)
+				}
+				for _, output := range n.Outputs {
+					add(output.Handler)
+				}
+			case *Template:
+				for _, input := range n.Inputs {
+					add(input.Value)
+				}
+				for _, output := range n.Outputs {
+					add(output.Handler)
+				}
+			case *BoundText:
+				add(n.Value)
+			}
+			return true
+		})
+	}
+	return diagnostics
+}
+
+// symbols returns the elements with their references and the blocks.
+func (d *lspDocument) symbols(nodes []Node) []lspDocumentSymbol {
+	symbols := []lspDocumentSymbol{}
+	for _, node := range nodes {
+		// a template is listed as the element it was written as
+		if template, ok := node.(*Template); ok {
+			node = template.Element()
+		}
+		switch n := node.(type) {
+		case *Element:
+			symbol := lspDocumentSymbol{
+				Name:           n.Name,
+				Kind:           lspSymbolField,
+				Range:          d.span(n.SourceSpan),
+				SelectionRange: d.span(ep.AbsoluteSourceSpan{Start: n.StartSourceSpan.Start + 1, End: n.StartSourceSpan.Start + 1 + len(n.Name)}),
+			}
+			for _, ref := range n.References {
+				keySpan := ref.KeySpan
+				if keySpan == (ep.AbsoluteSourceSpan{}) {
+					keySpan = ref.SourceSpan
+				}
+				symbol.Children = append(symbol.Children, lspDocumentSymbol{
+					Name:           "#" + ref.Name,
+					Detail:         ref.Value,
+					Kind:           lspSymbolVariable,
+					Range:          d.span(ref.SourceSpan),
+					SelectionRange: d.span(keySpan),
+				})
+			}
+			symbol.Children = append(symbol.Children, d.symbols(n.Children)...)
+			symbols = append(symbols, symbol)
+		case *Block:
+			var parameters []string
+			for _, parameter := range n.Parameters {
+				parameters = append(parameters, parameter.Expression)
+			}
+			symbols = append(symbols, lspDocumentSymbol{
+				Name:           "@" + n.Name,
+				Detail:         strings.Join(parameters, "; "),
+				Kind:           lspSymbolNamespace,
+				Range:          d.span(n.SourceSpan),
+				SelectionRange: d.span(n.StartSourceSpan),
+				Children:       d.symbols(n.Children),
+			})
+		}
+	}
+	return symbols
+}
+
+// foldingRanges folds elements and blocks up to the line before their end
+// and comments over several lines.
+func (d *lspDocument) foldingRanges() []lspFoldingRange {
+	ranges := []lspFoldingRange{}
+	fold := func(start int, end *ep.AbsoluteSourceSpan) {
+		if end == nil {
+			return
+		}
+		startLine, endLine := d.position(start).Line, d.position(end.Start).Line-1
+		if endLine > startLine {
+			ranges = append(ranges, lspFoldingRange{StartLine: startLine, EndLine: endLine})
+		}
+	}
+	for _, node := range d.template.Root.Nodes {
+		Walk(node, func(n Node) bool {
+			switch n := n.(type) {
+			case *Element:
+				fold(n.SourceSpan.Start, n.EndSourceSpan)
+			case *Template:
+				// the element of a structural directive folds on its own
+				if n.TemplateAttr == nil {
+					fold(n.SourceSpan.Start, n.EndSourceSpan)
+				}
+			case *Block:
+				fold(n.SourceSpan.Start, n.EndSourceSpan)
+			case *Comment:
+				startLine, endLine := d.position(n.SourceSpan.Start).Line, d.position(n.SourceSpan.End).Line
+				if endLine > startLine {
+					ranges = append(ranges, lspFoldingRange{StartLine: startLine, EndLine: endLine, Kind: "comment"})
+				}
+			}
+			return true
+		})
+	}
+	return ranges
+}
+
+// selectionRange returns the nested spans around an offset: the nodes, the
+// start tag, the attribute, its value and the expressions in it.
+func (d *lspDocument) selectionRange(offset int) lspSelectionRange {
+	root := d.template.Root
+	spans := []ep.AbsoluteSourceSpan{{Start: 0, End: len(root.Source)}}
+	contains := func(span ep.AbsoluteSourceSpan) bool {
+		return span.Start <= offset && offset <= span.End
+	}
+	addExpression := func(ast *ep.ASTWithSource) {
+		if ast == nil {
+			return
+		}
+		ep.Walk(ast, func(e ep.AST) bool {
+			span := e.GetSourceSpan()
+			if span.Start == span.End || !contains(span) {
+				return false
+			}
+			spans = append(spans, span)
+			return true
+		})
+	}
+	addAttribute := func(span, keySpan ep.AbsoluteSourceSpan, valueSpan *ep.AbsoluteSourceSpan, value *ep.ASTWithSource) bool {
+		if !contains(span) {
+			return false
+		}
+		spans = append(spans, span)
+		if contains(keySpan) {
+			spans = append(spans, keySpan)
+		} else if valueSpan != nil && contains(*valueSpan) {
+			spans = append(spans, *valueSpan)
+			addExpression(value)
+		}
+		return true
+	}
+
+	nodes := root.Nodes
+	for len(nodes) > 0 {
+		var found Node
+		for _, node := range nodes {
+			if contains(node.GetSourceSpan()) {
+				found = node
+				break
+			}
+		}
+		if found == nil {
+			break
+		}
+		if template, ok := found.(*Template); ok {
+			found = template.Element()
+		}
+		spans = append(spans, found.GetSourceSpan())
+		nodes = nil
+
+		switch n := found.(type) {
+		case *Element:
+			if contains(n.StartSourceSpan) {
+				spans = append(spans, n.StartSourceSpan)
+				d.selectAttribute(n, addAttribute)
+			} else if n.EndSourceSpan != nil && contains(*n.EndSourceSpan) {
+				spans = append(spans, *n.EndSourceSpan)
+			} else {
+				nodes = n.Children
+			}
+		case *Block:
+			if contains(n.StartSourceSpan) {
+				spans = append(spans, n.StartSourceSpan)
+				for _, parameter := range n.Parameters {
+					if contains(parameter.SourceSpan) {
+						spans = append(spans, parameter.SourceSpan)
+						break
+					}
+				}
+			} else if n.EndSourceSpan == nil || !contains(*n.EndSourceSpan) {
+				nodes = n.Children
+			}
+		case *BoundText:
+			addExpression(n.Value)
+		}
+	}
+
+	// keep the spans nested, outermost first
+	var selection *lspSelectionRange
+	var last ep.AbsoluteSourceSpan
+	for i, span := range spans {
+		if i > 0 && (span == last || span.Start < last.Start || span.End > last.End) {
+			continue
+		}
+		selection = &lspSelectionRange{Range: d.span(span), Parent: selection}
+		last = span
+	}
+	return *selection
+}
+
+// selectAttribute calls add with the attributes of an element until it
+// returns true, the two-way handler is the same attribute as the input.
+func (d *lspDocument) selectAttribute(n *Element, add func(span, keySpan ep.AbsoluteSourceSpan, valueSpan *ep.AbsoluteSourceSpan, value *ep.ASTWithSource) bool) {
+	for _, attr := range n.Attributes {
+		if add(attr.SourceSpan, attr.KeySpan, attr.ValueSpan, nil) {
+			return
+		}
+	}
+	for _, input := range n.Inputs {
+		if add(input.SourceSpan, input.KeySpan, input.ValueSpan, input.Value) {
+			return
+		}
+	}
+	for _, output := range n.Outputs {
+		if output.BindingType != BindingTypeTwoWay && add(output.SourceSpan, output.KeySpan, output.ValueSpan, output.Handler) {
+			return
+		}
+	}
+	for _, ref := range n.References {
+		if add(ref.SourceSpan, ref.KeySpan, ref.ValueSpan, nil) {
+			return
+		}
+	}
+}
+
+// semanticTokens returns the classified tokens of the template in the
+// relative encoding of the protocol.
+func (d *lspDocument) semanticTokens() []int {
+	data := []int{}
+	var previous lspPosition
+	for _, token := range classifyTemplate(d.template.Root) {
+		tokenType, modifiers := lspTokenType(token.Kind)
+		start, stop := d.position(token.Span.Start), d.position(token.Span.End)
+		// tokens may not span lines, a string across lines is one per line
+		for line := start.Line; line <= stop.Line; line++ {
+			from, to := lspPosition{Line: line}, stop
+			if line == start.Line {
+				from = start
+			}
+			if line != stop.Line {
+				to = d.position(d.lines[line+1] - 1)
+			}
+			if to.Character <= from.Character {
+				continue
+			}
+			character := from.Character
+			if from.Line == previous.Line {
+				character -= previous.Character
+			}
+			data = append(data, from.Line-previous.Line, character, to.Character-from.Character, tokenType, modifiers)
+			previous = from
+		}
+	}
+	return data
+}
+
+// lspTokenType returns the token type and the modifier bits of a kind.
+func lspTokenType(kind SemanticTokenKind) (int, int) {
+	switch kind {
+	case TokenTagName:
+		return lspTokenClass, 0
+	case TokenAttributeName, TokenPropertyRead:
+		return lspTokenProperty, 0
+	case TokenBindingKind:
+		return lspTokenDecorator, 0
+	case TokenEventName:
+		return lspTokenEvent, 0
+	case TokenReference:
+		return lspTokenVariable, lspModifierDeclaration
+	case TokenPipeName:
+		return lspTokenFunction, 0
+	case TokenControlFlowKeyword:
+		return lspTokenKeyword, lspModifierControlFlow
+	case TokenString:
+		return lspTokenString, 0
+	case TokenNumber:
+		return lspTokenNumber, 0
+	case TokenOperator:
+		return lspTokenOperator, 0
+	}
+	return lspTokenKeyword, 0
+}
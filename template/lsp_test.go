@@ -0,0 +1,200 @@
+package template
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// lspClient talks to ServeLSP in-process through pipes. The messages of the
+// server are read in the background, a pipe blocks the writer until they are
+// read.
+type lspClient struct {
+	t        *testing.T
+	writer   *io.PipeWriter
+	messages chan []byte
+	done     chan error
+	nextID   int
+	// notifications are the notifications received so far
+	notifications []lspTestMessage
+}
+
+type lspTestMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *lspError       `json:"error"`
+}
+
+func newLSPClient(t *testing.T) *lspClient {
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	c := &lspClient{t: t, writer: clientWriter, messages: make(chan []byte, 100), done: make(chan error, 1)}
+	go func() {
+		err := ServeLSP(serverReader, serverWriter)
+		serverWriter.Close()
+		c.done <- err
+	}()
+	go func() {
+		reader := bufio.NewReader(clientReader)
+		for {
+			data, err := readLSPMessage(reader)
+			if err != nil {
+				close(c.messages)
+				return
+			}
+			c.messages <- data
+		}
+	}()
+	return c
+}
+
+func (c *lspClient) send(message map[string]interface{}) {
+	message["jsonrpc"] = "2.0"
+	data, err := json.Marshal(message)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// request sends a request and returns its response, the notifications sent
+// before it are kept.
+func (c *lspClient) request(method string, params interface{}) lspTestMessage {
+	c.nextID++
+	c.send(map[string]interface{}{"id": c.nextID, "method": method, "params": params})
+	for {
+		message := c.read()
+		if message.Method != "" {
+			c.notifications = append(c.notifications, message)
+			continue
+		}
+		if string(message.ID) != fmt.Sprint(c.nextID) {
+			c.t.Fatalf("response to %s has id %s, want %d", method, message.ID, c.nextID)
+		}
+		return message
+	}
+}
+
+func (c *lspClient) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"method": method, "params": params})
+}
+
+func (c *lspClient) read() lspTestMessage {
+	data, ok := <-c.messages
+	if !ok {
+		c.t.Fatal("the server closed the connection")
+	}
+	var message lspTestMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		c.t.Fatalf("decoding %s: %v", data, err)
+	}
+	return message
+}
+
+func (c *lspClient) close() {
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Errorf("ServeLSP: %v", err)
+	}
+	c.writer.Close()
+}
+
+// lastDiagnostics returns the messages of the last diagnostics published.
+func (c *lspClient) lastDiagnostics() []string {
+	for i := len(c.notifications) - 1; i >= 0; i-- {
+		if c.notifications[i].Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params struct {
+			Diagnostics []lspDiagnostic `json:"diagnostics"`
+		}
+		if err := json.Unmarshal(c.notifications[i].Params, &params); err != nil {
+			c.t.Fatal(err)
+		}
+		var messages []string
+		for _, diagnostic := range params.Diagnostics {
+			messages = append(messages, diagnostic.Message)
+		}
+		return messages
+	}
+	c.t.Fatal("no diagnostics published")
+	return nil
+}
+
+func lspChange(uri string, version int, line, character int, text string) map[string]interface{} {
+	position := map[string]interface{}{"line": line, "character": character}
+	return map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": version},
+		"contentChanges": []interface{}{map[string]interface{}{"range": map[string]interface{}{"start": position, "end": position}, "text": text}},
+	}
+}
+
+func TestLSP(t *testing.T) {
+	const uri = "file:///app.component.html"
+	c := newLSPClient(t)
+	defer c.close()
+
+	if response := c.request("initialize", map[string]interface{}{}); response.Error != nil || !strings.Contains(string(response.Result), "semanticTokensProvider") {
+		t.Fatalf("initialize = %s %v", response.Result, response.Error)
+	}
+	c.notify("initialized", map[string]interface{}{})
+
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "version": 1, "text": "<div>\n  <p title=\"x\">{{ a + }}</p>\n</div>\n"},
+	})
+	// typing a binding inside a tag, the unfinished keys used to crash the parser
+	for i, text := range []string{" ", "[", "(", "x", ")", "]", "=", `"`, "b", `"`} {
+		c.notify("textDocument/didChange", lspChange(uri, i+2, 1, 4+i, text))
+	}
+
+	response := c.request("textDocument/documentSymbol", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri}})
+	if response.Error != nil {
+		t.Fatalf("documentSymbol: %v", response.Error)
+	}
+	var symbols []lspDocumentSymbol
+	if err := json.Unmarshal(response.Result, &symbols); err != nil {
+		t.Fatal(err)
+	}
+	if len(symbols) != 1 || symbols[0].Name != "div" || len(symbols[0].Children) != 1 || symbols[0].Children[0].Name != "p" {
+		t.Errorf("documentSymbol = %s", response.Result)
+	}
+	if diagnostics := c.lastDiagnostics(); len(diagnostics) != 1 || !strings.Contains(diagnostics[0], "Unexpected end of expression") {
+		t.Errorf("diagnostics = %q", diagnostics)
+	}
+
+	response = c.request("textDocument/semanticTokens/full", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri}})
+	var tokens struct {
+		Data []int `json:"data"`
+	}
+	if err := json.Unmarshal(response.Result, &tokens); err != nil || response.Error != nil {
+		t.Fatalf("semanticTokens = %s %v", response.Result, response.Error)
+	}
+	// div, p, `[(`, x, `)]`, b, title, a, +, p, div
+	if len(tokens.Data) != 11*5 {
+		t.Errorf("semanticTokens = %v", tokens.Data)
+	}
+
+	if response := c.request("textDocument/hover", map[string]interface{}{}); response.Error == nil || response.Error.Code != lspMethodNotFound {
+		t.Errorf("unknown method = %s %v", response.Result, response.Error)
+	}
+}
+
+func TestLSPRecoversFromPanics(t *testing.T) {
+	s := &lspServer{documents: map[string]*lspDocument{"broken": {}}}
+	params := json.RawMessage(`{"textDocument":{"uri":"broken"}}`)
+
+	_, rpcErr := s.recoverHandle("textDocument/documentSymbol", params)
+	if rpcErr == nil || rpcErr.Code != lspInternalError {
+		t.Errorf("documentSymbol of a broken document = %v, want an internal error", rpcErr)
+	}
+	if _, rpcErr := s.recoverHandle("shutdown", nil); rpcErr != nil {
+		t.Errorf("shutdown after a panic = %v", rpcErr)
+	}
+}
@@ -0,0 +1,201 @@
+package template
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/irustm/ng-template-parser/chars"
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+// https://github.com/angular/angular/blob/master/packages/compiler/src/expression_parser/parser.ts (parseTemplateBindings)
+
+// TemplateBinding is a binding of the microsyntax of a structural directive.
+// `*ngFor="let item of items; index as i"` has the bindings ngFor (without
+// value), item = $implicit, ngForOf = items and i = index.
+type TemplateBinding struct {
+	// Key is the directive input, e.g. `ngForOf`, or the name of a variable.
+	Key string
+	// Value is nil for variables and the directive key without expression.
+	Value *ep.ASTWithSource
+	// Variable is the property of the template context a variable reads, it is
+	// empty for inputs.
+	Variable string
+
+	// SourceSpan covers the key and the value of the binding. KeySpan is empty
+	// for the directive key, which is the attribute name. ValueSpan is the
+	// span of the context property of a variable, nil for `let item`.
+	SourceSpan ep.AbsoluteSourceSpan
+	KeySpan    ep.AbsoluteSourceSpan
+	ValueSpan  *ep.AbsoluteSourceSpan
+}
+
+func (b TemplateBinding) IsVariable() bool {
+	return b.Variable != ""
+}
+
+var templateBindingAliasRegexp = regexp.MustCompile(`\s+as\s+([A-Za-z_$][A-Za-z0-9_$]*)\s*$`)
+
+// ParseTemplateBindings parses the value of a structural directive attribute
+// like `*ngIf="user; else loading"`. The keywords after the first expression
+// are prefixed with the directive name, so `else` binds to ngIfElse. offset is
+// the absolute offset of value in the template.
+func ParseTemplateBindings(directive string, value string, location string, offset int, parser *ep.Parser) ([]TemplateBinding, []ep.ParserError) {
+	var bindings []TemplateBinding
+	var errors []ep.ParserError
+	first := true
+	span := func(start int, end int) ep.AbsoluteSourceSpan {
+		return ep.AbsoluteSourceSpan{Start: offset + start, End: offset + end}
+	}
+
+	for i := 0; ; {
+		i = skipBindingSeparators(value, i)
+		if i >= len(value) {
+			break
+		}
+
+		if hasWordAt(value, i, "let") {
+			nameStart := skipTagSpace(value, i+3)
+			name, end := scanBindingIdentifier(value, nameStart)
+			binding := TemplateBinding{Key: name, Variable: "$implicit", KeySpan: span(nameStart, end)}
+			if next := skipTagSpace(value, end); next < len(value) && value[next] == '=' {
+				valueStart := skipTagSpace(value, next+1)
+				binding.Variable, end = scanBindingIdentifier(value, valueStart)
+				valueSpan := span(valueStart, end)
+				binding.ValueSpan = &valueSpan
+			}
+			binding.SourceSpan = span(i, end)
+			if name == "" || binding.Variable == "" {
+				errors = append(errors, ep.NewParserError("Unexpected token in template bindings", value, "at column "+strconv.Itoa(end+1), location))
+				end = bindingExpressionEnd(value, end)
+			} else {
+				bindings = append(bindings, binding)
+			}
+
+			i = end
+			first = false
+			continue
+		}
+
+		key := directive
+		keyStart := i
+		var keySpan ep.AbsoluteSourceSpan
+		if first {
+			bindings = append(bindings, TemplateBinding{Key: directive})
+		} else {
+			keyword, end := scanBindingIdentifier(value, i)
+			if keyword != "" {
+				keySpan = span(i, end)
+				end = skipTagSpace(value, end)
+				// `index as i` aliases a context property
+				if hasWordAt(value, end, "as") {
+					aliasStart := skipTagSpace(value, end+2)
+					alias, aliasEnd := scanBindingIdentifier(value, aliasStart)
+					bindings = append(bindings, TemplateBinding{
+						Key: alias, Variable: keyword,
+						SourceSpan: span(i, aliasEnd), KeySpan: span(aliasStart, aliasEnd), ValueSpan: &keySpan,
+					})
+					i = aliasEnd
+					continue
+				}
+
+				key = directive + strings.ToUpper(keyword[:1]) + keyword[1:]
+				i = end
+				if i < len(value) && value[i] == ':' {
+					i = skipTagSpace(value, i+1)
+				}
+			}
+		}
+
+		end := bindingExpressionEnd(value, i)
+		expression := value[i:end]
+		alias := ""
+		var aliasSpan ep.AbsoluteSourceSpan
+		if match := templateBindingAliasRegexp.FindStringSubmatchIndex(expression); match != nil {
+			alias = expression[match[2]:match[3]]
+			aliasSpan = span(i+match[2], i+match[3])
+			expression = expression[:match[0]]
+		}
+
+		ast := parser.ParseBinding(expression, location, offset+i)
+		errors = append(errors, ast.Errors...)
+		sourceSpan := span(keyStart, i+len(strings.TrimRight(expression, " \t\n\r\f")))
+		if first {
+			bindings[len(bindings)-1].Value = ast
+			bindings[len(bindings)-1].SourceSpan = sourceSpan
+		} else {
+			bindings = append(bindings, TemplateBinding{Key: key, Value: ast, SourceSpan: sourceSpan, KeySpan: keySpan})
+		}
+		if alias != "" {
+			valueSpan := keySpan
+			bindings = append(bindings, TemplateBinding{
+				Key: alias, Variable: key,
+				SourceSpan: ep.AbsoluteSourceSpan{Start: sourceSpan.Start, End: aliasSpan.End}, KeySpan: aliasSpan, ValueSpan: &valueSpan,
+			})
+		}
+
+		i = end
+		first = false
+	}
+
+	if len(bindings) == 0 {
+		bindings = append(bindings, TemplateBinding{Key: directive})
+	}
+
+	return bindings, errors
+}
+
+func skipBindingSeparators(value string, i int) int {
+	for i < len(value) && (isTagSpace(value[i]) || value[i] == ';' || value[i] == ',') {
+		i++
+	}
+	return i
+}
+
+// hasWordAt reports whether value has word at i followed by whitespace or the
+// end of value.
+func hasWordAt(value string, i int, word string) bool {
+	end := i + len(word)
+	return strings.HasPrefix(value[i:], word) && (end == len(value) || isTagSpace(value[end]))
+}
+
+func scanBindingIdentifier(value string, i int) (string, int) {
+	start := i
+	for i < len(value) && chars.IsIdentifierPart(int(value[i])) {
+		i++
+	}
+	if i > start && !chars.IsIdentifierStart(int(value[start])) {
+		return "", start
+	}
+	return value[start:i], i
+}
+
+// bindingExpressionEnd returns the end of the expression at i, which is the
+// next `;` or `,` outside of brackets and strings.
+func bindingExpressionEnd(value string, i int) int {
+	depth := 0
+	var quote byte
+
+	for ; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case (c == ';' || c == ',') && depth <= 0:
+			return i
+		}
+	}
+
+	return i
+}
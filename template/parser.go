@@ -0,0 +1,919 @@
+// Package template parses Angular templates into a tree of nodes with parsed
+// binding expressions, and formats, validates, serializes, encodes and
+// renders them. Parse is the entry point, StreamTemplate and ParsedTemplate
+// parse without a tree and incrementally.
+package template
+
+import (
+	"github.com/irustm/ng-template-parser/chars"
+	"github.com/irustm/ng-template-parser/ep"
+	"github.com/irustm/ng-template-parser/schema"
+	"golang.org/x/net/html"
+	"io"
+	"strings"
+)
+
+type TextAttribute struct {
+	Name       string
+	Value      string
+	SourceSpan ep.AbsoluteSourceSpan
+	KeySpan    ep.AbsoluteSourceSpan
+	ValueSpan  *ep.AbsoluteSourceSpan
+}
+
+type Reference struct {
+	Name       string
+	Value      string
+	SourceSpan ep.AbsoluteSourceSpan
+	KeySpan    ep.AbsoluteSourceSpan
+	ValueSpan  *ep.AbsoluteSourceSpan
+}
+
+// https://github.com/angular/angular/blob/master/packages/compiler/src/expression_parser/ast.ts
+type BindingType int
+
+const (
+	BindingTypeProperty BindingType = iota
+	BindingTypeAttribute
+	BindingTypeClass
+	BindingTypeStyle
+	BindingTypeAnimation
+	BindingTypeTwoWay
+)
+
+type BoundAttribute struct {
+	Name            string
+	BindingType     BindingType
+	SecurityContext schema.SecurityContext
+	Value           *ep.ASTWithSource
+	SourceSpan      ep.AbsoluteSourceSpan
+	KeySpan         ep.AbsoluteSourceSpan
+	ValueSpan       *ep.AbsoluteSourceSpan
+}
+
+type BoundEvent struct {
+	Name        string
+	BindingType BindingType
+	Handler     *ep.ASTWithSource
+	SourceSpan  ep.AbsoluteSourceSpan
+	KeySpan     ep.AbsoluteSourceSpan
+	ValueSpan   *ep.AbsoluteSourceSpan
+}
+
+type BoundText struct {
+	Value      *ep.ASTWithSource
+	SourceSpan ep.AbsoluteSourceSpan
+}
+
+type Text struct {
+	Value      string
+	SourceSpan ep.AbsoluteSourceSpan
+}
+
+type Comment struct {
+	Value      string
+	SourceSpan ep.AbsoluteSourceSpan
+}
+
+// Block is a control flow block like `@if (cond) { ... }`. Connected blocks
+// like `@else` or `@empty` are siblings of the block they continue.
+type Block struct {
+	// Name is e.g. `if`, `else if`, `for` or `case`.
+	Name       string
+	Parameters []BlockParameter
+	Children   []Node
+
+	SourceSpan      ep.AbsoluteSourceSpan
+	StartSourceSpan ep.AbsoluteSourceSpan
+	// EndSourceSpan is nil for unclosed blocks.
+	EndSourceSpan *ep.AbsoluteSourceSpan
+}
+
+// BlockParameter is one of the `;` separated parameters of a block, e.g.
+// `item of items` or `track item.id`.
+type BlockParameter struct {
+	Expression string
+	SourceSpan ep.AbsoluteSourceSpan
+}
+
+type Element struct {
+	Name        string
+	Attributes  []TextAttribute
+	Inputs      []BoundAttribute
+	Outputs     []BoundEvent
+	References  []Reference
+	Children    []Node
+	SelfClosing bool
+
+	SourceSpan      ep.AbsoluteSourceSpan
+	StartSourceSpan ep.AbsoluteSourceSpan
+	// EndSourceSpan is nil for void, self-closing and unclosed elements.
+	EndSourceSpan *ep.AbsoluteSourceSpan
+}
+
+// Template is an <ng-template>, or the template of an element with a
+// structural directive like `<li *ngFor="let item of items">`, which has the
+// element as its only child. An element with several `*` attributes is in a
+// Template for each of them, the first one outermost.
+type Template struct {
+	// TagName is the name of the <ng-template> or of the element of the
+	// structural directive.
+	TagName string
+	// TemplateAttr is the `*` attribute of a structural directive, nil for an
+	// <ng-template>.
+	TemplateAttr *TextAttribute
+
+	// The attributes of an <ng-template>, the ones of an element with a
+	// structural directive stay on the element.
+	Attributes  []TextAttribute
+	Inputs      []BoundAttribute
+	Outputs     []BoundEvent
+	References  []Reference
+	Children    []Node
+	SelfClosing bool
+
+	// The spans of a structural directive are the ones of its element.
+	SourceSpan      ep.AbsoluteSourceSpan
+	StartSourceSpan ep.AbsoluteSourceSpan
+	// EndSourceSpan is nil for self-closing and unclosed templates.
+	EndSourceSpan *ep.AbsoluteSourceSpan
+}
+
+// Element returns the element a template was written as: an <ng-template>
+// element, or the element of a structural directive with its `*` attribute.
+// It is a copy, e.g. to print the start tag, which shares the children.
+func (t *Template) Element() *Element {
+	if t.TemplateAttr == nil {
+		return &Element{
+			Name:            t.TagName,
+			Attributes:      t.Attributes,
+			Inputs:          t.Inputs,
+			Outputs:         t.Outputs,
+			References:      t.References,
+			Children:        t.Children,
+			SelfClosing:     t.SelfClosing,
+			SourceSpan:      t.SourceSpan,
+			StartSourceSpan: t.StartSourceSpan,
+			EndSourceSpan:   t.EndSourceSpan,
+		}
+	}
+
+	var element Element
+	if len(t.Children) == 1 {
+		switch child := t.Children[0].(type) {
+		case *Element:
+			element = *child
+		case *Template:
+			element = *child.Element()
+		}
+	}
+	if element.Name == "" {
+		// a template which was built without its element
+		element = Element{Name: t.TagName, Children: t.Children, SourceSpan: t.SourceSpan,
+			StartSourceSpan: t.StartSourceSpan, EndSourceSpan: t.EndSourceSpan}
+	}
+
+	// the attribute goes back to its place in the start tag
+	attributes := make([]TextAttribute, 0, len(element.Attributes)+1)
+	inserted := false
+	for _, attr := range element.Attributes {
+		if !inserted && attr.SourceSpan.Start > t.TemplateAttr.SourceSpan.Start {
+			attributes = append(attributes, *t.TemplateAttr)
+			inserted = true
+		}
+		attributes = append(attributes, attr)
+	}
+	if !inserted {
+		attributes = append(attributes, *t.TemplateAttr)
+	}
+	element.Attributes = attributes
+	return &element
+}
+
+type Root struct {
+	Nodes  []Node
+	Errors []ep.ParserError
+	// Source is the parsed template text, the spans of the nodes point into it.
+	Source string
+}
+
+// Parse parses a template, location is used in expression errors.
+func Parse(r io.Reader, location string) Root {
+	tokenizer := newTemplateTokenizer(r)
+	tokenizer.location = location
+
+	return parse(tokenizer)
+}
+
+// templateTokenizer keeps the raw text of the current token, html.Tokenizer
+// lowercases tag and attribute names in place while building a Token. It also
+// collects the errors of the binding expressions parsed along the way.
+type templateTokenizer struct {
+	*html.Tokenizer
+	raw   string
+	token html.Token
+
+	location         string
+	expressionParser *ep.Parser
+	errors           []ep.ParserError
+
+	// source accumulates the raw tokens, start is the offset of the current
+	// one and offset the offset after it.
+	source strings.Builder
+	start  int
+	offset int
+
+	// stream receives the nodes as they start and end instead of building a
+	// tree, see StreamTemplate. depth counts the open elements, templates and
+	// blocks and streamErr is the first error of stream, which ends the parse.
+	stream    func(StreamEvent) error
+	depth     int
+	streamErr error
+
+	// pending holds the tokens a text token was split into, blockDepth counts
+	// the open blocks so a `}` outside of blocks stays text.
+	pending    []templateToken
+	blockDepth int
+	// rawText is set after the start tag of an element like <script> whose
+	// content is not parsed.
+	rawText    bool
+	parameters []rawBlockParameter
+}
+
+// Token types for control flow blocks, which html.Tokenizer sees as text.
+const (
+	blockStartToken html.TokenType = iota + 100
+	blockEndToken
+)
+
+type templateToken struct {
+	raw        string
+	token      html.Token
+	parameters []rawBlockParameter
+}
+
+// rawBlockParameter is a block parameter, offsets are relative to the start
+// of the block.
+type rawBlockParameter struct {
+	Expression string
+	Start      int
+	End        int
+}
+
+func newTemplateTokenizer(r io.Reader) *templateTokenizer {
+	return &templateTokenizer{Tokenizer: html.NewTokenizer(r), expressionParser: ep.NewParser()}
+}
+
+func (t *templateTokenizer) parseBinding(source string, absoluteOffset int) *ep.ASTWithSource {
+	ast := t.expressionParser.ParseBinding(source, t.location, absoluteOffset)
+	t.addErrors(ast)
+	return ast
+}
+
+func (t *templateTokenizer) parseAction(source string, absoluteOffset int) *ep.ASTWithSource {
+	ast := t.expressionParser.ParseAction(source, t.location, absoluteOffset)
+	t.addErrors(ast)
+	return ast
+}
+
+func (t *templateTokenizer) parseInterpolation(source string, absoluteOffset int) *ep.ASTWithSource {
+	ast := t.expressionParser.ParseInterpolation(source, t.location, absoluteOffset)
+	if ast != nil {
+		t.addErrors(ast)
+	}
+	return ast
+}
+
+// addErrors collects the errors of an expression, streamed nodes keep them
+// only in their expressions.
+func (t *templateTokenizer) addErrors(ast *ep.ASTWithSource) {
+	if t.stream == nil {
+		t.errors = append(t.errors, ast.Errors...)
+	}
+}
+
+// enter and leave pass a node to stream, children are only added to their
+// parent when nodes aren't streamed.
+func (t *templateTokenizer) enter(node Node) {
+	if t.stream != nil && t.streamErr == nil {
+		t.streamErr = t.stream(StreamEvent{Type: EnterNode, Node: node, Depth: t.depth})
+	}
+	t.depth++
+}
+
+func (t *templateTokenizer) leave(node Node) {
+	t.depth--
+	if t.stream != nil && t.streamErr == nil {
+		t.streamErr = t.stream(StreamEvent{Type: LeaveNode, Node: node, Depth: t.depth})
+	}
+}
+
+func (t *templateTokenizer) appendChild(children []Node, child Node) []Node {
+	if child == nil || t.stream != nil {
+		return children
+	}
+	return append(children, child)
+}
+
+func (t *templateTokenizer) Err() error {
+	if t.streamErr != nil {
+		return t.streamErr
+	}
+	return t.Tokenizer.Err()
+}
+
+func (t *templateTokenizer) Next() html.TokenType {
+	if t.streamErr != nil {
+		// end the parse like the end of the input
+		t.raw = ""
+		t.token = html.Token{Type: html.ErrorToken}
+		t.start = t.offset
+		return t.token.Type
+	}
+
+	if len(t.pending) == 0 {
+		tokenType := t.Tokenizer.Next()
+		raw := string(t.Tokenizer.Raw())
+		token := t.Tokenizer.Token()
+
+		if tokenType == html.TextToken && !t.rawText {
+			t.pending = t.splitBlocks(raw, token)
+		} else {
+			t.pending = []templateToken{{raw: raw, token: token}}
+		}
+		t.rawText = tokenType == html.StartTagToken && isRawTextElement(token.Data)
+	}
+
+	next := t.pending[0]
+	t.pending = t.pending[1:]
+	t.raw = next.raw
+	t.token = next.token
+	t.parameters = next.parameters
+
+	t.start = t.offset
+	t.offset += len(t.raw)
+	if t.stream == nil {
+		t.source.WriteString(t.raw)
+	}
+
+	return t.token.Type
+}
+
+// splitBlocks splits a raw text token at the starts and ends of control flow
+// blocks, the text in between becomes separate text tokens.
+func (t *templateTokenizer) splitBlocks(raw string, token html.Token) []templateToken {
+	var tokens []templateToken
+	textStart := 0
+
+	flushText := func(end int) {
+		if end > textStart {
+			text := raw[textStart:end]
+			data := html.UnescapeString(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text))
+			tokens = append(tokens, templateToken{raw: text, token: html.Token{Type: html.TextToken, Data: data}})
+		}
+	}
+
+	for i := 0; i < len(raw); {
+		switch {
+		case strings.HasPrefix(raw[i:], "{{"):
+			if end := strings.Index(raw[i+2:], "}}"); end != -1 {
+				i += end + 4
+			} else {
+				i = len(raw)
+			}
+		case raw[i] == '@':
+			name, parameters, end := scanBlockStart(raw[i:])
+			if end == -1 {
+				i++
+				continue
+			}
+			flushText(i)
+			tokens = append(tokens, templateToken{raw: raw[i : i+end], token: html.Token{Type: blockStartToken, Data: name}, parameters: parameters})
+			t.blockDepth++
+			i += end
+			textStart = i
+		case raw[i] == '}' && t.blockDepth > 0:
+			flushText(i)
+			tokens = append(tokens, templateToken{raw: "}", token: html.Token{Type: blockEndToken}})
+			t.blockDepth--
+			i++
+			textStart = i
+		default:
+			i++
+		}
+	}
+
+	if textStart == 0 {
+		return []templateToken{{raw: raw, token: token}}
+	}
+	flushText(len(raw))
+
+	return tokens
+}
+
+// scanBlockStart scans `@name (parameters) {` at the start of text and returns
+// the length of it, or -1 if text doesn't start with a block.
+func scanBlockStart(text string) (string, []rawBlockParameter, int) {
+	i := 1
+	for i < len(text) && isBlockNameChar(text[i]) {
+		i++
+	}
+	if i == 1 || !chars.IsAsciiLetter(int(text[1])) {
+		return "", nil, -1
+	}
+	name := text[1:i]
+
+	i = skipTagSpace(text, i)
+	if name == "else" && strings.HasPrefix(text[i:], "if") && (i+2 == len(text) || !isBlockNameChar(text[i+2])) {
+		name = "else if"
+		i = skipTagSpace(text, i+2)
+	}
+
+	var parameters []rawBlockParameter
+	if i < len(text) && text[i] == '(' {
+		depth := 0
+		paramStart := i + 1
+		var quote byte
+
+		addParameter := func(end int) {
+			start := skipTagSpace(text, paramStart)
+			for end > start && isTagSpace(text[end-1]) {
+				end--
+			}
+			if end > start {
+				parameters = append(parameters, rawBlockParameter{Expression: text[start:end], Start: start, End: end})
+			}
+		}
+
+		for ; i < len(text); i++ {
+			c := text[i]
+			if quote != 0 {
+				if c == '\\' {
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+				continue
+			}
+
+			if c == '"' || c == '\'' || c == '`' {
+				quote = c
+			} else if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+				if depth == 0 {
+					addParameter(i)
+					break
+				}
+			} else if c == ';' && depth == 1 {
+				addParameter(i)
+				paramStart = i + 1
+			}
+		}
+		if i >= len(text) {
+			return "", nil, -1
+		}
+		i = skipTagSpace(text, i+1)
+	}
+
+	if i >= len(text) || text[i] != '{' {
+		return "", nil, -1
+	}
+
+	return name, parameters, i + 1
+}
+
+func isBlockNameChar(c byte) bool {
+	return chars.IsAsciiLetter(int(c)) || chars.IsDigit(int(c)) || c == '_'
+}
+
+func skipTagSpace(text string, i int) int {
+	for i < len(text) && isTagSpace(text[i]) {
+		i++
+	}
+	return i
+}
+
+// isRawTextElement reports whether the content of an element is not parsed
+// by html.Tokenizer.
+func isRawTextElement(name string) bool {
+	switch name {
+	case "iframe", "noembed", "noframes", "noscript", "plaintext", "script", "style", "textarea", "title", "xmp":
+		return true
+	}
+	return false
+}
+
+func (t *templateTokenizer) Token() html.Token {
+	return t.token
+}
+
+// span returns the source span of the current token.
+func (t *templateTokenizer) span() ep.AbsoluteSourceSpan {
+	return ep.AbsoluteSourceSpan{Start: t.start, End: t.start + len(t.raw)}
+}
+
+// rawAttribute is an attribute of a raw start tag, offsets are relative to
+// the start of the tag and ValueStart is -1 for an attribute without value.
+type rawAttribute struct {
+	Name       string
+	Start      int
+	End        int
+	ValueStart int
+	ValueEnd   int
+}
+
+// rawStartTag returns the tag name and the attributes of a raw start tag with
+// their original case, angular bindings like [innerHTML] are case-sensitive.
+func rawStartTag(raw string) (string, []rawAttribute) {
+	i := 1
+	for i < len(raw) && !isTagSpace(raw[i]) && raw[i] != '/' && raw[i] != '>' {
+		i++
+	}
+	tagName := raw[1:i]
+
+	var attrs []rawAttribute
+	for i < len(raw) {
+		for i < len(raw) && (isTagSpace(raw[i]) || raw[i] == '/') {
+			i++
+		}
+		if i >= len(raw) || raw[i] == '>' {
+			break
+		}
+
+		attr := rawAttribute{Start: i, ValueStart: -1, ValueEnd: -1}
+		i++
+		for i < len(raw) && !isTagSpace(raw[i]) && raw[i] != '/' && raw[i] != '=' && raw[i] != '>' {
+			i++
+		}
+		attr.Name = raw[attr.Start:i]
+		attr.End = i
+
+		j := i
+		for j < len(raw) && isTagSpace(raw[j]) {
+			j++
+		}
+		if j >= len(raw) || raw[j] != '=' {
+			attrs = append(attrs, attr)
+			continue
+		}
+		i = j + 1
+		for i < len(raw) && isTagSpace(raw[i]) {
+			i++
+		}
+		if i < len(raw) && (raw[i] == '"' || raw[i] == '\'') {
+			quote := raw[i]
+			i++
+			attr.ValueStart = i
+			for i < len(raw) && raw[i] != quote {
+				i++
+			}
+			attr.ValueEnd = i
+			if i < len(raw) {
+				i++
+			}
+		} else {
+			attr.ValueStart = i
+			for i < len(raw) && !isTagSpace(raw[i]) && raw[i] != '>' {
+				i++
+			}
+			attr.ValueEnd = i
+		}
+		attr.End = i
+
+		attrs = append(attrs, attr)
+	}
+
+	return tagName, attrs
+}
+
+func isTagSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f'
+}
+
+func parse(tokenizer *templateTokenizer) Root {
+	root := Root{}
+	tokenizer.Next()
+
+	for {
+		token := tokenizer.Token()
+
+		if token.Type == html.ErrorToken {
+			// the end of the file, or an error reading it which ends the
+			// template like the end of the file
+			if err := tokenizer.Err(); err != io.EOF {
+				tokenizer.errors = append(tokenizer.errors, ep.ParserError{
+					Message: "error tokenizing HTML: " + err.Error(), CtxLocation: tokenizer.location})
+			}
+			break
+		}
+
+		root.Nodes = tokenizer.appendChild(root.Nodes, walk(tokenizer, token))
+	}
+
+	root.Source = tokenizer.source.String()
+	root.Errors = tokenizer.errors
+
+	return root
+}
+
+// walk returns the node starting at token, or nil for doctypes and stray end
+// tags.
+func walk(tokenizer *templateTokenizer, token html.Token) Node {
+	tokenType := token.Type
+	span := tokenizer.span()
+
+	if tokenType == html.TextToken {
+		data := token.Data
+		tokenizer.Next()
+
+		var node Node = &Text{Value: data, SourceSpan: span}
+		if ast := tokenizer.parseInterpolation(data, span.Start); ast != nil {
+			node = &BoundText{Value: ast, SourceSpan: span}
+		}
+
+		tokenizer.enter(node)
+		tokenizer.leave(node)
+		return node
+	}
+
+	if tokenType == blockStartToken {
+		block := &Block{Name: token.Data, SourceSpan: span, StartSourceSpan: span}
+		for _, parameter := range tokenizer.parameters {
+			block.Parameters = append(block.Parameters, BlockParameter{
+				Expression: parameter.Expression,
+				SourceSpan: ep.AbsoluteSourceSpan{Start: span.Start + parameter.Start, End: span.Start + parameter.End},
+			})
+		}
+
+		tokenizer.enter(block)
+
+		tokenType = tokenizer.Next()
+		for tokenType != blockEndToken && tokenType != html.EndTagToken && tokenType != html.ErrorToken {
+			block.Children = tokenizer.appendChild(block.Children, walk(tokenizer, tokenizer.Token()))
+			tokenType = tokenizer.Token().Type
+		}
+
+		if tokenType == blockEndToken {
+			endSpan := tokenizer.span()
+			block.EndSourceSpan = &endSpan
+			block.SourceSpan.End = endSpan.End
+			tokenizer.Next()
+		} else {
+			// the end tag or EOF belongs to the parent
+			block.SourceSpan.End = tokenizer.start
+		}
+
+		tokenizer.leave(block)
+		return block
+	}
+
+	if tokenType == html.CommentToken {
+		tokenizer.Next()
+		comment := &Comment{Value: token.Data, SourceSpan: span}
+		tokenizer.enter(comment)
+		tokenizer.leave(comment)
+		return comment
+	}
+
+	if tokenType == html.StartTagToken || tokenType == html.SelfClosingTagToken {
+		tagName, rawAttrs := rawStartTag(tokenizer.raw)
+		element := &Element{
+			Name:            tagName,
+			SelfClosing:     tokenType == html.SelfClosingTagToken,
+			SourceSpan:      span,
+			StartSourceSpan: span,
+		}
+
+		// parse attributes
+		for i, attr := range token.Attr {
+			attrSpan := span
+			var keySpan ep.AbsoluteSourceSpan
+			var valueSpan *ep.AbsoluteSourceSpan
+			valueOffset := span.Start
+
+			if len(rawAttrs) == len(token.Attr) {
+				rawAttr := rawAttrs[i]
+				attr.Key = rawAttr.Name
+				attrSpan = ep.AbsoluteSourceSpan{Start: span.Start + rawAttr.Start, End: span.Start + rawAttr.End}
+				keySpan = ep.AbsoluteSourceSpan{Start: attrSpan.Start, End: attrSpan.Start + len(rawAttr.Name)}
+				if rawAttr.ValueStart != -1 {
+					valueSpan = &ep.AbsoluteSourceSpan{Start: span.Start + rawAttr.ValueStart, End: span.Start + rawAttr.ValueEnd}
+					valueOffset = valueSpan.Start
+				}
+			}
+
+			key := attr.Key
+			twoWay := len(key) >= 4 && strings.HasPrefix(key, "[(") && strings.HasSuffix(key, ")]")
+
+			// Reference
+			if strings.HasPrefix(key, "#") {
+				element.References = append(element.References, Reference{
+					Name:       attr.Key[1:],
+					Value:      attr.Val,
+					SourceSpan: attrSpan,
+					KeySpan:    keySpan,
+					ValueSpan:  valueSpan,
+				})
+
+				// Output
+			} else if len(key) >= 2 && strings.HasPrefix(key, "(") && strings.HasSuffix(key, ")") {
+				name := attr.Key[1 : len(attr.Key)-1]
+
+				element.Outputs = append(element.Outputs,
+					BoundEvent{
+						Name:       name,
+						Handler:    tokenizer.parseAction(attr.Val, valueOffset),
+						SourceSpan: attrSpan,
+						KeySpan:    keySpan,
+						ValueSpan:  valueSpan,
+					})
+
+				// Input
+			} else if len(key) >= 2 && strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") && !twoWay {
+				name := attr.Key[1 : len(attr.Key)-1]
+				// [class.asd]
+				nameStrings := strings.Split(name, ".")
+				inputTypeString := nameStrings[0]
+				bindingType := BindingTypeProperty
+
+				if inputTypeString == "class" {
+					bindingType = BindingTypeClass
+				}
+
+				if inputTypeString == "style" {
+					bindingType = BindingTypeStyle
+				}
+
+				if inputTypeString == "attr" {
+					bindingType = BindingTypeAttribute
+				}
+
+				if bindingType != BindingTypeProperty {
+					if len(nameStrings) > 1 {
+						// the unit of [style.width.px] stays in the name
+						name = strings.Join(nameStrings[1:], ".")
+					} else {
+						// [class], [style] and [attr] bind the whole property
+						bindingType = BindingTypeProperty
+					}
+				}
+
+				element.Inputs = append(element.Inputs,
+					BoundAttribute{
+						Name:        name,
+						BindingType: bindingType,
+						Value:       tokenizer.parseBinding(attr.Val, valueOffset),
+						SourceSpan:  attrSpan,
+						KeySpan:     keySpan,
+						ValueSpan:   valueSpan,
+					})
+
+				// Input / Output
+			} else if twoWay {
+				name := attr.Key[2 : len(attr.Key)-2]
+
+				element.Inputs = append(element.Inputs,
+					BoundAttribute{
+						Name:        name,
+						BindingType: BindingTypeTwoWay,
+						Value:       tokenizer.parseBinding(attr.Val, valueOffset),
+						SourceSpan:  attrSpan,
+						KeySpan:     keySpan,
+						ValueSpan:   valueSpan,
+					})
+
+				handlerName := name + "Change"
+				// nodes don't share spans, ApplyEdit moves them in place
+				handlerValueSpan := valueSpan
+				if valueSpan != nil {
+					copied := *valueSpan
+					handlerValueSpan = &copied
+				}
+
+				element.Outputs = append(element.Outputs,
+					BoundEvent{
+						Name:        handlerName,
+						BindingType: BindingTypeTwoWay,
+						Handler:     tokenizer.parseAction(attr.Val+"=$event", valueOffset),
+						SourceSpan:  attrSpan,
+						KeySpan:     keySpan,
+						ValueSpan:   handlerValueSpan,
+					})
+			} else {
+				element.Attributes = append(element.Attributes,
+					TextAttribute{
+						Name:       attr.Key,
+						Value:      attr.Val,
+						SourceSpan: attrSpan,
+						KeySpan:    keySpan,
+						ValueSpan:  valueSpan,
+					})
+
+				// TODO Error
+				//fmt.Println(attr.Key, " = ", attr.Val)
+			}
+		}
+
+		// an <ng-template> is a Template, the `*` attributes of an element
+		// put it in Templates
+		templates := directiveTemplates(element)
+		var node Node = element
+		var ngTemplate *Template
+		if schema.IsNgTemplate(strings.ToLower(element.Name)) {
+			ngTemplate = &Template{
+				TagName:         element.Name,
+				Attributes:      element.Attributes,
+				Inputs:          element.Inputs,
+				Outputs:         element.Outputs,
+				References:      element.References,
+				SelfClosing:     element.SelfClosing,
+				SourceSpan:      element.SourceSpan,
+				StartSourceSpan: element.StartSourceSpan,
+			}
+			node = ngTemplate
+		}
+
+		for _, template := range templates {
+			tokenizer.enter(template)
+		}
+		tokenizer.enter(node)
+		tokenType = tokenizer.Next()
+
+		if token.Type != html.SelfClosingTagToken && !isVoidElement(token.Data) {
+			for tokenType != html.EndTagToken && tokenType != html.ErrorToken {
+				token = tokenizer.Token()
+				element.Children = tokenizer.appendChild(element.Children, walk(tokenizer, token))
+				tokenType = tokenizer.Token().Type
+			}
+
+			if tokenType == html.EndTagToken {
+				endSpan := tokenizer.span()
+				element.EndSourceSpan = &endSpan
+			}
+			element.SourceSpan.End = tokenizer.span().End
+
+			tokenizer.Next()
+		}
+
+		if ngTemplate != nil {
+			ngTemplate.Children = element.Children
+			ngTemplate.SourceSpan = element.SourceSpan
+			ngTemplate.EndSourceSpan = element.EndSourceSpan
+		}
+		tokenizer.leave(node)
+
+		for i := len(templates) - 1; i >= 0; i-- {
+			template := templates[i]
+			template.Children = tokenizer.appendChild(nil, node)
+			template.SourceSpan = element.SourceSpan
+			// nodes don't share spans, ApplyEdit moves them in place
+			if element.EndSourceSpan != nil {
+				endSpan := *element.EndSourceSpan
+				template.EndSourceSpan = &endSpan
+			}
+			tokenizer.leave(template)
+			node = template
+		}
+		return node
+	}
+
+	// doctype and stray end tags
+	tokenizer.Next()
+
+	return nil
+}
+
+// directiveTemplates takes the `*` attributes off an element and returns a
+// Template for each of them, the spans of their ends are set by the caller.
+func directiveTemplates(element *Element) []*Template {
+	var templates []*Template
+	var attributes []TextAttribute
+	for _, attr := range element.Attributes {
+		if len(attr.Name) > 1 && strings.HasPrefix(attr.Name, "*") {
+			attr := attr
+			templates = append(templates, &Template{
+				TagName:         element.Name,
+				TemplateAttr:    &attr,
+				SourceSpan:      element.SourceSpan,
+				StartSourceSpan: element.StartSourceSpan,
+			})
+		} else {
+			attributes = append(attributes, attr)
+		}
+	}
+
+	if templates != nil {
+		element.Attributes = attributes
+	}
+	return templates
+}
+
+func isVoidElement(name string) bool {
+	switch name {
+	case "area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "param", "source", "track", "wbr":
+		return true
+	}
+	return false
+}
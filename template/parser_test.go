@@ -0,0 +1,62 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUnfinishedBindingKeys(t *testing.T) {
+	for _, key := range []string{"[", "(", "[(", "[(x", "[x", "(x", "[(x)", "#"} {
+		src := "<p " + key + "></p>"
+		root := Parse(strings.NewReader(src), "")
+		if len(root.Nodes) != 1 {
+			t.Errorf("%q parses into %d nodes", src, len(root.Nodes))
+			continue
+		}
+		element := root.Nodes[0].(*Element)
+		if key != "#" && (len(element.Attributes) != 1 || element.Attributes[0].Name != key) {
+			t.Errorf("%q has attributes %+v, want the text attribute %q", src, element.Attributes, key)
+		}
+	}
+}
+
+func TestParseTemplates(t *testing.T) {
+	src := `<li *ngIf="items" class="a" *ngFor="let item of items">{{ item }}</li>` +
+		`<ng-template #empty let-reason><b>{{ reason }}</b></ng-template>`
+	root := Parse(strings.NewReader(src), "")
+	if len(root.Nodes) != 2 {
+		t.Fatalf("%q parses into %d nodes", src, len(root.Nodes))
+	}
+
+	// the first `*` attribute is the outermost template
+	ngIf, ok := root.Nodes[0].(*Template)
+	if !ok || ngIf.TemplateAttr == nil || ngIf.TemplateAttr.Name != "*ngIf" || len(ngIf.Children) != 1 {
+		t.Fatalf("got %#v, want the template of *ngIf", root.Nodes[0])
+	}
+	ngFor, ok := ngIf.Children[0].(*Template)
+	if !ok || ngFor.TemplateAttr == nil || ngFor.TemplateAttr.Name != "*ngFor" || len(ngFor.Children) != 1 {
+		t.Fatalf("got %#v, want the template of *ngFor", ngIf.Children[0])
+	}
+	li, ok := ngFor.Children[0].(*Element)
+	if !ok || len(li.Attributes) != 1 || li.Attributes[0].Name != "class" {
+		t.Fatalf("got %#v, want <li> with its class", ngFor.Children[0])
+	}
+	if ngIf.SourceSpan != li.SourceSpan || ngFor.EndSourceSpan == li.EndSourceSpan || *ngFor.EndSourceSpan != *li.EndSourceSpan {
+		t.Errorf("the templates don't have the spans of <li>, in spans of their own")
+	}
+	if attrs := ngIf.Element().Attributes; len(attrs) != 3 || attrs[0].Name != "*ngIf" || attrs[1].Name != "class" || attrs[2].Name != "*ngFor" {
+		t.Errorf("the element of the templates has the attributes %+v", attrs)
+	}
+
+	ngTemplate, ok := root.Nodes[1].(*Template)
+	if !ok || ngTemplate.TemplateAttr != nil || len(ngTemplate.References) != 1 || len(ngTemplate.Children) != 1 {
+		t.Fatalf("got %#v, want the <ng-template>", root.Nodes[1])
+	}
+	if _, ok := ngTemplate.Children[0].(*Element); !ok {
+		t.Errorf("the <ng-template> has the children %#v", ngTemplate.Children)
+	}
+
+	if got := Serialize(root, SerializeLossless); got != src {
+		t.Errorf("Serialize(%q) = %q", src, got)
+	}
+}
@@ -0,0 +1,128 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+// https://github.com/angular/angular/tree/master/packages/common/src/pipes
+
+// RegisterCommonPipes registers the pipes of CommonModule which don't depend
+// on a locale: uppercase, lowercase, titlecase, json and slice.
+func RegisterCommonPipes(evaluator *ep.Evaluator) {
+	evaluator.RegisterPipe("uppercase", ep.PipeFunc(stringPipe("UpperCasePipe", strings.ToUpper)))
+	evaluator.RegisterPipe("lowercase", ep.PipeFunc(stringPipe("LowerCasePipe", strings.ToLower)))
+	evaluator.RegisterPipe("titlecase", ep.PipeFunc(stringPipe("TitleCasePipe", titleCase)))
+	evaluator.RegisterPipe("json", ep.PipeFunc(jsonPipe))
+	evaluator.RegisterPipe("slice", ep.PipeFunc(slicePipe))
+}
+
+// stringPipe returns a pipe which transforms strings, null and undefined
+// become null.
+func stringPipe(name string, transform func(string) string) func(value interface{}, args ...interface{}) (interface{}, error) {
+	return func(value interface{}, args ...interface{}) (interface{}, error) {
+		if ep.IsNullish(value) {
+			return nil, nil
+		}
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("InvalidPipeArgument: '%s' for pipe '%s'", ep.ToString(value), name)
+		}
+		return transform(str), nil
+	}
+}
+
+// titleCase capitalizes the first letter of every word and lower cases the rest.
+func titleCase(str string) string {
+	var out strings.Builder
+	start := true
+	for _, c := range str {
+		if unicode.IsSpace(c) {
+			start = true
+			out.WriteRune(c)
+			continue
+		}
+		if start {
+			out.WriteRune(unicode.ToUpper(c))
+		} else {
+			out.WriteRune(unicode.ToLower(c))
+		}
+		start = false
+	}
+	return out.String()
+}
+
+func jsonPipe(value interface{}, args ...interface{}) (interface{}, error) {
+	if value == ep.Undefined {
+		return nil, nil
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// slicePipe returns a part of an array or string, with negative indexes
+// counting from the end like Array.prototype.slice.
+func slicePipe(value interface{}, args ...interface{}) (interface{}, error) {
+	if ep.IsNullish(value) {
+		return value, nil
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("The pipe 'slice' requires at least 1 argument")
+	}
+
+	if str, ok := value.(string); ok {
+		runes := []rune(str)
+		start, end := sliceBounds(len(runes), args)
+		return string(runes[start:end]), nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("InvalidPipeArgument: '%s' for pipe 'SlicePipe'", ep.ToString(value))
+	}
+
+	start, end := sliceBounds(v.Len(), args)
+	items := make([]interface{}, 0, end-start)
+	for i := start; i < end; i++ {
+		items = append(items, v.Index(i).Interface())
+	}
+	return items, nil
+}
+
+func sliceBounds(length int, args []interface{}) (int, int) {
+	bound := func(arg interface{}) int {
+		n := ep.ToNumber(arg)
+		if math.IsNaN(n) {
+			return 0
+		}
+		i := int(n)
+		if i < 0 {
+			i += length
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > length {
+			return length
+		}
+		return i
+	}
+
+	start, end := bound(args[0]), length
+	if len(args) > 1 && !ep.IsNullish(args[1]) {
+		end = bound(args[1])
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
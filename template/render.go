@@ -0,0 +1,1087 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/irustm/ng-template-parser/ep"
+	"github.com/irustm/ng-template-parser/schema"
+)
+
+// Renderer renders parsed templates to static HTML, like a server-side render
+// of a component without its child components and directives. Interpolations
+// and bindings to DOM properties, attributes, classes and styles are
+// evaluated, control flow blocks and the structural directives ngIf, ngFor,
+// ngSwitch and ngTemplateOutlet are executed, and events are dropped.
+type Renderer struct {
+	Registry  *schema.DomElementSchemaRegistry
+	Evaluator *ep.Evaluator
+}
+
+func NewRenderer(registry *schema.DomElementSchemaRegistry, evaluator *ep.Evaluator) *Renderer {
+	return &Renderer{Registry: registry, Evaluator: evaluator}
+}
+
+// Render renders a template against a context with the pipes registered by
+// RegisterCommonPipes.
+func Render(root Root, context interface{}, registry *schema.DomElementSchemaRegistry) (string, error) {
+	evaluator := ep.NewEvaluator()
+	RegisterCommonPipes(evaluator)
+
+	return NewRenderer(registry, evaluator).Render(root, context)
+}
+
+// Render renders a template, the names in its expressions are read from
+// context. Templates with expression errors are not rendered.
+func (r *Renderer) Render(root Root, context interface{}) (string, error) {
+	if len(root.Errors) > 0 {
+		return "", root.Errors[0]
+	}
+
+	rendering := &rendering{Renderer: r, parser: ep.NewParser(), templates: map[string]interface{}{}}
+	collectTemplates(root.Nodes, rendering.templates)
+
+	err := rendering.renderNodes(root.Nodes, ep.NewScope(context, rendering.templates), "", textCollapsed)
+	if err != nil {
+		return "", err
+	}
+
+	return rendering.out.String(), nil
+}
+
+// templateRef is the value of a reference to an <ng-template>.
+type templateRef struct {
+	template *Template
+}
+
+// collectTemplates adds the <ng-template> elements with a reference to
+// templates, they are visible everywhere in the template.
+func collectTemplates(nodes []Node, templates map[string]interface{}) {
+	for _, node := range nodes {
+		Walk(node, func(node Node) bool {
+			if template, ok := node.(*Template); ok && template.TemplateAttr == nil {
+				for _, ref := range template.References {
+					templates[ref.Name] = &templateRef{template: template}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// textMode is how the text in an element is rendered.
+type textMode int
+
+const (
+	// textCollapsed drops whitespace only text and collapses whitespace, like
+	// Angular does unless whitespace is preserved.
+	textCollapsed textMode = iota
+	textPreserved
+	// textRaw is the content of e.g. <xmp>, which browsers don't unescape.
+	// The template text is written as it is, interpolated values can't be
+	// escaped in it and are refused.
+	textRaw
+)
+
+type rendering struct {
+	*Renderer
+	out       strings.Builder
+	parser    *ep.Parser
+	templates map[string]interface{}
+	// switches are the values of the enclosing [ngSwitch] elements.
+	switches []*ngSwitch
+}
+
+type ngSwitch struct {
+	value   interface{}
+	matched bool
+}
+
+func (r *rendering) evaluate(ast *ep.ASTWithSource, scope interface{}) (interface{}, error) {
+	value, err := r.Evaluator.Evaluate(ast, scope)
+	if err != nil {
+		return nil, expressionError(err, ast)
+	}
+	return value, nil
+}
+
+// expressionError adds the expression and its location to an error.
+func expressionError(err error, ast *ep.ASTWithSource) error {
+	if ast.Location != "" {
+		return fmt.Errorf("%w in [%s] in %s", err, ast.Source, ast.Location)
+	}
+	return fmt.Errorf("%w in [%s]", err, ast.Source)
+}
+
+func (r *rendering) renderNodes(nodes []Node, scope interface{}, namespace string, mode textMode) error {
+	for i := 0; i < len(nodes); i++ {
+		var err error
+
+		switch node := nodes[i].(type) {
+		case *Text:
+			r.renderText(node.Value, mode)
+		case *BoundText:
+			err = r.renderBoundText(node, scope, mode)
+		case *Element:
+			err = r.renderElement(node, scope, namespace)
+		case *Template:
+			if node.TemplateAttr != nil {
+				err = r.renderStructuralDirective(node, scope, namespace)
+			} else {
+				err = r.renderNgTemplate(node, scope, namespace)
+			}
+		case *Block:
+			i, err = r.renderBlock(nodes, i, scope, namespace)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *rendering) renderText(text string, mode textMode) {
+	switch mode {
+	case textRaw:
+		r.out.WriteString(text)
+	case textPreserved:
+		r.out.WriteString(escapeRenderedText(text))
+	default:
+		if strings.Trim(text, " \t\n\r\f\v") != "" {
+			r.out.WriteString(escapeRenderedText(collapseWhitespace(text)))
+		}
+	}
+}
+
+func (r *rendering) renderBoundText(text *BoundText, scope interface{}, mode textMode) error {
+	if mode == textRaw {
+		return expressionError(fmt.Errorf("Interpolation in an element whose text can't be escaped"), text.Value)
+	}
+
+	interpolation, ok := text.Value.Ast.(*ep.Interpolation)
+	if !ok {
+		value, err := r.evaluate(text.Value, scope)
+		if err == nil {
+			r.renderText(ep.InterpolationString(value), textPreserved)
+		}
+		return err
+	}
+
+	// whitespace is collapsed in the text around the expressions only
+	var out strings.Builder
+	for i, str := range interpolation.Strings {
+		if mode == textCollapsed {
+			str = collapseWhitespace(str)
+		}
+		out.WriteString(escapeRenderedText(str))
+
+		if i < len(interpolation.Expressions) {
+			value, err := r.Evaluator.Evaluate(interpolation.Expressions[i], scope)
+			if err != nil {
+				return expressionError(err, text.Value)
+			}
+			out.WriteString(escapeRenderedText(ep.InterpolationString(value)))
+		}
+	}
+	r.out.WriteString(out.String())
+
+	return nil
+}
+
+// connectedBlocks returns the blocks which continue a block, e.g. the @else
+// blocks of an @if, and the index of the last one in nodes.
+func connectedBlocks(nodes []Node, index int) ([]*Block, int) {
+	block := nodes[index].(*Block)
+	chain := []*Block{block}
+	last := index
+
+	for i := index + 1; i < len(nodes); i++ {
+		if text, ok := nodes[i].(*Text); ok && strings.Trim(text.Value, " \t\n\r\f\v") == "" {
+			continue
+		}
+		next, ok := nodes[i].(*Block)
+		if !ok || !continuesBlock(block.Name, next.Name) || chain[len(chain)-1].Name == "else" {
+			break
+		}
+		chain = append(chain, next)
+		last = i
+	}
+
+	return chain, last
+}
+
+func continuesBlock(name string, next string) bool {
+	switch name {
+	case "if":
+		return next == "else if" || next == "else"
+	case "for":
+		return next == "empty"
+	case "defer":
+		return next == "placeholder" || next == "loading" || next == "error"
+	}
+	return false
+}
+
+// renderBlock renders the block at index together with its connected blocks
+// and returns the index of the last one.
+func (r *rendering) renderBlock(nodes []Node, index int, scope interface{}, namespace string) (int, error) {
+	chain, last := connectedBlocks(nodes, index)
+	block := chain[0]
+
+	switch block.Name {
+	case "if":
+		for _, branch := range chain {
+			if branch.Name == "else" {
+				return last, r.renderNodes(branch.Children, scope, namespace, textCollapsed)
+			}
+			if len(branch.Parameters) == 0 {
+				return last, fmt.Errorf("@%s block must have a condition", branch.Name)
+			}
+
+			condition, err := r.evaluateBlockParameter(branch.Parameters[0].Expression, branch.Parameters[0].SourceSpan.Start, scope)
+			if err != nil {
+				return last, err
+			}
+			if !ep.IsTruthy(condition) {
+				continue
+			}
+
+			branchScope := scope
+			for _, parameter := range branch.Parameters[1:] {
+				if alias := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(parameter.Expression), "as")); alias != "" {
+					branchScope = ep.NewScope(scope, map[string]interface{}{alias: condition})
+				}
+			}
+			return last, r.renderNodes(branch.Children, branchScope, namespace, textCollapsed)
+		}
+	case "for":
+		return last, r.renderForBlock(chain, scope, namespace)
+	case "switch":
+		return last, r.renderSwitchBlock(block, scope, namespace)
+	case "defer":
+		// the placeholder is what is rendered on the server
+		for _, branch := range chain[1:] {
+			if branch.Name == "placeholder" {
+				return last, r.renderNodes(branch.Children, scope, namespace, textCollapsed)
+			}
+		}
+	default:
+		return last, r.renderNodes(block.Children, scope, namespace, textCollapsed)
+	}
+
+	return last, nil
+}
+
+func (r *rendering) evaluateBlockParameter(expression string, offset int, scope interface{}) (interface{}, error) {
+	ast := r.parser.ParseBinding(expression, "", offset)
+	if len(ast.Errors) > 0 {
+		return nil, ast.Errors[0]
+	}
+	return r.evaluate(ast, scope)
+}
+
+func (r *rendering) renderForBlock(chain []*Block, scope interface{}, namespace string) error {
+	block := chain[0]
+	if len(block.Parameters) == 0 {
+		return fmt.Errorf("@for loop must have an \"item of items\" expression")
+	}
+
+	expression := block.Parameters[0]
+	match := forOfRegexp.FindStringSubmatchIndex(expression.Expression)
+	if match == nil {
+		return fmt.Errorf("Cannot parse expression. @for loop expression must match the pattern \"<identifier> of <expression>\"")
+	}
+	itemName := expression.Expression[match[2]:match[3]]
+
+	iterable, err := r.evaluateBlockParameter(expression.Expression[match[4]:], expression.SourceSpan.Start+match[4], scope)
+	if err != nil {
+		return err
+	}
+	items, err := iterate(iterable)
+	if err != nil {
+		return err
+	}
+
+	// let i = $index, odd = $odd
+	aliases := map[string]string{}
+	for _, parameter := range block.Parameters[1:] {
+		if !hasWordAt(parameter.Expression, 0, "let") {
+			continue
+		}
+		for _, alias := range strings.Split(parameter.Expression[3:], ",") {
+			if parts := strings.SplitN(alias, "=", 2); len(parts) == 2 {
+				aliases[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			}
+		}
+	}
+
+	if len(items) == 0 {
+		for _, branch := range chain[1:] {
+			if branch.Name == "empty" {
+				return r.renderNodes(branch.Children, scope, namespace, textCollapsed)
+			}
+		}
+		return nil
+	}
+
+	for i, item := range items {
+		locals := map[string]interface{}{
+			itemName: item,
+			"$index": i,
+			"$count": len(items),
+			"$first": i == 0,
+			"$last":  i == len(items)-1,
+			"$even":  i%2 == 0,
+			"$odd":   i%2 == 1,
+		}
+		for alias, variable := range aliases {
+			locals[alias] = locals[variable]
+		}
+
+		if err := r.renderNodes(block.Children, ep.NewScope(scope, locals), namespace, textCollapsed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// iterate returns the items of an array or the characters of a string, null
+// and undefined have no items.
+func iterate(value interface{}) ([]interface{}, error) {
+	if ep.IsNullish(value) {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).Interface()
+		}
+		return items, nil
+	case reflect.String:
+		var items []interface{}
+		for _, c := range v.String() {
+			items = append(items, string(c))
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("Cannot iterate over %s, only arrays and strings are supported", ep.ToString(value))
+}
+
+func (r *rendering) renderSwitchBlock(block *Block, scope interface{}, namespace string) error {
+	if len(block.Parameters) == 0 {
+		return fmt.Errorf("@switch block must have a parameter")
+	}
+	value, err := r.evaluateBlockParameter(block.Parameters[0].Expression, block.Parameters[0].SourceSpan.Start, scope)
+	if err != nil {
+		return err
+	}
+
+	var defaultCase *Block
+	for _, child := range block.Children {
+		switchCase, ok := child.(*Block)
+		if !ok {
+			continue
+		}
+
+		if switchCase.Name == "default" {
+			defaultCase = switchCase
+			continue
+		}
+		if switchCase.Name != "case" || len(switchCase.Parameters) == 0 {
+			continue
+		}
+
+		caseValue, err := r.evaluateBlockParameter(switchCase.Parameters[0].Expression, switchCase.Parameters[0].SourceSpan.Start, scope)
+		if err != nil {
+			return err
+		}
+		if ep.StrictEquals(value, caseValue) {
+			return r.renderNodes(switchCase.Children, scope, namespace, textCollapsed)
+		}
+	}
+
+	if defaultCase != nil {
+		return r.renderNodes(defaultCase.Children, scope, namespace, textCollapsed)
+	}
+	return nil
+}
+
+func (r *rendering) renderElement(element *Element, scope interface{}, namespace string) error {
+	name := strings.ToLower(element.Name)
+	if schema.IsNgContent(name) {
+		return nil
+	}
+	// Angular removes <script> from templates and moves <style> to the styles
+	// of the component
+	if name == "script" || name == "style" {
+		return nil
+	}
+
+	for _, input := range element.Inputs {
+		if input.Name == "ngSwitch" && input.BindingType == BindingTypeProperty {
+			value, err := r.evaluate(input.Value, scope)
+			if err != nil {
+				return err
+			}
+			matched, err := r.switchCaseMatches(element.Children, value, scope)
+			if err != nil {
+				return err
+			}
+
+			r.switches = append(r.switches, &ngSwitch{value: value, matched: matched})
+			defer func() { r.switches = r.switches[:len(r.switches)-1] }()
+		}
+	}
+
+	if schema.IsNgContainer(name) {
+		return r.renderNodes(element.Children, scope, namespace, textCollapsed)
+	}
+
+	elementName, childNamespace := schemaElementName(element.Name, namespace)
+	if strings.EqualFold(element.Name, "foreignObject") {
+		childNamespace = ""
+	}
+
+	attrs, content, err := r.renderAttributes(element, elementName, namespace, scope)
+	if err != nil {
+		return err
+	}
+
+	r.out.WriteString("<" + element.Name + attrs + ">")
+	if isVoidElement(name) {
+		return nil
+	}
+
+	if content != nil {
+		r.out.WriteString(*content)
+	} else {
+		mode := textCollapsed
+		switch {
+		case isRawTextElement(name) && !isEscapableRawTextElement(name):
+			mode = textRaw
+		case name == "pre" || name == "textarea" || name == "listing":
+			mode = textPreserved
+		}
+		for _, attr := range element.Attributes {
+			if attr.Name == "ngPreserveWhitespaces" {
+				mode = textPreserved
+			}
+		}
+
+		if err := r.renderNodes(element.Children, scope, childNamespace, mode); err != nil {
+			return err
+		}
+	}
+
+	r.out.WriteString("</" + element.Name + ">")
+
+	return nil
+}
+
+// switchCaseMatches reports whether one of the ngSwitchCase templates in the
+// children of an [ngSwitch] element matches its value, otherwise
+// ngSwitchDefault is rendered.
+func (r *rendering) switchCaseMatches(children []Node, value interface{}, scope interface{}) (bool, error) {
+	for _, child := range children {
+		template, ok := child.(*Template)
+		if !ok {
+			continue
+		}
+
+		var caseValue *ep.ASTWithSource
+		if attr := template.TemplateAttr; attr != nil && attr.Name == "*ngSwitchCase" {
+			bindings, errors := ParseTemplateBindings("ngSwitchCase", attr.Value, "", attributeValueOffset(*attr), r.parser)
+			if len(errors) > 0 {
+				return false, errors[0]
+			}
+			caseValue = bindings[0].Value
+		}
+		for _, input := range template.Inputs {
+			if input.Name == "ngSwitchCase" {
+				caseValue = input.Value
+			}
+		}
+		if caseValue == nil {
+			continue
+		}
+
+		evaluated, err := r.evaluate(caseValue, scope)
+		if err != nil {
+			return false, err
+		}
+		if ep.StrictEquals(value, evaluated) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func attributeValueOffset(attr TextAttribute) int {
+	if attr.ValueSpan != nil {
+		return attr.ValueSpan.Start
+	}
+	return attr.SourceSpan.Start
+}
+
+// renderStructuralDirective renders the template of a `*` attribute as the
+// embedded views of the directive.
+func (r *rendering) renderStructuralDirective(template *Template, scope interface{}, namespace string) error {
+	attr := *template.TemplateAttr
+	directive := attr.Name[1:]
+
+	bindings, errors := ParseTemplateBindings(directive, attr.Value, "", attributeValueOffset(attr), r.parser)
+	if len(errors) > 0 {
+		return errors[0]
+	}
+
+	inputs := map[string]*ep.ASTWithSource{}
+	var variables []TemplateBinding
+	for _, binding := range bindings {
+		if binding.IsVariable() {
+			variables = append(variables, binding)
+		} else if binding.Value != nil {
+			inputs[binding.Key] = binding.Value
+		}
+	}
+
+	return r.renderDirective(directive, inputs, variables, scope, func(viewScope interface{}) error {
+		return r.renderNodes(template.Children, viewScope, namespace, textCollapsed)
+	})
+}
+
+// renderNgTemplate renders an <ng-template> with a structural directive,
+// like `<ng-template ngFor let-item [ngForOf]="items">`. Other templates are
+// only rendered where they are used.
+func (r *rendering) renderNgTemplate(template *Template, scope interface{}, namespace string) error {
+	inputs := map[string]*ep.ASTWithSource{}
+	for _, input := range template.Inputs {
+		if input.BindingType == BindingTypeProperty {
+			inputs[input.Name] = input.Value
+		}
+	}
+
+	directive := ""
+	switch {
+	case inputs["ngIf"] != nil:
+		directive = "ngIf"
+	case inputs["ngForOf"] != nil:
+		directive = "ngFor"
+	case inputs["ngSwitchCase"] != nil:
+		directive = "ngSwitchCase"
+	case inputs["ngTemplateOutlet"] != nil:
+		directive = "ngTemplateOutlet"
+	}
+	for _, attr := range template.Attributes {
+		if attr.Name == "ngSwitchDefault" {
+			directive = "ngSwitchDefault"
+		}
+	}
+	if directive == "" {
+		return nil
+	}
+
+	return r.renderDirective(directive, inputs, templateVariables(template), scope, func(viewScope interface{}) error {
+		return r.renderNodes(template.Children, viewScope, namespace, textCollapsed)
+	})
+}
+
+// templateVariables returns the `let-item="value"` variables of an <ng-template>.
+func templateVariables(template *Template) []TemplateBinding {
+	var variables []TemplateBinding
+	for _, attr := range template.Attributes {
+		if strings.HasPrefix(attr.Name, "let-") {
+			variable := attr.Value
+			if variable == "" {
+				variable = "$implicit"
+			}
+			variables = append(variables, TemplateBinding{Key: attr.Name[4:], Variable: variable})
+		}
+	}
+	return variables
+}
+
+// viewScope returns the scope of an embedded view, its variables read the
+// properties of the template context.
+func viewScope(scope interface{}, variables []TemplateBinding, context interface{}) interface{} {
+	if len(variables) == 0 {
+		return scope
+	}
+
+	contextScope := ep.NewScope(context, nil)
+	locals := map[string]interface{}{}
+	for _, variable := range variables {
+		value, ok := contextScope.Get(variable.Variable)
+		if !ok {
+			value = ep.Undefined
+		}
+		locals[variable.Key] = value
+	}
+	return ep.NewScope(scope, locals)
+}
+
+// renderDirective renders the embedded views of a structural directive, body
+// renders one view. Directives other than the ones of CommonModule render a
+// single view.
+func (r *rendering) renderDirective(directive string, inputs map[string]*ep.ASTWithSource, variables []TemplateBinding,
+	scope interface{}, body func(scope interface{}) error) error {
+	evaluate := func(input string) (interface{}, error) {
+		if inputs[input] == nil {
+			return ep.Undefined, nil
+		}
+		return r.evaluate(inputs[input], scope)
+	}
+
+	switch directive {
+	case "ngIf":
+		condition, err := evaluate("ngIf")
+		if err != nil {
+			return err
+		}
+		context := map[string]interface{}{"$implicit": condition, "ngIf": condition}
+
+		template := "ngIfElse"
+		if ep.IsTruthy(condition) {
+			if inputs["ngIfThen"] == nil {
+				return body(viewScope(scope, variables, context))
+			}
+			template = "ngIfThen"
+		}
+
+		ref, err := evaluate(template)
+		if err != nil {
+			return err
+		}
+		return r.renderTemplateRef(ref, context, scope)
+	case "ngFor":
+		iterable, err := evaluate("ngForOf")
+		if err != nil {
+			return err
+		}
+		items, err := iterate(iterable)
+		if err != nil {
+			return err
+		}
+
+		for i, item := range items {
+			context := map[string]interface{}{
+				"$implicit": item,
+				"ngForOf":   iterable,
+				"index":     i,
+				"count":     len(items),
+				"first":     i == 0,
+				"last":      i == len(items)-1,
+				"even":      i%2 == 0,
+				"odd":       i%2 == 1,
+			}
+			if err := body(viewScope(scope, variables, context)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "ngSwitchCase", "ngSwitchDefault":
+		if len(r.switches) == 0 {
+			return fmt.Errorf("An element with the \"%s\" attribute must be located inside an element with the \"ngSwitch\" attribute", directive)
+		}
+		ngSwitch := r.switches[len(r.switches)-1]
+
+		if directive == "ngSwitchDefault" {
+			if ngSwitch.matched {
+				return nil
+			}
+			return body(viewScope(scope, variables, nil))
+		}
+
+		value, err := evaluate("ngSwitchCase")
+		if err != nil || !ep.StrictEquals(ngSwitch.value, value) {
+			return err
+		}
+		return body(viewScope(scope, variables, nil))
+	case "ngTemplateOutlet":
+		ref, err := evaluate("ngTemplateOutlet")
+		if err != nil {
+			return err
+		}
+		context, err := evaluate("ngTemplateOutletContext")
+		if err != nil {
+			return err
+		}
+		return r.renderTemplateRef(ref, context, scope)
+	default:
+		return body(viewScope(scope, variables, nil))
+	}
+}
+
+// renderTemplateRef renders the <ng-template> a reference points to, null
+// renders nothing.
+func (r *rendering) renderTemplateRef(ref interface{}, context interface{}, scope interface{}) error {
+	if ep.IsNullish(ref) {
+		return nil
+	}
+
+	template, ok := ref.(*templateRef)
+	if !ok {
+		return fmt.Errorf("%s is not a reference to an <ng-template>", ep.ToString(ref))
+	}
+
+	return r.renderNodes(template.template.Children, viewScope(scope, templateVariables(template.template), context), "", textCollapsed)
+}
+
+// renderedAttributes keeps the attributes of an element in order, the class
+// and style attributes are assembled from their parts at the end.
+type renderedAttributes struct {
+	names  []string
+	values map[string]*string
+
+	classes []string
+	styles  []string
+	style   map[string]string
+}
+
+func (a *renderedAttributes) add(name string) {
+	if _, ok := a.values[name]; !ok {
+		a.names = append(a.names, name)
+		a.values[name] = nil
+	}
+}
+
+func (a *renderedAttributes) set(name string, value string) {
+	a.add(name)
+	a.values[name] = &value
+}
+
+// setBoolean sets an attribute without value, like `disabled`.
+func (a *renderedAttributes) setBoolean(name string) {
+	a.add(name)
+	a.values[name] = nil
+}
+
+func (a *renderedAttributes) remove(name string) {
+	for i, existing := range a.names {
+		if existing == name {
+			a.names = append(a.names[:i], a.names[i+1:]...)
+			delete(a.values, name)
+			return
+		}
+	}
+}
+
+func (a *renderedAttributes) setClass(class string, enabled bool) {
+	a.add("class")
+	for i, existing := range a.classes {
+		if existing == class {
+			if !enabled {
+				a.classes = append(a.classes[:i], a.classes[i+1:]...)
+			}
+			return
+		}
+	}
+	if enabled {
+		a.classes = append(a.classes, class)
+	}
+}
+
+// setClasses applies the value of `[class]` or `[ngClass]`: a string of
+// classes, an array of them or an object of classes to booleans.
+func (a *renderedAttributes) setClasses(value interface{}) {
+	if ep.IsNullish(value) {
+		return
+	}
+
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.String:
+		for _, class := range strings.Fields(v.String()) {
+			a.setClass(class, true)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			a.setClasses(ep.ToString(v.Index(i).Interface()))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			for _, class := range strings.Fields(key.String()) {
+				a.setClass(class, ep.IsTruthy(v.MapIndex(key).Interface()))
+			}
+		}
+	}
+}
+
+// setStyle sets a style property, with the unit of `[style.width.px]`. null,
+// undefined and "" remove it.
+func (a *renderedAttributes) setStyle(name string, value interface{}) {
+	a.add("style")
+	property, unit := name, ""
+	if dot := strings.IndexByte(name, '.'); dot != -1 {
+		property, unit = name[:dot], name[dot+1:]
+	}
+	property = hyphenate(property)
+
+	if ep.IsNullish(value) || ep.ToString(value) == "" {
+		if _, ok := a.style[property]; ok {
+			delete(a.style, property)
+			for i, existing := range a.styles {
+				if existing == property {
+					a.styles = append(a.styles[:i], a.styles[i+1:]...)
+					break
+				}
+			}
+		}
+		return
+	}
+
+	if _, ok := a.style[property]; !ok {
+		a.styles = append(a.styles, property)
+	}
+	a.style[property] = ep.ToString(value) + unit
+}
+
+// setStyles applies the value of `[style]` or `[ngStyle]`, a string of
+// declarations or an object of properties to values.
+func (a *renderedAttributes) setStyles(value interface{}) {
+	if ep.IsNullish(value) {
+		return
+	}
+
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.String:
+		for _, declaration := range strings.Split(v.String(), ";") {
+			if parts := strings.SplitN(declaration, ":", 2); len(parts) == 2 {
+				a.setStyle(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			a.setStyle(key.String(), v.MapIndex(key).Interface())
+		}
+	}
+}
+
+func (a *renderedAttributes) String() string {
+	var out strings.Builder
+
+	for _, name := range a.names {
+		value := a.values[name]
+		switch name {
+		case "class":
+			if len(a.classes) == 0 {
+				continue
+			}
+			class := strings.Join(a.classes, " ")
+			value = &class
+		case "style":
+			if len(a.styles) == 0 {
+				continue
+			}
+			declarations := make([]string, len(a.styles))
+			for i, property := range a.styles {
+				declarations[i] = property + ": " + a.style[property] + ";"
+			}
+			style := strings.Join(declarations, " ")
+			value = &style
+		}
+
+		out.WriteString(" " + name)
+		if value != nil {
+			out.WriteString(`="` + escapeRenderedAttribute(*value) + `"`)
+		}
+	}
+
+	return out.String()
+}
+
+// hyphenate turns a camel case style property into its CSS name, e.g.
+// fontSize into font-size.
+func hyphenate(name string) string {
+	var out strings.Builder
+	for i, c := range name {
+		if unicode.IsUpper(c) && i > 0 {
+			out.WriteByte('-')
+			c = unicode.ToLower(c)
+		}
+		out.WriteRune(c)
+	}
+	return out.String()
+}
+
+// propertyAttributes are the attributes of DOM properties whose name isn't
+// the lower case property name.
+var propertyAttributes = map[string]string{
+	"className":     "class",
+	"htmlFor":       "for",
+	"acceptCharset": "accept-charset",
+	"httpEquiv":     "http-equiv",
+}
+
+// renderAttributes returns the attributes of an element and the content set
+// by `[innerHTML]` or `[textContent]`.
+func (r *rendering) renderAttributes(element *Element, elementName string, namespace string, scope interface{}) (string, *string, error) {
+	attrs := &renderedAttributes{values: map[string]*string{}, style: map[string]string{}}
+	var content *string
+
+	// an attribute with interpolations binds its property, like
+	// `title="{{name}}"` is `[title]="'' + name"`, or the attribute without a
+	// property of its name, like `aria-label="{{name}}"`
+	var inputs []BoundAttribute
+	interpolated := map[string]bool{}
+	for _, attr := range element.Attributes {
+		if !strings.Contains(attr.Value, "{{") || isRenderInstruction(attr.Name) {
+			continue
+		}
+		ast := r.parser.ParseInterpolation(attr.Value, "", attributeValueOffset(attr))
+		if ast == nil {
+			continue
+		}
+		if len(ast.Errors) > 0 {
+			return "", nil, ast.Errors[0]
+		}
+		bindingType := BindingTypeProperty
+		if _, ok := r.Registry.GetPropertyType(elementName, r.Registry.GetMappedPropName(attr.Name)); !ok {
+			bindingType = BindingTypeAttribute
+		}
+		inputs = append(inputs, BoundAttribute{Name: attr.Name, BindingType: bindingType, Value: ast,
+			SourceSpan: attr.SourceSpan, KeySpan: attr.KeySpan, ValueSpan: attr.ValueSpan})
+		interpolated[attr.Name] = true
+	}
+	inputs = append(inputs, element.Inputs...)
+
+	for _, attr := range element.Attributes {
+		switch {
+		case attr.Name == "class":
+			attrs.setClasses(attr.Value)
+		case attr.Name == "style":
+			attrs.setStyles(attr.Value)
+		case isRenderInstruction(attr.Name):
+			// compiler instructions, Angular removes them
+		case interpolated[attr.Name]:
+			// bound above
+		case attr.ValueSpan == nil && attr.Value == "":
+			attrs.setBoolean(attr.Name)
+		default:
+			attrs.set(attr.Name, attr.Value)
+		}
+	}
+
+	for _, input := range inputs {
+		if input.BindingType == BindingTypeAnimation {
+			continue
+		}
+		if input.BindingType == BindingTypeAttribute {
+			if message, disallowed := r.Registry.ValidateAttribute(input.Name); disallowed {
+				return "", nil, expressionError(fmt.Errorf("%s", message), input.Value)
+			}
+		}
+
+		value, err := r.evaluate(input.Value, scope)
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch input.BindingType {
+		case BindingTypeClass:
+			attrs.setClass(input.Name, ep.IsTruthy(value))
+			continue
+		case BindingTypeStyle:
+			attrs.setStyle(input.Name, value)
+			continue
+		}
+
+		switch input.Name {
+		case "class", "className", "ngClass":
+			attrs.setClasses(value)
+			continue
+		case "style", "ngStyle":
+			attrs.setStyles(value)
+			continue
+		case "innerHTML":
+			html := ""
+			if !ep.IsNullish(value) {
+				html = SanitizeHTML(ep.ToString(value))
+			}
+			content = &html
+			continue
+		case "textContent", "innerText":
+			text := escapeRenderedText(ep.InterpolationString(value))
+			content = &text
+			continue
+		}
+
+		name := input.Name
+		if input.BindingType != BindingTypeAttribute {
+			propertyType, ok := r.Registry.GetPropertyType(elementName, r.Registry.GetMappedPropName(input.Name))
+			if !ok {
+				// an input of a component or directive
+				continue
+			}
+
+			if attribute, ok := propertyAttributes[name]; ok {
+				name = attribute
+			} else if namespace == "" {
+				name = strings.ToLower(name)
+			}
+
+			if propertyType == schema.PropertyTypeBoolean {
+				if ep.IsTruthy(value) {
+					attrs.setBoolean(name)
+				} else {
+					attrs.remove(name)
+				}
+				continue
+			}
+		}
+
+		if ep.IsNullish(value) {
+			attrs.remove(name)
+			continue
+		}
+
+		str := ep.ToString(value)
+		switch bindingSecurityContext(elementName, input, r.Registry) {
+		case schema.SecurityContextURL:
+			str = SanitizeURL(str)
+		case schema.SecurityContextResourceURL:
+			return "", nil, expressionError(fmt.Errorf("NG0904: unsafe value used in a resource URL context (see https://g.co/ng/security#xss)"), input.Value)
+		case schema.SecurityContextHTML:
+			str = SanitizeHTML(str)
+		}
+		attrs.set(name, str)
+	}
+
+	return attrs.String(), content, nil
+}
+
+// isRenderInstruction reports whether an attribute is an instruction to the
+// compiler.
+func isRenderInstruction(name string) bool {
+	return name == "i18n" || strings.HasPrefix(name, "i18n-") || name == "ngPreserveWhitespaces" ||
+		name == "ngNonBindable" || name == "ngProjectAs"
+}
+
+var renderedTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", " ", "&nbsp;")
+
+var renderedAttributeEscaper = strings.NewReplacer("&", "&amp;", `"`, "&quot;", " ", "&nbsp;")
+
+func escapeRenderedText(text string) string {
+	return renderedTextEscaper.Replace(text)
+}
+
+func escapeRenderedAttribute(value string) string {
+	return renderedAttributeEscaper.Replace(value)
+}
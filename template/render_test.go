@@ -0,0 +1,125 @@
+package template
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/irustm/ng-template-parser/schema"
+)
+
+const xss = `"'><script>alert(1)</script>`
+
+func renderTest(t *testing.T, src string) (string, error) {
+	t.Helper()
+	root := Parse(strings.NewReader(src), "")
+	context := map[string]interface{}{
+		"name":   xss,
+		"url":    "javascript:alert(1)",
+		"safe":   "https://example.com/?a=1&b=2",
+		"html":   `<b onclick="alert(1)">bold</b><script>alert(1)</script>`,
+		"active": true,
+	}
+	return Render(root, context, schema.NewDomElementSchemaRegistry())
+}
+
+// TestRenderEscaping checks that values can't end the text or the quoted
+// attribute they are written to.
+func TestRenderEscaping(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`<p>{{ name }}</p>`, `<p>"'&gt;&lt;script&gt;alert(1)&lt;/script&gt;</p>`},
+		{`<p [title]="name"></p>`, `<p title="&quot;'><script>alert(1)</script>"></p>`},
+		{`<p title="{{ name }}"></p>`, `<p title="&quot;'><script>alert(1)</script>"></p>`},
+		{`<p title="a {{ 1 + 1 }} b"></p>`, `<p title="a 2 b"></p>`},
+		{`<textarea>{{ name }}</textarea>`, `<textarea>"'&gt;&lt;script&gt;alert(1)&lt;/script&gt;</textarea>`},
+		{`<p [textContent]="name"></p>`, `<p>"'&gt;&lt;script&gt;alert(1)&lt;/script&gt;</p>`},
+		{`<p [class.on]="active" class="a"></p>`, `<p class="a on"></p>`},
+		{`<p data-x="{{ name }}"></p>`, `<p data-x="&quot;'><script>alert(1)</script>"></p>`},
+		{`<p aria-label="a {{ 1 + 1 }}"></p>`, `<p aria-label="a 2"></p>`},
+	}
+
+	for _, test := range tests {
+		got, err := renderTest(t, test.src)
+		if err != nil {
+			t.Errorf("Render(%q): %v", test.src, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Render(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestRenderSanitization(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`<a [href]="url">x</a>`, `<a href="unsafe:javascript:alert(1)">x</a>`},
+		{`<a href="{{ url }}">x</a>`, `<a href="unsafe:javascript:alert(1)">x</a>`},
+		{`<a [attr.href]="url">x</a>`, `<a href="unsafe:javascript:alert(1)">x</a>`},
+		{`<a [href]="safe">x</a>`, `<a href="https://example.com/?a=1&amp;b=2">x</a>`},
+		{`<div [innerHTML]="html"></div>`, `<div><b>bold</b></div>`},
+		{`<script>var a = '{{ name }}'</script><p>ok</p>`, `<p>ok</p>`},
+		{`<style>p { color: red }</style><p>ok</p>`, `<p>ok</p>`},
+		{`<p onclick="go()">x</p>`, `<p onclick="go()">x</p>`},
+		{`<svg><a [attr.href]="url"></a></svg>`, `<svg><a href="unsafe:javascript:alert(1)"></a></svg>`},
+		{`<svg><a [attr.xlink:href]="url"></a></svg>`, `<svg><a xlink:href="unsafe:javascript:alert(1)"></a></svg>`},
+		{`<svg><a xlink:href="{{ url }}"></a></svg>`, `<svg><a xlink:href="unsafe:javascript:alert(1)"></a></svg>`},
+		{`<a [attr.xlink:href]="url">x</a>`, `<a xlink:href="unsafe:javascript:alert(1)">x</a>`},
+		{`<svg><image [attr.href]="url" /></svg>`, `<svg><image href="unsafe:javascript:alert(1)"></image></svg>`},
+		{`<svg><use [attr.href]="url" /></svg>`, `<svg><use href="unsafe:javascript:alert(1)"></use></svg>`},
+	}
+
+	for _, test := range tests {
+		got, err := renderTest(t, test.src)
+		if err != nil {
+			t.Errorf("Render(%q): %v", test.src, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Render(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestRenderRefusesUnsafeBindings(t *testing.T) {
+	for _, src := range []string{
+		`<p [attr.onclick]="name"></p>`,
+		`<p [attr.ONmouseover]="name"></p>`,
+		`<p onclick="{{ name }}"></p>`,
+		`<xmp>{{ name }}</xmp>`,
+		`<iframe [src]="url"></iframe>`,
+	} {
+		if got, err := renderTest(t, src); err == nil {
+			t.Errorf("Render(%q) = %q, want an error", src, got)
+		}
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`<p *ngIf="active" class="a">on</p><p *ngIf="!active">off</p>`, `<p class="a">on</p>`},
+		{`<b *ngFor="let c of ['x', 'y']; index as i" *ngIf="i">{{ c }}</b>`, `<b>y</b>`},
+		{`<ng-template [ngIf]="active"><i>on</i></ng-template>`, `<i>on</i>`},
+		{`<p *ngIf="!active; else other">off</p><ng-template #other let-on><i>{{ on }}</i></ng-template>`, `<i>false</i>`},
+		{`<div [ngSwitch]="1"><p *ngSwitchCase="1">one</p><p *ngSwitchDefault>other</p></div>`, `<div><p>one</p></div>`},
+		{`<div [ngSwitch]="2"><ng-template [ngSwitchCase]="1">one</ng-template><p *ngSwitchDefault>other</p></div>`, `<div><p>other</p></div>`},
+	}
+
+	for _, test := range tests {
+		got, err := renderTest(t, test.src)
+		if err != nil {
+			t.Errorf("Render(%q): %v", test.src, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Render(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// https://github.com/angular/angular/blob/master/packages/core/src/sanitization/html_sanitizer.ts
+
+func tagSet(tags ...string) map[string]bool {
+	set := map[string]bool{}
+	for _, list := range tags {
+		for _, tag := range strings.Split(list, ",") {
+			set[tag] = true
+		}
+	}
+	return set
+}
+
+var sanitizerVoidElements = tagSet("area,br,col,hr,img,wbr")
+
+var sanitizerValidElements = tagSet(
+	"area,br,col,hr,img,wbr",
+	"colgroup,dd,dt,li,p,tbody,td,tfoot,th,thead,tr",
+	"rp,rt",
+	"address,article,aside,blockquote,caption,center,del,details,dialog,dir,div,dl,figure,figcaption,footer,h1,h2,h3,h4,h5,h6,"+
+		"header,hgroup,hr,ins,main,map,menu,nav,ol,pre,section,summary,table,ul",
+	"a,abbr,acronym,audio,b,bdi,bdo,big,br,cite,code,del,dfn,em,font,i,img,ins,kbd,label,map,mark,picture,q,ruby,rp,rt,s,"+
+		"samp,small,source,span,strike,strong,sub,sup,time,track,tt,u,var,video",
+)
+
+var sanitizerURIAttributes = tagSet("background,cite,href,itemtype,longdesc,poster,src,xlink:href")
+
+var sanitizerValidAttributes = tagSet(
+	"background,cite,href,itemtype,longdesc,poster,src,xlink:href,srcset",
+	"abbr,accesskey,align,alt,autoplay,axis,bgcolor,border,cellpadding,cellspacing,class,clear,color,cols,colspan,"+
+		"compact,controls,coords,datetime,default,dir,download,face,headers,height,hidden,hreflang,hspace,ismap,itemscope,"+
+		"itemprop,kind,label,lang,language,loop,media,muted,nohref,nowrap,open,preload,rel,rev,role,rows,rowspan,rules,"+
+		"scope,scrolling,shape,size,sizes,span,srclang,start,summary,tabindex,target,title,translate,type,usemap,"+
+		"valign,value,vspace,width",
+)
+
+// sanitizerSkipContent are the invalid elements whose content is dropped too.
+var sanitizerSkipContent = tagSet("script,style,template")
+
+// SanitizeHTML removes the elements and attributes of an HTML fragment which
+// could run script, like Angular does for `[innerHTML]` bindings. The text of
+// removed elements is kept, except for <script>, <style> and <template>.
+func SanitizeHTML(unsafe string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(unsafe))
+	var out strings.Builder
+	skipDepth := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			return out.String()
+		case html.TextToken:
+			if skipDepth == 0 {
+				out.WriteString(escapeRenderedText(string(tokenizer.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if sanitizerSkipContent[token.Data] {
+				if tokenType == html.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if skipDepth > 0 || !sanitizerValidElements[token.Data] {
+				continue
+			}
+
+			out.WriteString("<" + token.Data)
+			for _, attr := range token.Attr {
+				name := strings.ToLower(attr.Key)
+				if attr.Namespace != "" {
+					name = attr.Namespace + ":" + name
+				}
+				if !sanitizerValidAttributes[name] && !strings.HasPrefix(name, "aria-") {
+					continue
+				}
+
+				value := attr.Val
+				if sanitizerURIAttributes[name] {
+					value = SanitizeURL(value)
+				} else if name == "srcset" {
+					value = sanitizeSrcset(value)
+				}
+				out.WriteString(" " + name + `="` + escapeRenderedAttribute(value) + `"`)
+			}
+			out.WriteString(">")
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if sanitizerSkipContent[token.Data] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if skipDepth == 0 && sanitizerValidElements[token.Data] && !sanitizerVoidElements[token.Data] {
+				out.WriteString("</" + token.Data + ">")
+			}
+		}
+	}
+}
+
+// safeURLRegexp matches URLs with a scheme or relative URLs, the scheme is
+// checked against javascript separately as RE2 has no lookahead.
+var safeURLRegexp = regexp.MustCompile(`(?i)^(?:[a-z0-9+.-]+:|[^&:/?#]*(?:[/?#]|$))`)
+
+// SanitizeURL prefixes a URL with `unsafe:` if it could run script, e.g.
+// `javascript:alert(1)`, like Angular does for URL bindings like `[href]`.
+func SanitizeURL(url string) string {
+	if safeURLRegexp.MatchString(url) && !strings.HasPrefix(strings.ToLower(strings.TrimSpace(url)), "javascript:") {
+		return url
+	}
+	return "unsafe:" + url
+}
+
+func sanitizeSrcset(srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		candidates[i] = SanitizeURL(strings.TrimSpace(candidate))
+	}
+	return strings.Join(candidates, ", ")
+}
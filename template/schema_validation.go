@@ -0,0 +1,130 @@
+package template
+
+import (
+	"strings"
+
+	"github.com/irustm/ng-template-parser/schema"
+)
+
+type SchemaError struct {
+	Element string
+	Message string
+}
+
+// ValidateSchema reports unknown elements and property bindings which are not
+// known properties of the element, in the same way Angular does for templates
+// of components declared with the given schemas.
+func ValidateSchema(root Root, registry *schema.DomElementSchemaRegistry, schemas []schema.SchemaMetadata) []SchemaError {
+	var errors []SchemaError
+
+	for _, node := range root.Nodes {
+		errors = validateNode(node, "", registry, schemas, errors)
+	}
+
+	return errors
+}
+
+func validateNode(node Node, namespace string, registry *schema.DomElementSchemaRegistry, schemas []schema.SchemaMetadata, errors []SchemaError) []SchemaError {
+	if block, ok := node.(*Block); ok {
+		for _, child := range block.Children {
+			errors = validateNode(child, namespace, registry, schemas, errors)
+		}
+		return errors
+	}
+
+	if template, ok := node.(*Template); ok {
+		if template.TemplateAttr != nil {
+			for _, child := range template.Children {
+				errors = validateNode(child, namespace, registry, schemas, errors)
+			}
+			return errors
+		}
+		node = template.Element()
+	}
+
+	element, ok := node.(*Element)
+	if !ok {
+		return errors
+	}
+
+	elementName, namespace := schemaElementName(element.Name, namespace)
+
+	if !schema.IsNgTemplate(elementName) && !registry.HasElement(elementName, schemas) {
+		errors = append(errors, SchemaError{Element: element.Name, Message: unknownElementMessage(element.Name)})
+	}
+
+	for _, input := range element.Inputs {
+		switch input.BindingType {
+		case BindingTypeProperty, BindingTypeTwoWay:
+			propName := registry.GetMappedPropName(input.Name)
+
+			if msg, disallowed := registry.ValidateProperty(propName); disallowed {
+				errors = append(errors, SchemaError{Element: element.Name, Message: msg})
+			} else if !schema.IsNgTemplate(elementName) && !registry.HasProperty(elementName, propName, schemas) {
+				errors = append(errors, SchemaError{Element: element.Name, Message: unknownPropertyMessage(element.Name, propName)})
+			}
+		case BindingTypeAttribute:
+			if msg, disallowed := registry.ValidateAttribute(input.Name); disallowed {
+				errors = append(errors, SchemaError{Element: element.Name, Message: msg})
+			}
+		}
+	}
+
+	// foreignObject children are back in the HTML namespace
+	if strings.EqualFold(element.Name, "foreignObject") {
+		namespace = ""
+	}
+
+	for _, child := range element.Children {
+		errors = validateNode(child, namespace, registry, schemas, errors)
+	}
+
+	return errors
+}
+
+// schemaElementName returns the `:svg:rect` form of an element name used by
+// the schema and the namespace inherited by its children.
+func schemaElementName(name string, parentNamespace string) (string, string) {
+	namespace := parentNamespace
+
+	switch strings.ToLower(name) {
+	case "svg":
+		namespace = "svg"
+	case "math":
+		namespace = "math"
+	}
+
+	if namespace == "" {
+		return name, namespace
+	}
+
+	return ":" + namespace + ":" + name, namespace
+}
+
+func unknownElementMessage(name string) string {
+	msg := `'` + name + `' is not a known element:` + "\n"
+	msg += `1. If '` + name + `' is an Angular component, then verify that it is part of this module.` + "\n"
+
+	if strings.Contains(name, "-") {
+		msg += `2. If '` + name + `' is a Web Component then add 'CUSTOM_ELEMENTS_SCHEMA' to the '@NgModule.schemas' of this component to suppress this message.`
+	} else {
+		msg += `2. To allow any element add 'NO_ERRORS_SCHEMA' to the '@NgModule.schemas' of this component.`
+	}
+
+	return msg
+}
+
+func unknownPropertyMessage(elementName string, propName string) string {
+	msg := `Can't bind to '` + propName + `' since it isn't a known property of '` + elementName + `'.`
+
+	if strings.HasPrefix(elementName, "ng-") {
+		msg += "\n" + `1. If '` + propName + `' is an Angular directive, then add 'CommonModule' to the '@NgModule.imports' of this component.` +
+			"\n" + `2. To allow any property add 'NO_ERRORS_SCHEMA' to the '@NgModule.schemas' of this component.`
+	} else if strings.Contains(elementName, "-") {
+		msg += "\n" + `1. If '` + elementName + `' is an Angular component and it has '` + propName + `' input, then verify that it is part of this module.` +
+			"\n" + `2. If '` + elementName + `' is a Web Component then add 'CUSTOM_ELEMENTS_SCHEMA' to the '@NgModule.schemas' of this component to suppress this message.` +
+			"\n" + `3. To allow any property add 'NO_ERRORS_SCHEMA' to the '@NgModule.schemas' of this component.`
+	}
+
+	return msg
+}
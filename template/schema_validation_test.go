@@ -0,0 +1,58 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/irustm/ng-template-parser/schema"
+)
+
+func TestValidateSchema(t *testing.T) {
+	unknownHreff := "Can't bind to 'hreff' since it isn't a known property of 'a'."
+	onclickProperty := "Binding to event property 'onclick' is disallowed for security reasons, please use (click)=...\n" +
+		"If 'onclick' is a directive input, make sure the directive is imported by the current module."
+	onclickAttribute := "Binding to event attribute 'onclick' is disallowed for security reasons, please use (click)=..."
+	custom := []schema.SchemaMetadata{schema.CustomElementsSchema}
+	noErrors := []schema.SchemaMetadata{schema.NoErrorsSchema}
+
+	tests := []struct {
+		src     string
+		schemas []schema.SchemaMetadata
+		want    []SchemaError
+	}{
+		{`<a [href]="u" [attr.hreff]="u" (click)="go()"></a>`, nil, nil},
+		{`<a [hreff]="u"></a>`, nil, []SchemaError{{"a", unknownHreff}}},
+		{`<a [hreff]="u"></a>`, custom, []SchemaError{{"a", unknownHreff}}},
+		{`<a [hreff]="u"></a>`, noErrors, nil},
+		{`@if (x) { <p><a [hreff]="u"></a></p> }`, nil, []SchemaError{{"a", unknownHreff}}},
+		{`<a *ngIf="x" [hreff]="u"></a>`, nil, []SchemaError{{"a", unknownHreff}}},
+
+		// custom elements
+		{`<my-el [foo]="x"></my-el>`, custom, nil},
+		{`<my-el></my-el>`, nil, []SchemaError{{"my-el", "'my-el' is not a known element:\n" +
+			"1. If 'my-el' is an Angular component, then verify that it is part of this module.\n" +
+			"2. If 'my-el' is a Web Component then add 'CUSTOM_ELEMENTS_SCHEMA' to the '@NgModule.schemas' of this component to suppress this message."}}},
+		{`<foo></foo>`, custom, []SchemaError{{"foo", "'foo' is not a known element:\n" +
+			"1. If 'foo' is an Angular component, then verify that it is part of this module.\n" +
+			"2. To allow any element add 'NO_ERRORS_SCHEMA' to the '@NgModule.schemas' of this component."}}},
+
+		// on* bindings fail with any schema
+		{`<div [onclick]="x"></div>`, nil, []SchemaError{{"div", onclickProperty}}},
+		{`<div [onclick]="x"></div>`, noErrors, []SchemaError{{"div", onclickProperty}}},
+		{`<div [attr.onclick]="x"></div>`, custom, []SchemaError{{"div", onclickAttribute}}},
+
+		// <ng-template> and <ng-container> take the inputs of directives, their
+		// children are validated
+		{`<ng-template [ngIf]="x" [ngIfElse]="y"></ng-template><ng-container [ngTemplateOutlet]="t"></ng-container>`, nil, nil},
+		{`<ng-template [ngIf]="x"><a [hreff]="u"></a></ng-template>`, nil, []SchemaError{{"a", unknownHreff}}},
+	}
+
+	registry := schema.NewDomElementSchemaRegistry()
+	for _, test := range tests {
+		got := ValidateSchema(Parse(strings.NewReader(test.src), ""), registry, test.schemas)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ValidateSchema(%q, %v) = %q, want %q", test.src, test.schemas, got, test.want)
+		}
+	}
+}
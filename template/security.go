@@ -0,0 +1,128 @@
+package template
+
+import "github.com/irustm/ng-template-parser/schema"
+
+type SecurityReportEntry struct {
+	Element string
+	Binding BoundAttribute
+}
+
+// AnnotateSecurityContexts sets the SecurityContext of every bound attribute
+// from its element and property name, e.g. URL for `[href]` on `a`.
+func AnnotateSecurityContexts(root *Root, registry *schema.DomElementSchemaRegistry) {
+	for _, node := range root.Nodes {
+		annotateSecurityContexts(node, "", registry)
+	}
+}
+
+func annotateSecurityContexts(node Node, namespace string, registry *schema.DomElementSchemaRegistry) {
+	if block, ok := node.(*Block); ok {
+		for _, child := range block.Children {
+			annotateSecurityContexts(child, namespace, registry)
+		}
+		return
+	}
+
+	// the view of an <ng-template> shares its bindings
+	if template, ok := node.(*Template); ok {
+		if template.TemplateAttr != nil {
+			for _, child := range template.Children {
+				annotateSecurityContexts(child, namespace, registry)
+			}
+			return
+		}
+		node = template.Element()
+	}
+
+	element, ok := node.(*Element)
+	if !ok {
+		return
+	}
+
+	elementName, namespace := schemaElementName(element.Name, namespace)
+
+	for i, input := range element.Inputs {
+		element.Inputs[i].SecurityContext = bindingSecurityContext(elementName, input, registry)
+	}
+
+	for _, child := range element.Children {
+		annotateSecurityContexts(child, namespace, registry)
+	}
+}
+
+func bindingSecurityContext(elementName string, input BoundAttribute, registry *schema.DomElementSchemaRegistry) schema.SecurityContext {
+	switch input.BindingType {
+	case BindingTypeProperty, BindingTypeTwoWay:
+		return registry.SecurityContext(elementName, registry.GetMappedPropName(input.Name), false)
+	case BindingTypeAttribute:
+		return registry.SecurityContext(elementName, input.Name, true)
+	case BindingTypeStyle:
+		return schema.SecurityContextStyle
+	default:
+		return schema.SecurityContextNone
+	}
+}
+
+// SecurityReport lists every binding of an annotated tree into a sensitive
+// sink, i.e. with a security context other than NONE.
+func SecurityReport(root Root) []SecurityReportEntry {
+	var entries []SecurityReportEntry
+
+	for _, node := range root.Nodes {
+		entries = collectSensitiveBindings(node, entries)
+	}
+
+	return entries
+}
+
+func collectSensitiveBindings(node Node, entries []SecurityReportEntry) []SecurityReportEntry {
+	if block, ok := node.(*Block); ok {
+		for _, child := range block.Children {
+			entries = collectSensitiveBindings(child, entries)
+		}
+		return entries
+	}
+
+	if template, ok := node.(*Template); ok {
+		if template.TemplateAttr != nil {
+			for _, child := range template.Children {
+				entries = collectSensitiveBindings(child, entries)
+			}
+			return entries
+		}
+		node = template.Element()
+	}
+
+	element, ok := node.(*Element)
+	if !ok {
+		return entries
+	}
+
+	for _, input := range element.Inputs {
+		if input.SecurityContext != schema.SecurityContextNone {
+			entries = append(entries, SecurityReportEntry{Element: element.Name, Binding: input})
+		}
+	}
+
+	for _, child := range element.Children {
+		entries = collectSensitiveBindings(child, entries)
+	}
+
+	return entries
+}
+
+// BindingKey returns the attribute name a binding was written with, e.g. `[attr.href]`.
+func BindingKey(input BoundAttribute) string {
+	switch input.BindingType {
+	case BindingTypeAttribute:
+		return "[attr." + input.Name + "]"
+	case BindingTypeClass:
+		return "[class." + input.Name + "]"
+	case BindingTypeStyle:
+		return "[style." + input.Name + "]"
+	case BindingTypeTwoWay:
+		return "[(" + input.Name + ")]"
+	default:
+		return "[" + input.Name + "]"
+	}
+}
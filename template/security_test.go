@@ -0,0 +1,39 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/irustm/ng-template-parser/schema"
+)
+
+func TestSecurityReport(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`<a [href]="u" [title]="t"></a>`, []string{"a [href] URL"}},
+		{`<iframe [src]="u" [attr.srcdoc]="h"></iframe>`, []string{"iframe [src] RESOURCE_URL", "iframe [attr.srcdoc] HTML"}},
+		{`<div [innerHTML]="h" [style.color]="c"></div>`, []string{"div [innerHTML] HTML", "div [style.color] STYLE"}},
+		{`<svg><a [attr.href]="u" [attr.xlink:href]="u"></a></svg>`, []string{"a [attr.href] URL", "a [attr.xlink:href] URL"}},
+		{`<svg><image [attr.href]="u" /><circle [attr.r]="r" /></svg>`, []string{"image [attr.href] URL"}},
+		{`<ng-template [ngIf]="a"><img *ngIf="b" [src]="u"></ng-template>`, []string{"img [src] URL"}},
+		{`@if (a) { <a [href]="u"></a> }`, []string{"a [href] URL"}},
+	}
+
+	registry := schema.NewDomElementSchemaRegistry()
+	for _, test := range tests {
+		root := Parse(strings.NewReader(test.src), "")
+		AnnotateSecurityContexts(&root, registry)
+
+		var got []string
+		for _, entry := range SecurityReport(root) {
+			got = append(got, fmt.Sprintf("%s %s %s", entry.Element, BindingKey(entry.Binding), entry.Binding.SecurityContext))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SecurityReport(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
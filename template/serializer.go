@@ -0,0 +1,378 @@
+package template
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/irustm/ng-template-parser/ep"
+	"golang.org/x/net/html"
+)
+
+// SerializeMode selects how Serialize turns a parsed tree back into a template.
+type SerializeMode int
+
+const (
+	// SerializeNormalized emits every node from the tree: attributes in source
+	// order, expressions in their canonical form and text re-escaped.
+	SerializeNormalized SerializeMode = iota
+	// SerializeLossless copies the source text of every unchanged node and the
+	// trivia between nodes and attributes, so an unmodified tree serializes to
+	// its exact source. Changed and new nodes are emitted as in normalized mode.
+	SerializeLossless
+)
+
+// Serialize regenerates the HTML of a parsed template.
+func Serialize(root Root, mode SerializeMode) string {
+	s := &serializer{}
+	if mode == SerializeLossless {
+		s.source = root.Source
+	}
+
+	s.serializeNodes(root.Nodes, 0, len(s.source))
+	return s.out.String()
+}
+
+type serializer struct {
+	out strings.Builder
+	// source is empty in normalized mode.
+	source string
+	// rawText is set within elements like <script> whose text isn't escaped.
+	rawText bool
+}
+
+func (s *serializer) lossless() bool {
+	return s.source != ""
+}
+
+// hasSpan reports whether span points into the source, nodes created after
+// parsing have an empty span.
+func (s *serializer) hasSpan(span ep.AbsoluteSourceSpan) bool {
+	return s.lossless() && span.Start < span.End && span.End <= len(s.source)
+}
+
+// serializeNodes writes nodes, in lossless mode together with the source
+// between start and end which doesn't belong to any node, e.g. whitespace,
+// a doctype or a stray end tag.
+func (s *serializer) serializeNodes(nodes []Node, start int, end int) {
+	cursor := start
+	gaps := s.lossless() && start < end
+
+	for _, node := range nodes {
+		if span := node.GetSourceSpan(); gaps && s.hasSpan(span) && span.Start >= cursor && span.End <= end {
+			s.out.WriteString(s.source[cursor:span.Start])
+			cursor = span.End
+		}
+
+		s.serializeNode(node)
+	}
+
+	if gaps && cursor < end {
+		s.out.WriteString(s.source[cursor:end])
+	}
+}
+
+func (s *serializer) serializeNode(node Node) {
+	switch n := node.(type) {
+	case *Element:
+		s.serializeElement(n)
+	case *Template:
+		s.serializeElement(n.Element())
+	case *Block:
+		s.serializeBlock(n)
+	case *Text:
+		if s.hasSpan(n.SourceSpan) && html.UnescapeString(s.sourceText(n.SourceSpan)) == n.Value {
+			s.out.WriteString(s.sourceText(n.SourceSpan))
+		} else if s.rawText {
+			s.out.WriteString(n.Value)
+		} else {
+			s.out.WriteString(escapeText(n.Value))
+		}
+	case *BoundText:
+		if s.hasSpan(n.SourceSpan) && ep.PrintPreserving(n.Value) == n.Value.Source {
+			s.out.WriteString(s.sourceText(n.SourceSpan))
+		} else if s.lossless() {
+			s.out.WriteString(ep.PrintPreserving(n.Value))
+		} else if interpolation, ok := n.Value.Ast.(*ep.Interpolation); ok {
+			for i, str := range interpolation.Strings {
+				s.out.WriteString(escapeText(str))
+				if i < len(interpolation.Expressions) {
+					s.out.WriteString("{{ " + ep.Print(interpolation.Expressions[i]) + " }}")
+				}
+			}
+		} else {
+			s.out.WriteString(ep.Print(n.Value))
+		}
+	case *Comment:
+		if s.hasSpan(n.SourceSpan) && s.sourceText(n.SourceSpan) == "<!--"+n.Value+"-->" {
+			s.out.WriteString(s.sourceText(n.SourceSpan))
+		} else {
+			s.out.WriteString("<!--" + n.Value + "-->")
+		}
+	}
+}
+
+func (s *serializer) sourceText(span ep.AbsoluteSourceSpan) string {
+	return s.source[span.Start:span.End]
+}
+
+func (s *serializer) serializeElement(element *Element) {
+	void := isVoidElement(strings.ToLower(element.Name))
+	selfClosing := element.SelfClosing && len(element.Children) == 0
+	startTag := element.StartSourceSpan
+	originalStartTag := s.hasSpan(startTag) && strings.HasPrefix(s.source[startTag.Start:], "<"+element.Name) &&
+		startTag.Start+1+len(element.Name) < len(s.source) &&
+		(isTagSpace(s.source[startTag.Start+1+len(element.Name)]) || strings.ContainsRune("/>", rune(s.source[startTag.Start+1+len(element.Name)])))
+
+	if originalStartTag {
+		s.serializeOriginalStartTag(element)
+	} else {
+		s.out.WriteString("<" + element.Name)
+		for _, attr := range elementAttributes(element) {
+			s.out.WriteString(" " + attr.text)
+		}
+		if selfClosing {
+			s.out.WriteString(" />")
+		} else {
+			s.out.WriteString(">")
+		}
+	}
+
+	if void || selfClosing {
+		return
+	}
+
+	rawText := s.rawText
+	s.rawText = isRawTextElement(strings.ToLower(element.Name)) && !isEscapableRawTextElement(strings.ToLower(element.Name))
+	s.serializeChildren(element.Children, startTag, element.SourceSpan, element.EndSourceSpan)
+	s.rawText = rawText
+
+	if element.EndSourceSpan != nil && s.hasSpan(*element.EndSourceSpan) && originalStartTag {
+		s.out.WriteString(s.sourceText(*element.EndSourceSpan))
+	} else if element.EndSourceSpan != nil || !s.hasSpan(startTag) {
+		s.out.WriteString("</" + element.Name + ">")
+	}
+}
+
+func (s *serializer) serializeBlock(block *Block) {
+	startTag := block.StartSourceSpan
+
+	if s.hasSpan(startTag) && sameBlockStart(s.sourceText(startTag), block) {
+		s.out.WriteString(s.sourceText(startTag))
+	} else {
+		s.out.WriteString(blockStart(block))
+	}
+
+	s.serializeChildren(block.Children, startTag, block.SourceSpan, block.EndSourceSpan)
+
+	if block.EndSourceSpan != nil || !s.hasSpan(startTag) {
+		s.out.WriteString("}")
+	}
+}
+
+// serializeChildren writes the children of an element or block, in lossless
+// mode with the source between its start and its end.
+func (s *serializer) serializeChildren(children []Node, start ep.AbsoluteSourceSpan, span ep.AbsoluteSourceSpan, end *ep.AbsoluteSourceSpan) {
+	if !s.hasSpan(start) {
+		s.serializeNodes(children, 0, 0)
+		return
+	}
+
+	childrenEnd := span.End
+	if end != nil {
+		childrenEnd = end.Start
+	}
+	s.serializeNodes(children, start.End, childrenEnd)
+}
+
+// sameBlockStart reports whether source is the start of a block with the
+// name and parameters of block.
+func sameBlockStart(source string, block *Block) bool {
+	name, parameters, end := scanBlockStart(source)
+	if end != len(source) || name != block.Name || len(parameters) != len(block.Parameters) {
+		return false
+	}
+
+	for i, parameter := range parameters {
+		if parameter.Expression != block.Parameters[i].Expression {
+			return false
+		}
+	}
+
+	return true
+}
+
+// blockStart returns the normalized start of a block, e.g. `@for (item of items; track item) {`.
+func blockStart(block *Block) string {
+	start := "@" + block.Name
+	if len(block.Parameters) > 0 {
+		parameters := make([]string, len(block.Parameters))
+		for i, parameter := range block.Parameters {
+			parameters[i] = parameter.Expression
+		}
+		start += " (" + strings.Join(parameters, "; ") + ")"
+	}
+	return start + " {"
+}
+
+// serializeOriginalStartTag copies the start tag from the source, replacing
+// the attributes which changed since parsing and appending the new ones.
+func (s *serializer) serializeOriginalStartTag(element *Element) {
+	startTag := element.StartSourceSpan
+	cursor := startTag.Start + 1 + len(element.Name)
+	s.out.WriteString(s.source[startTag.Start:cursor])
+
+	var added []serializedAttribute
+	for _, attr := range elementAttributes(element) {
+		if !s.hasSpan(attr.sourceSpan) || attr.sourceSpan.Start < cursor || attr.sourceSpan.End > startTag.End {
+			added = append(added, attr)
+			continue
+		}
+
+		s.out.WriteString(s.source[cursor:attr.sourceSpan.Start])
+		s.out.WriteString(s.losslessAttribute(attr))
+		cursor = attr.sourceSpan.End
+	}
+
+	tail := s.source[cursor:startTag.End]
+	head := strings.TrimRight(tail, "/> \t\r\n\f")
+	s.out.WriteString(head)
+	for _, attr := range added {
+		s.out.WriteString(" " + attr.text)
+	}
+	s.out.WriteString(tail[len(head):])
+}
+
+// losslessAttribute returns the source of an attribute, with its value
+// replaced if it changed since parsing.
+func (s *serializer) losslessAttribute(attr serializedAttribute) string {
+	span := attr.sourceSpan
+	if s.source[attr.keySpan.Start:attr.keySpan.End] != attr.key {
+		return attr.text
+	}
+
+	if attr.valueSpan == nil {
+		if attr.value == "" && attr.expression == nil {
+			return s.sourceText(span)
+		}
+		return attr.text
+	}
+
+	valueSpan := *attr.valueSpan
+	rawValue := s.sourceText(valueSpan)
+
+	var value string
+	if attr.expression != nil {
+		value = ep.PrintPreserving(attr.expression)
+		if value == attr.expression.Source {
+			return s.sourceText(span)
+		}
+	} else {
+		if html.UnescapeString(rawValue) == attr.value {
+			return s.sourceText(span)
+		}
+		value = attr.value
+	}
+
+	quote := byte('"')
+	if valueSpan.Start > span.Start && s.source[valueSpan.Start-1] == '\'' {
+		quote = '\''
+	}
+
+	return s.source[span.Start:valueSpan.Start] + escapeAttribute(value, quote, attr.expression == nil) + s.source[valueSpan.End:span.End]
+}
+
+type serializedAttribute struct {
+	key        string
+	value      string
+	expression *ep.ASTWithSource
+	sourceSpan ep.AbsoluteSourceSpan
+	keySpan    ep.AbsoluteSourceSpan
+	valueSpan  *ep.AbsoluteSourceSpan
+	// text is the normalized form of the attribute.
+	text string
+}
+
+// elementAttributes returns the attributes, bindings and references of an
+// element in source order, those without span follow in the order plain
+// attributes, references, inputs, outputs.
+func elementAttributes(element *Element) []serializedAttribute {
+	var attrs []serializedAttribute
+
+	for _, attr := range element.Attributes {
+		text := attr.Name
+		if attr.Value != "" || attr.ValueSpan != nil {
+			text += `="` + escapeAttribute(attr.Value, '"', true) + `"`
+		}
+		attrs = append(attrs, serializedAttribute{key: attr.Name, value: attr.Value, sourceSpan: attr.SourceSpan,
+			keySpan: attr.KeySpan, valueSpan: attr.ValueSpan, text: text})
+	}
+
+	for _, ref := range element.References {
+		text := "#" + ref.Name
+		if ref.Value != "" || ref.ValueSpan != nil {
+			text += `="` + escapeAttribute(ref.Value, '"', true) + `"`
+		}
+		attrs = append(attrs, serializedAttribute{key: "#" + ref.Name, value: ref.Value, sourceSpan: ref.SourceSpan,
+			keySpan: ref.KeySpan, valueSpan: ref.ValueSpan, text: text})
+	}
+
+	for _, input := range element.Inputs {
+		key := BindingKey(input)
+		attrs = append(attrs, serializedAttribute{key: key, expression: input.Value, sourceSpan: input.SourceSpan,
+			keySpan: input.KeySpan, valueSpan: input.ValueSpan, text: key + `="` + escapeAttribute(ep.Print(input.Value), '"', false) + `"`})
+	}
+
+	for _, output := range element.Outputs {
+		// [(x)] is written once, with its input.
+		if output.BindingType == BindingTypeTwoWay {
+			continue
+		}
+
+		key := "(" + output.Name + ")"
+		attrs = append(attrs, serializedAttribute{key: key, expression: output.Handler, sourceSpan: output.SourceSpan,
+			keySpan: output.KeySpan, valueSpan: output.ValueSpan, text: key + `="` + escapeAttribute(ep.Print(output.Handler), '"', false) + `"`})
+	}
+
+	sort.SliceStable(attrs, func(i, j int) bool {
+		a, b := attrs[i].sourceSpan, attrs[j].sourceSpan
+		if a.End == 0 || b.End == 0 {
+			return a.End != 0 && b.End == 0
+		}
+		return a.Start < b.Start
+	})
+
+	return attrs
+}
+
+// isEscapableRawTextElement reports whether the text of a raw text element
+// contains character references.
+func isEscapableRawTextElement(name string) bool {
+	return name == "textarea" || name == "title"
+}
+
+// Braces and @ would start or end a control flow block or an ICU expression.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "@", "&#64;", "{", "&#123;", "}", "&#125;")
+
+func escapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// characterReferenceRegexp matches an ampersand which starts a character
+// reference like `&lt;` or `&#60;` in an attribute value.
+var characterReferenceRegexp = regexp.MustCompile(`&([#0-9A-Za-z])`)
+
+// escapeAttribute escapes an attribute value for the given quote, in
+// expressions only ampersands which would start a character reference are
+// escaped so `a && b` stays readable.
+func escapeAttribute(value string, quote byte, escapeAmpersand bool) string {
+	if escapeAmpersand {
+		value = strings.ReplaceAll(value, "&", "&amp;")
+	} else {
+		value = characterReferenceRegexp.ReplaceAllString(value, "&amp;$1")
+	}
+	if quote == '\'' {
+		return strings.ReplaceAll(value, "'", "&#39;")
+	}
+	return strings.ReplaceAll(value, `"`, "&quot;")
+}
@@ -0,0 +1,52 @@
+package template
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSerializeRoundTrip serializes templates, parses the result again and
+// serializes it once more, which must not change it, and checks that the
+// lossless mode returns the source.
+func TestSerializeRoundTrip(t *testing.T) {
+	sources := []string{
+		`<p [title]="'&amp;lt;'" (click)="a && b()" title="&amp;lt; &lt;">&amp;lt;</p>`,
+		`<p [title]="a&&b" [id]="'&#60;' + '&x'">{{ '&amp;' }}</p>`,
+		`<input [value]="'&quot;'" placeholder='a "b"'>`,
+	}
+	paths, err := filepath.Glob("testdata/angular/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range append(paths, benchmarkTemplates...) {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, string(src))
+	}
+
+	for _, src := range sources {
+		root := Parse(strings.NewReader(src), "")
+		if got := Serialize(root, SerializeLossless); got != src {
+			t.Errorf("Serialize(%.40q, SerializeLossless) = %.40q", src, got)
+		}
+
+		normalized := Serialize(root, SerializeNormalized)
+		again := Serialize(Parse(strings.NewReader(normalized), ""), SerializeNormalized)
+		if again != normalized {
+			t.Errorf("Serialize(%.40q, SerializeNormalized) changed when parsed again:\n%s\nto\n%s", src, normalized, again)
+		}
+
+		formatted, err := FormatTemplate([]byte(src), DefaultFormatOptions)
+		if err != nil {
+			t.Errorf("FormatTemplate(%.40q): %v", src, err)
+			continue
+		}
+		if twice, err := FormatTemplate(formatted, DefaultFormatOptions); err != nil || string(twice) != string(formatted) {
+			t.Errorf("FormatTemplate(%.40q) isn't idempotent:\n%s\nto\n%s", src, formatted, twice)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package template
+
+import (
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+type StreamEventType int
+
+const (
+	// EnterNode is sent for an element, template or block after its start
+	// tag with its attributes and parameters, and for other nodes when they
+	// are complete.
+	EnterNode StreamEventType = iota
+	// LeaveNode is sent after the children of a node with its end spans set,
+	// for text and comments it follows EnterNode.
+	LeaveNode
+)
+
+func (t StreamEventType) String() string {
+	if t == EnterNode {
+		return "enter"
+	}
+	return "leave"
+}
+
+// StreamEvent is a node passed to the function of StreamTemplate. The enter
+// and leave events of a node carry the same pointer.
+type StreamEvent struct {
+	Type StreamEventType
+	Node Node
+	// Depth is the number of elements, templates and blocks the node is in.
+	Depth int
+}
+
+// StreamTemplate parses a template read from r and calls fn in document
+// order when a node starts and when it ends, e.g. for a template too large
+// to hold in memory. The streamed elements, templates and blocks have no
+// Children, as they were passed to fn before, and the errors of the
+// expressions are only in their ASTWithSource, so the memory used doesn't
+// grow with the template.
+// Parsing stops at the first error returned by fn, which is returned, or at
+// the first error reading r.
+func StreamTemplate(r io.Reader, location string, fn func(StreamEvent) error) error {
+	tokenizer := newTemplateTokenizer(r)
+	tokenizer.location = location
+	tokenizer.stream = fn
+
+	tokenizer.Next()
+	for tokenizer.Token().Type != html.ErrorToken {
+		walk(tokenizer, tokenizer.Token())
+	}
+
+	if err := tokenizer.Err(); err != io.EOF {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStreamTemplates(t *testing.T) {
+	var events []string
+	err := StreamTemplate(strings.NewReader(`<ul><li>a</li><!--b--></ul>`), "", func(event StreamEvent) error {
+		name := ""
+		switch n := event.Node.(type) {
+		case *Element:
+			name = n.Name
+		case *Text:
+			name = n.Value
+		case *Comment:
+			name = n.Value
+		}
+		events = append(events, fmt.Sprintf("%s %s %d", event.Type, name, event.Depth))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"enter ul 0", "enter li 1", "enter a 2", "leave a 2", "leave li 1", "enter b 1", "leave b 1", "leave ul 0"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got the events %q, want %q", events, want)
+	}
+}
+
+func TestStreamStructuralDirectives(t *testing.T) {
+	var events []string
+	err := StreamTemplate(strings.NewReader(`<p *ngIf="a" *ngFor="let i of b">x</p>`), "", func(event StreamEvent) error {
+		name := ""
+		switch n := event.Node.(type) {
+		case *Template:
+			name = n.TemplateAttr.Name
+		case *Element:
+			name = n.Name
+		case *Text:
+			name = n.Value
+		}
+		events = append(events, fmt.Sprintf("%s %s %d", event.Type, name, event.Depth))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"enter *ngIf 0", "enter *ngFor 1", "enter p 2", "enter x 3", "leave x 3", "leave p 2", "leave *ngFor 1", "leave *ngIf 0"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got the events %q, want %q", events, want)
+	}
+}
+
+func TestStreamTemplateStops(t *testing.T) {
+	stop := errors.New("stop")
+	count := 0
+	err := StreamTemplate(strings.NewReader(`<p>a</p><p>b</p>`), "", func(event StreamEvent) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("got %v after %d events, want the error of fn after the first", err, count)
+	}
+}
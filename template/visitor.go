@@ -0,0 +1,155 @@
+package template
+
+import (
+	"github.com/irustm/ng-template-parser/ep"
+)
+
+// https://github.com/angular/angular/blob/master/packages/compiler/src/render3/r3_ast.ts
+
+// Node is a node of the template tree: *Element, *Template, *Text,
+// *BoundText, *Comment or *Block. The interface is sealed, other packages can't implement it.
+type Node interface {
+	Visit(visitor TemplateVisitor, context interface{}) interface{}
+	GetSourceSpan() ep.AbsoluteSourceSpan
+	isNode()
+}
+
+func (n *Element) Visit(visitor TemplateVisitor, context interface{}) interface{} {
+	return visitor.VisitElement(n, context)
+}
+
+func (n *Template) Visit(visitor TemplateVisitor, context interface{}) interface{} {
+	return visitor.VisitTemplate(n, context)
+}
+
+func (n *Text) Visit(visitor TemplateVisitor, context interface{}) interface{} {
+	return visitor.VisitText(n, context)
+}
+
+func (n *BoundText) Visit(visitor TemplateVisitor, context interface{}) interface{} {
+	return visitor.VisitBoundText(n, context)
+}
+
+func (n *Comment) Visit(visitor TemplateVisitor, context interface{}) interface{} {
+	return visitor.VisitComment(n, context)
+}
+
+func (n *Block) Visit(visitor TemplateVisitor, context interface{}) interface{} {
+	return visitor.VisitBlock(n, context)
+}
+
+func (n *Element) GetSourceSpan() ep.AbsoluteSourceSpan   { return n.SourceSpan }
+func (n *Template) GetSourceSpan() ep.AbsoluteSourceSpan  { return n.SourceSpan }
+func (n *Text) GetSourceSpan() ep.AbsoluteSourceSpan      { return n.SourceSpan }
+func (n *BoundText) GetSourceSpan() ep.AbsoluteSourceSpan { return n.SourceSpan }
+func (n *Comment) GetSourceSpan() ep.AbsoluteSourceSpan   { return n.SourceSpan }
+func (n *Block) GetSourceSpan() ep.AbsoluteSourceSpan     { return n.SourceSpan }
+
+func (n *Element) isNode()   {}
+func (n *Template) isNode()  {}
+func (n *Text) isNode()      {}
+func (n *BoundText) isNode() {}
+func (n *Comment) isNode()   {}
+func (n *Block) isNode()     {}
+
+type TemplateVisitor interface {
+	VisitElement(node *Element, context interface{}) interface{}
+	VisitTemplate(node *Template, context interface{}) interface{}
+	VisitText(node *Text, context interface{}) interface{}
+	VisitBoundText(node *BoundText, context interface{}) interface{}
+	VisitComment(node *Comment, context interface{}) interface{}
+	VisitBlock(node *Block, context interface{}) interface{}
+}
+
+// RecursiveVisitor visits every node of a template. Embed it and override
+// the methods of interest, calling back into the embedded visitor with Self
+// set to the outer visitor to keep visiting children.
+type RecursiveVisitor struct {
+	Self TemplateVisitor
+}
+
+func (v *RecursiveVisitor) visitAll(nodes []Node, context interface{}) {
+	var visitor TemplateVisitor = v
+	if v.Self != nil {
+		visitor = v.Self
+	}
+
+	for _, node := range nodes {
+		node.Visit(visitor, context)
+	}
+}
+
+func (v *RecursiveVisitor) VisitElement(node *Element, context interface{}) interface{} {
+	v.visitAll(node.Children, context)
+	return nil
+}
+
+func (v *RecursiveVisitor) VisitTemplate(node *Template, context interface{}) interface{} {
+	v.visitAll(node.Children, context)
+	return nil
+}
+
+func (v *RecursiveVisitor) VisitText(node *Text, context interface{}) interface{} {
+	return nil
+}
+
+func (v *RecursiveVisitor) VisitBoundText(node *BoundText, context interface{}) interface{} {
+	return nil
+}
+
+func (v *RecursiveVisitor) VisitComment(node *Comment, context interface{}) interface{} {
+	return nil
+}
+
+func (v *RecursiveVisitor) VisitBlock(node *Block, context interface{}) interface{} {
+	v.visitAll(node.Children, context)
+	return nil
+}
+
+// VisitAll visits nodes in order.
+func VisitAll(visitor TemplateVisitor, nodes []Node, context interface{}) {
+	for _, node := range nodes {
+		node.Visit(visitor, context)
+	}
+}
+
+// Children returns the child nodes of an element, template or block, other
+// nodes have none.
+func Children(node Node) []Node {
+	switch n := node.(type) {
+	case *Element:
+		return n.Children
+	case *Template:
+		return n.Children
+	case *Block:
+		return n.Children
+	}
+	return nil
+}
+
+// Walk calls fn for node and its descendants in document order, the children
+// of a node are skipped if fn returns false.
+func Walk(node Node, fn func(Node) bool) {
+	if !fn(node) {
+		return
+	}
+	for _, child := range Children(node) {
+		Walk(child, fn)
+	}
+}
+
+// Parents returns the parent of every node nested in the given nodes, the
+// nodes themselves have no entry. The tree isn't linked to its parents, call
+// Parents again after changing it.
+func Parents(nodes []Node) map[Node]Node {
+	parents := map[Node]Node{}
+	for _, node := range nodes {
+		Walk(node, func(n Node) bool {
+			for _, child := range Children(n) {
+				parents[child] = n
+			}
+			return true
+		})
+	}
+	return parents
+}